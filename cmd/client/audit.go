@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"titan/pkg/managerclient"
+	pb "titan/pkg/proto"
+)
+
+// searchAudit prints the manager's audit log of cluster-mutating calls,
+// newest first
+func searchAudit(client *managerclient.Client, args []string) {
+	fs := flag.NewFlagSet("audit", flag.ExitOnError)
+	since := fs.String("since", "24h", "Only calls after this time (RFC3339, or a duration such as 1h meaning ago; empty for all)")
+	until := fs.String("until", "", "Only calls before this time (RFC3339, or a duration meaning ago)")
+	caller := fs.String("caller", "", "Only calls made by this caller")
+	method := fs.String("method", "", "Only calls of this method, e.g. SubmitJob")
+	limit := fs.Int("limit", 0, "Maximum number of entries to show (default: server limit)")
+	showArgs := fs.Bool("args", false, "Show each call's arguments")
+	fs.Parse(args)
+
+	req := pb.AuditRequest{
+		Caller: *caller,
+		Method: *method,
+		Limit:  int32(*limit),
+	}
+	var err error
+	if req.Since, err = parseTimeArg(*since); err != nil {
+		fmt.Printf("Invalid --since: %v\n", err)
+		os.Exit(1)
+	}
+	if req.Until, err = parseTimeArg(*until); err != nil {
+		fmt.Printf("Invalid --until: %v\n", err)
+		os.Exit(1)
+	}
+
+	var resp pb.AuditResponse
+	if err := client.Call("ManagerService.SearchAudit", req, &resp); err != nil {
+		fmt.Printf("Error searching audit log: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tCALLER\tMETHOD\tTARGET\tRESULT")
+	for _, e := range resp.Entries {
+		who := e.Caller
+		if who == "" {
+			who = "-"
+		} else if e.CallerKind != "" {
+			who = e.CallerKind + ":" + who
+		}
+		result := e.Result
+		if e.Error != "" {
+			result += ": " + e.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			time.Unix(e.Time, 0).Format(time.RFC3339),
+			who,
+			e.Method,
+			e.Target,
+			result)
+		if *showArgs && e.Args != "" {
+			fmt.Fprintf(w, "\t\t  %s\n", e.Args)
+		}
+	}
+	w.Flush()
+}
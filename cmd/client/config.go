@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// tokenEnv names the environment variable holding the API token
+const tokenEnv = "TITAN_TOKEN"
+
+// configPath returns the client configuration file: --config if given,
+// then $TITAN_CONFIG, then titan/config in the user's config directory.
+// explicit reports whether the user named the file.
+func configPath(flagValue string) (path string, explicit bool) {
+	if flagValue != "" {
+		return flagValue, true
+	}
+	if env := os.Getenv("TITAN_CONFIG"); env != "" {
+		return env, true
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, "titan", "config"), false
+}
+
+// applyDefaults fills in flags not given on the command line: the token
+// from $TITAN_TOKEN, then anything from the configuration file. The file
+// holds one "flag = value" per line, e.g. "token = ..." or
+// "manager = host:8080"; blank lines and lines starting with # are
+// ignored. A missing file is only an error if it was named explicitly.
+func applyDefaults(path string, explicit bool) error {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if token := os.Getenv(tokenEnv); token != "" && !set["token"] {
+		flag.Set("token", token)
+		set["token"] = true
+	}
+
+	if path == "" {
+		return nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) && !explicit {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" {
+			return fmt.Errorf("%s:%d: want flag = value", path, lineNo)
+		}
+		if flag.Lookup(name) == nil || name == "config" {
+			return fmt.Errorf("%s:%d: unknown setting %q", path, lineNo, name)
+		}
+		if set[name] {
+			continue
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: %v", path, lineNo, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"titan/pkg/managerclient"
+	pb "titan/pkg/proto"
+)
+
+// searchHistory searches the manager's archive of evicted jobs. With a job
+// ID it prints that job in full; otherwise it prints a table of matches.
+func searchHistory(client *managerclient.Client, args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	status := fs.String("status", "", "Only jobs that ended in this status")
+	command := fs.String("command", "", "Only jobs whose command contains this text")
+	after := fs.String("after", "", "Only jobs submitted after this time (RFC3339, or a duration such as 24h meaning ago)")
+	before := fs.String("before", "", "Only jobs submitted before this time (RFC3339, or a duration meaning ago)")
+	limit := fs.Int("limit", 0, "Maximum number of jobs to show (default: server limit)")
+	fs.Parse(args)
+
+	req := pb.HistoryRequest{
+		JobId:   fs.Arg(0),
+		Status:  strings.ToUpper(*status),
+		Command: *command,
+		Limit:   int32(*limit),
+	}
+	var err error
+	if req.SubmittedAfter, err = parseTimeArg(*after); err != nil {
+		fmt.Printf("Invalid --after: %v\n", err)
+		os.Exit(1)
+	}
+	if req.SubmittedBefore, err = parseTimeArg(*before); err != nil {
+		fmt.Printf("Invalid --before: %v\n", err)
+		os.Exit(1)
+	}
+
+	var resp pb.HistoryResponse
+	if err := client.Call("ManagerService.SearchHistory", req, &resp); err != nil {
+		fmt.Printf("Error searching history: %v\n", err)
+		os.Exit(1)
+	}
+
+	if req.JobId != "" {
+		if len(resp.Jobs) == 0 {
+			fmt.Printf("Job %s not found in history\n", req.JobId)
+			os.Exit(1)
+		}
+		fmt.Printf("Archived: %s\n", formatTime(resp.Jobs[0].ArchivedAt))
+		printJobStatus(resp.Jobs[0].Job)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB ID\tSTATUS\tEXIT CODE\tSUBMITTED\tARCHIVED\tCOMMAND")
+	for _, a := range resp.Jobs {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n",
+			a.Job.JobId,
+			a.Job.Status,
+			a.Job.ExitCode,
+			formatTime(a.Job.CreatedAt),
+			formatTime(a.ArchivedAt),
+			jobCommand(a.Job))
+	}
+	w.Flush()
+}
+
+// parseTimeArg parses an RFC3339 time, or a duration counted back from
+// now, into a Unix timestamp. An empty string yields 0.
+func parseTimeArg(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d).Unix(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Unix(), nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"titan/pkg/managerclient"
+	pb "titan/pkg/proto"
+)
+
+// labelFlag collects repeated --label key=value flags
+type labelFlag map[string]string
+
+func (l labelFlag) String() string {
+	return formatLabels(l)
+}
+
+func (l labelFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	l[k] = v
+	return nil
+}
+
+// formatLabels renders labels as sorted key=value pairs
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// listJobs prints one page of jobs and how to fetch the next one
+func listJobs(client *managerclient.Client, req pb.ListJobsRequest) {
+	var resp pb.ListJobsResponse
+	if err := client.Call("ManagerService.ListJobs", req, &resp); err != nil {
+		fmt.Printf("Error listing jobs: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Jobs:\n")
+	for _, job := range resp.Jobs {
+		fmt.Printf("- %s [%s] Worker: %s ExitCode: %d", job.JobId, job.Status, job.WorkerId, job.ExitCode)
+		if job.Owner != "" {
+			fmt.Printf(" Owner: %s", job.Owner)
+		}
+		if len(job.Labels) > 0 {
+			fmt.Printf(" Labels: %s", formatLabels(job.Labels))
+		}
+		fmt.Printf(" Command: %s\n", jobCommand(job))
+	}
+	if resp.NextPageToken != "" {
+		fmt.Printf("More jobs available: --page-token %s\n", resp.NextPageToken)
+	}
+}
@@ -3,42 +3,178 @@ package main
 import (
 	"flag"
 	"fmt"
-	"net/rpc"
+	"log/slog"
 	"os"
+	"strings"
+	"time"
 
+	"titan/pkg/certs"
+	"titan/pkg/logger"
+	"titan/pkg/managerclient"
 	pb "titan/pkg/proto"
 )
 
 func main() {
-	managerAddr := flag.String("manager", "localhost:8080", "Manager address")
+	managerAddr := flag.String("manager", "localhost:8080", "Manager address, or a comma-separated list of manager replicas")
+	transport := flag.String("transport", "rpc", "Protocol for talking to the manager: rpc or grpc")
 	command := flag.String("command", "", "Command to run")
 	list := flag.Bool("list", false, "List all jobs")
 	status := flag.String("status", "", "Get status of job ID")
+	wait := flag.String("wait", "", "Wait for job ID to finish and exit with its exit code")
+	labels := labelFlag{}
+	flag.Var(labels, "label", "Job label as key=value; sets labels on submit, filters --list (repeatable)")
+	var secretRefs secretFlag
+	flag.Var(&secretRefs, "secret", "With --command: deliver a secret as NAME=env:VAR or NAME=file:PATH (repeatable)")
+	state := flag.String("state", "", "With --list: comma-separated statuses to show")
+	worker := flag.String("worker", "", "With --list: only jobs placed on this worker")
+	contains := flag.String("contains", "", "With --list: only jobs whose command contains this text")
+	after := flag.String("after", "", "With --list: only jobs submitted after this time (RFC3339, or a duration such as 1h meaning ago)")
+	before := flag.String("before", "", "With --list: only jobs submitted before this time (RFC3339, or a duration meaning ago)")
+	oldestFirst := flag.Bool("oldest-first", false, "With --list: show the oldest jobs first")
+	pageSize := flag.Int("page-size", 0, "With --list: jobs per page (default: server default)")
+	pageToken := flag.String("page-token", "", "With --list: continue from a previous page")
+	tlsFiles := certs.RegisterFlags(flag.CommandLine)
+	token := flag.String("token", "", "API token for the manager (default $"+tokenEnv+")")
+	configFile := flag.String("config", "", "Client configuration file (default $TITAN_CONFIG, or titan/config in the user config directory)")
 	flag.Parse()
 
-	client, err := rpc.Dial("tcp", *managerAddr)
+	if err := applyDefaults(configPath(*configFile)); err != nil {
+		fmt.Printf("Invalid configuration: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Keep connection logs out of the command's output
+	logger.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	managerTransport, err := managerclient.ParseTransport(*transport)
 	if err != nil {
-		fmt.Printf("Error connecting to manager: %v\n", err)
+		fmt.Println(err)
 		os.Exit(1)
 	}
+	tlsConfig, err := tlsFiles.Load()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	client := managerclient.NewWithOptions(managerclient.Options{Transport: managerTransport, TLS: tlsConfig, Token: *token}, managerclient.SplitAddrs(*managerAddr)...)
 	defer client.Close()
 
-	if *list {
-		var req pb.ListJobsRequest
-		var resp pb.ListJobsResponse
-		err = client.Call("ManagerService.ListJobs", req, &resp)
-		if err != nil {
-			fmt.Printf("Error listing jobs: %v\n", err)
+	switch flag.Arg(0) {
+	case "workers":
+		listWorkers(client)
+		return
+	case "worker":
+		if flag.NArg() < 2 {
+			fmt.Println("Usage: client.exe worker <WORKER_ID>")
 			os.Exit(1)
 		}
+		showWorker(client, flag.Arg(1))
+		return
+	case "drain", "undrain":
+		if flag.NArg() < 2 {
+			fmt.Printf("Usage: client.exe %s <WORKER_ID>\n", flag.Arg(0))
+			os.Exit(1)
+		}
+		drainWorker(client, flag.Arg(1), flag.Arg(0) == "undrain")
+		return
+	case "revoke", "unrevoke":
+		if flag.NArg() < 2 {
+			fmt.Printf("Usage: client.exe %s <WORKER_ID> [REASON]\n", flag.Arg(0))
+			os.Exit(1)
+		}
+		revokeWorker(client, flag.Arg(1), strings.Join(flag.Args()[2:], " "), flag.Arg(0) == "unrevoke")
+		return
+	case "cancel":
+		if flag.NArg() < 2 {
+			fmt.Println("Usage: client.exe cancel <JOB_ID> [REASON]")
+			os.Exit(1)
+		}
+		req := pb.CancelJobRequest{JobId: flag.Arg(1), Reason: strings.Join(flag.Args()[2:], " ")}
+		var resp pb.JobStatusResponse
+		if err := client.Call("ManagerService.CancelJob", req, &resp); err != nil {
+			fmt.Printf("Error cancelling job: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Job %s is %s\n", resp.JobId, resp.Status)
+		return
+	case "submit":
+		submitSpec(client, flag.Args()[1:])
+		return
+	case "get":
+		getJob(client, flag.Args()[1:])
+		return
+	case "history":
+		searchHistory(client, flag.Args()[1:])
+		return
+	case "audit":
+		searchAudit(client, flag.Args()[1:])
+		return
+	case "secrets":
+		manageSecrets(client, flag.Args()[1:])
+		return
+	case "templates":
+		manageTemplates(client, flag.Args()[1:])
+		return
+	case "whoami":
+		var resp pb.AuthResponse
+		if err := client.Call("AuthService.Authenticate", pb.AuthRequest{}, &resp); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if resp.Name == "" {
+			fmt.Println("Not authenticated")
+			return
+		}
+		fmt.Printf("%s (%s)\n", resp.Name, resp.Kind)
+		if len(resp.Groups) > 0 {
+			fmt.Printf("Groups: %s\n", strings.Join(resp.Groups, ", "))
+		}
+		if resp.Role != "" {
+			fmt.Printf("Role: %s\n", resp.Role)
+		}
+		if resp.ExpiresAt != 0 {
+			fmt.Printf("Expires: %s\n", time.Unix(resp.ExpiresAt, 0).Format(time.RFC3339))
+		}
+		return
+	}
 
-		fmt.Printf("Jobs:\n")
-		for _, job := range resp.Jobs {
-			fmt.Printf("- %s [%s] Worker: %s ExitCode: %d\n", job.JobId, job.Status, job.WorkerId, job.ExitCode)
+	if *list {
+		req := pb.ListJobsRequest{
+			WorkerId:    *worker,
+			Labels:      labels,
+			Command:     *contains,
+			OldestFirst: *oldestFirst,
+			PageSize:    int32(*pageSize),
+			PageToken:   *pageToken,
+			Summary:     true,
 		}
+		for _, name := range strings.Split(*state, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				req.Statuses = append(req.Statuses, strings.ToUpper(name))
+			}
+		}
+		if req.SubmittedAfter, err = parseTimeArg(*after); err != nil {
+			fmt.Printf("Invalid --after: %v\n", err)
+			os.Exit(1)
+		}
+		if req.SubmittedBefore, err = parseTimeArg(*before); err != nil {
+			fmt.Printf("Invalid --before: %v\n", err)
+			os.Exit(1)
+		}
+		listJobs(client, req)
 		return
 	}
 
+	if *wait != "" {
+		resp, err := client.WaitForJob(*wait)
+		if err != nil {
+			fmt.Printf("Error waiting for job: %v\n", err)
+			os.Exit(1)
+		}
+		printJobStatus(resp)
+		os.Exit(jobExitCode(resp))
+	}
+
 	if *status != "" {
 		req := pb.JobStatusRequest{JobId: *status}
 		var resp pb.JobStatusResponse
@@ -48,11 +184,7 @@ func main() {
 			os.Exit(1)
 		}
 
-		fmt.Printf("Job ID: %s\n", resp.JobId)
-		fmt.Printf("Status: %s\n", resp.Status)
-		fmt.Printf("Worker: %s\n", resp.WorkerId)
-		fmt.Printf("Exit Code: %d\n", resp.ExitCode)
-		fmt.Printf("Output:\n%s\n", resp.Output)
+		printJobStatus(resp)
 		return
 	}
 
@@ -60,6 +192,8 @@ func main() {
 		req := pb.JobRequest{
 			Command: *command,
 			Env:     make(map[string]string),
+			Labels:  labels,
+			Secrets: secretRefs,
 		}
 		var resp pb.JobResponse
 		err = client.Call("ManagerService.SubmitJob", req, &resp)
@@ -75,7 +209,87 @@ func main() {
 	}
 
 	fmt.Println("Usage:")
-	fmt.Println("  Submit job: client.exe --command \"echo hello\"")
-	fmt.Println("  List jobs:  client.exe --list")
+	fmt.Println("  Submit job: client.exe --command \"echo hello\" [--label k=v] [--secret NAME=env:VAR|NAME=file:PATH]")
+	fmt.Println("  From spec:  client.exe submit -f job.yaml [--dry-run]")
+	fmt.Println("  List jobs:  client.exe --list [--state S,...] [--worker ID] [--label k=v] [--contains TEXT] [--after T] [--before T] [--oldest-first] [--page-size N] [--page-token TOKEN]")
 	fmt.Println("  Job status: client.exe --status <JOB_ID>")
+	fmt.Println("  Get job:    client.exe get <JOB_ID> [-o yaml|json]")
+	fmt.Println("  Wait:       client.exe --wait <JOB_ID>")
+	fmt.Println("  Cancel:     client.exe cancel <JOB_ID> [REASON]")
+	fmt.Println("  Workers:    client.exe workers")
+	fmt.Println("  Worker:     client.exe worker <WORKER_ID>")
+	fmt.Println("  Drain:      client.exe drain|undrain <WORKER_ID>")
+	fmt.Println("  Revoke:     client.exe revoke|unrevoke <WORKER_ID> [REASON]")
+	fmt.Println("  History:    client.exe history [--status S] [--command TEXT] [--after T] [--before T] [--limit N] [JOB_ID]")
+	fmt.Println("  Audit:      client.exe audit [--since T] [--until T] [--caller NAME] [--method M] [--limit N] [--args]")
+	fmt.Println("  Secrets:    client.exe secrets list | secrets set [--from-file FILE] <NAME> | secrets delete <NAME>")
+	fmt.Println("  Templates:  client.exe templates list | templates get <NAME> [-o yaml|json] | templates create -f FILE")
+	fmt.Println("              client.exe templates submit <NAME> [PARAM=VALUE ...] | templates delete <NAME>")
+	fmt.Println("  Identity:   client.exe whoami")
+}
+
+// printJobStatus prints the details of a job, including its history,
+// attempts and output
+func printJobStatus(resp pb.JobStatusResponse) {
+	fmt.Printf("Job ID: %s\n", resp.JobId)
+	fmt.Printf("Command: %s\n", jobCommand(resp))
+	fmt.Printf("Status: %s\n", resp.Status)
+	fmt.Printf("Worker: %s\n", resp.WorkerId)
+	fmt.Printf("Exit Code: %d\n", resp.ExitCode)
+	if resp.Owner != "" {
+		fmt.Printf("Owner: %s\n", resp.Owner)
+	}
+	if len(resp.Labels) > 0 {
+		fmt.Printf("Labels: %s\n", formatLabels(resp.Labels))
+	}
+	if len(resp.Secrets) > 0 {
+		refs := secretFlag(resp.Secrets)
+		fmt.Printf("Secrets: %s\n", refs.String())
+	}
+	if resp.Priority != 0 {
+		fmt.Printf("Priority: %d\n", resp.Priority)
+	}
+	if resp.Resources.CpuMillicores != 0 || resp.Resources.MemoryMb != 0 {
+		fmt.Printf("Resources: %d millicores, %d MB\n", resp.Resources.CpuMillicores, resp.Resources.MemoryMb)
+	}
+	if len(resp.Constraints) > 0 {
+		fmt.Printf("Constraints: %s\n", formatLabels(resp.Constraints))
+	}
+	if resp.TimeoutSeconds > 0 {
+		fmt.Printf("Timeout: %s\n", time.Duration(resp.TimeoutSeconds)*time.Second)
+	}
+	if resp.MaxRetries > 0 {
+		fmt.Printf("Retries: %d of %d\n", resp.Retries, resp.MaxRetries)
+	}
+	fmt.Printf("History:\n")
+	for _, t := range resp.History {
+		from := t.From
+		if from == "" {
+			from = "-"
+		}
+		fmt.Printf("  %s  %s -> %s  (%s)\n", time.Unix(t.At, 0).Format(time.RFC3339), from, t.To, t.Reason)
+	}
+	fmt.Printf("Attempts:\n")
+	printAttempts(resp.Attempts)
+	fmt.Printf("Output:\n%s\n", resp.Output)
+}
+
+// jobCommand is what a job runs: its shell command, or its argv
+func jobCommand(resp pb.JobStatusResponse) string {
+	if len(resp.Argv) > 0 {
+		return strings.Join(resp.Argv, " ")
+	}
+	return resp.Command
+}
+
+// jobExitCode is the exit code for --wait: the job's own exit code, or 1 if
+// the job didn't complete but has no failing exit code of its own
+func jobExitCode(resp pb.JobStatusResponse) int {
+	if resp.ExitCode != 0 {
+		return int(resp.ExitCode)
+	}
+	if resp.Status != "COMPLETED" {
+		return 1
+	}
+	return 0
 }
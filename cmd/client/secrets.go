@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"titan/pkg/managerclient"
+	pb "titan/pkg/proto"
+)
+
+// secretFlag collects repeated --secret NAME=env:VAR or NAME=file:PATH flags
+type secretFlag []pb.SecretRef
+
+func (s *secretFlag) String() string {
+	refs := make([]string, len(*s))
+	for i, ref := range *s {
+		refs[i] = formatSecretRef(ref)
+	}
+	return strings.Join(refs, ",")
+}
+
+func (s *secretFlag) Set(value string) error {
+	name, target, ok := strings.Cut(value, "=")
+	kind, dest, ok2 := strings.Cut(target, ":")
+	if !ok || !ok2 || name == "" || dest == "" {
+		return fmt.Errorf("expected NAME=env:VAR or NAME=file:PATH, got %q", value)
+	}
+	ref := pb.SecretRef{Name: name}
+	switch kind {
+	case "env":
+		ref.Env = dest
+	case "file":
+		ref.File = dest
+	default:
+		return fmt.Errorf("expected NAME=env:VAR or NAME=file:PATH, got %q", value)
+	}
+	*s = append(*s, ref)
+	return nil
+}
+
+// formatSecretRef renders a secret reference the way --secret takes it
+func formatSecretRef(ref pb.SecretRef) string {
+	if ref.File != "" {
+		return ref.Name + "=file:" + ref.File
+	}
+	return ref.Name + "=env:" + ref.Env
+}
+
+// manageSecrets runs the secrets subcommands
+func manageSecrets(client *managerclient.Client, args []string) {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch args[0] {
+	case "list":
+		listSecrets(client)
+	case "set":
+		setSecret(client, args[1:])
+	case "delete":
+		if len(args) != 2 {
+			fmt.Println("Usage: client.exe secrets delete <NAME>")
+			os.Exit(1)
+		}
+		deleteSecret(client, args[1])
+	default:
+		fmt.Println("Usage: client.exe secrets list|set|delete")
+		os.Exit(1)
+	}
+}
+
+// listSecrets prints the name and age of every secret; values are never
+// returned by the manager
+func listSecrets(client *managerclient.Client) {
+	var resp pb.ListSecretsResponse
+	if err := client.Call("ManagerService.ListSecrets", pb.ListSecretsRequest{}, &resp); err != nil {
+		fmt.Printf("Error listing secrets: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tUPDATED\tUPDATED BY")
+	for _, secret := range resp.Secrets {
+		by := secret.UpdatedBy
+		if by == "" {
+			by = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", secret.Name, time.Unix(secret.UpdatedAt, 0).Format(time.RFC3339), by)
+	}
+	w.Flush()
+}
+
+// setSecret creates or replaces a secret. The value is read from a file
+// or standard input, never from the command line, where it would end up
+// in the shell history and the process list.
+func setSecret(client *managerclient.Client, args []string) {
+	fs := flag.NewFlagSet("secrets set", flag.ExitOnError)
+	fromFile := fs.String("from-file", "", "Read the value from this file (default: standard input)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: client.exe secrets set [--from-file FILE] <NAME>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	var value []byte
+	var err error
+	if *fromFile != "" {
+		value, err = os.ReadFile(*fromFile)
+	} else {
+		value, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		fmt.Printf("Error reading secret value: %v\n", err)
+		os.Exit(1)
+	}
+
+	req := pb.PutSecretRequest{Name: fs.Arg(0), Value: value}
+	var resp pb.SecretInfo
+	if err := client.Call("ManagerService.PutSecret", req, &resp); err != nil {
+		fmt.Printf("Error setting secret: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Secret %s set (%d bytes)\n", resp.Name, len(value))
+}
+
+// deleteSecret deletes a secret
+func deleteSecret(client *managerclient.Client, name string) {
+	var resp pb.SecretInfo
+	if err := client.Call("ManagerService.DeleteSecret", pb.DeleteSecretRequest{Name: name}, &resp); err != nil {
+		fmt.Printf("Error deleting secret: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Secret %s deleted\n", resp.Name)
+}
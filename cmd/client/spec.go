@@ -0,0 +1,471 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"titan/pkg/managerclient"
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+	"titan/pkg/secrets"
+)
+
+// jobSpec is a job as written in a spec file for `submit -f`, and as
+// printed by `get -o yaml`. JSON files are accepted too, since YAML is a
+// superset of JSON.
+type jobSpec struct {
+	Command     string            `yaml:"command,omitempty" json:"command,omitempty"`
+	Argv        []string          `yaml:"argv,omitempty" json:"argv,omitempty"`
+	Env         map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	Secrets     []secretSpec      `yaml:"secrets,omitempty" json:"secrets,omitempty"`
+	Resources   *resourcesSpec    `yaml:"resources,omitempty" json:"resources,omitempty"`
+	Priority    int32             `yaml:"priority,omitempty" json:"priority,omitempty"`
+	Retries     int32             `yaml:"retries,omitempty" json:"retries,omitempty"`
+	Timeout     string            `yaml:"timeout,omitempty" json:"timeout,omitempty"` // A duration such as 90s or 1h
+	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Constraints map[string]string `yaml:"constraints,omitempty" json:"constraints,omitempty"`
+}
+
+// secretSpec delivers a secret in an environment variable or a file
+type secretSpec struct {
+	Name string `yaml:"name" json:"name"`
+	Env  string `yaml:"env,omitempty" json:"env,omitempty"`
+	File string `yaml:"file,omitempty" json:"file,omitempty"`
+}
+
+// resourcesSpec is what a job needs from the worker it runs on
+type resourcesSpec struct {
+	CPUMillicores int32 `yaml:"cpu_millicores,omitempty" json:"cpu_millicores,omitempty"`
+	MemoryMB      int64 `yaml:"memory_mb,omitempty" json:"memory_mb,omitempty"`
+}
+
+// The fields each mapping of a spec may have
+var (
+	specFields      = []string{"command", "argv", "env", "secrets", "resources", "priority", "retries", "timeout", "labels", "constraints"}
+	secretFields    = []string{"name", "env", "file"}
+	resourcesFields = []string{"cpu_millicores", "memory_mb"}
+)
+
+// specErrors collects the problems found in a spec, each prefixed with
+// its line where known
+type specErrors struct {
+	lines  map[string]int // Field path to line
+	prefix string         // Path of the job spec within the file, if nested
+	errs   []string
+}
+
+func (e *specErrors) add(path, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if e.prefix != "" {
+		if path == "" {
+			path = e.prefix
+		} else {
+			path = joinPath(e.prefix, path)
+		}
+	}
+	if path != "" {
+		msg = path + ": " + msg
+	}
+	if line, ok := e.lines[path]; ok {
+		msg = fmt.Sprintf("line %d: %s", line, msg)
+	}
+	e.errs = append(e.errs, msg)
+}
+
+func (e *specErrors) err() error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(e.errs, "\n"))
+}
+
+// parseJobSpec parses and validates a spec. Every problem is reported at
+// once, so a spec can be fixed in one go.
+func parseJobSpec(data []byte) (jobSpec, error) {
+	var spec jobSpec
+	root, err := parseDocument(data)
+	if err != nil {
+		return spec, err
+	}
+
+	// Reject unknown fields first: a misspelled one would otherwise be
+	// silently dropped
+	errs := &specErrors{lines: make(map[string]int)}
+	checkSpecFields(root, "", errs)
+	if err := errs.err(); err != nil {
+		return spec, err
+	}
+
+	if err := decodeNode(root, &spec); err != nil {
+		return spec, err
+	}
+	spec.validate(errs)
+	return spec, errs.err()
+}
+
+// parseDocument parses a YAML document and returns its root node
+func parseDocument(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == 0 || len(doc.Content) == 0 {
+		return nil, errors.New("file is empty")
+	}
+	return doc.Content[0], nil
+}
+
+// checkSpecFields checks the fields of the job spec at path
+func checkSpecFields(node *yaml.Node, path string, errs *specErrors) {
+	checkFields(node, path, specFields, errs)
+	for i := 0; node.Kind == yaml.MappingNode && i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch key.Value {
+		case "resources":
+			checkFields(value, joinPath(path, "resources"), resourcesFields, errs)
+		case "secrets":
+			if value.Kind != yaml.SequenceNode {
+				break
+			}
+			for j, item := range value.Content {
+				itemPath := joinPath(path, fmt.Sprintf("secrets[%d]", j))
+				errs.lines[itemPath] = item.Line
+				checkFields(item, itemPath, secretFields, errs)
+			}
+		case "env", "labels", "constraints":
+			recordKeys(value, joinPath(path, key.Value), errs)
+		}
+	}
+}
+
+// decodeNode decodes a checked document, reporting each value of the
+// wrong type with its line
+func decodeNode(node *yaml.Node, out any) error {
+	if err := node.Decode(out); err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) {
+			return errors.New(strings.Join(typeErr.Errors, "\n"))
+		}
+		return err
+	}
+	return nil
+}
+
+// checkFields reports keys of a mapping that aren't in allowed, and
+// records the line of those that are
+func checkFields(node *yaml.Node, path string, allowed []string, errs *specErrors) {
+	if node.Kind != yaml.MappingNode {
+		errs.lines[path] = node.Line
+		errs.add(path, "expected a mapping with fields %s", strings.Join(allowed, ", "))
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i]
+		field := joinPath(path, key.Value)
+		errs.lines[field] = key.Line
+		if !hasString(allowed, key.Value) {
+			errs.add(field, "unknown field (allowed: %s)", strings.Join(allowed, ", "))
+		}
+	}
+}
+
+// recordKeys records the line of each key of a mapping
+func recordKeys(node *yaml.Node, path string, errs *specErrors) {
+	for i := 0; node.Kind == yaml.MappingNode && i+1 < len(node.Content); i += 2 {
+		errs.lines[joinPath(path, node.Content[i].Value)] = node.Content[i].Line
+	}
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func hasString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks what the manager would reject, and a little more
+func (spec jobSpec) validate(errs *specErrors) {
+	switch {
+	case spec.Command != "" && len(spec.Argv) > 0:
+		errs.add("argv", "set either command or argv, not both")
+	case len(spec.Argv) > 0 && spec.Argv[0] == "":
+		errs.add("argv", "the first element must name the program to run")
+	case len(spec.Argv) == 0 && strings.TrimSpace(spec.Command) == "":
+		errs.add("", "command or argv is required")
+	}
+
+	for _, name := range sortedKeys(spec.Env) {
+		if err := secrets.ValidateEnvName(name); err != nil {
+			errs.add("env."+name, "%v", err)
+		}
+	}
+
+	for i, ref := range spec.Secrets {
+		path := fmt.Sprintf("secrets[%d]", i)
+		if err := secrets.ValidateName(ref.Name); err != nil {
+			errs.add(path+".name", "%v", err)
+		}
+		switch {
+		case (ref.Env == "") == (ref.File == ""):
+			errs.add(path, "set exactly one of env and file")
+		case ref.Env != "":
+			if err := secrets.ValidateEnvName(ref.Env); err != nil {
+				errs.add(path+".env", "%v", err)
+			} else if _, ok := spec.Env[ref.Env]; ok {
+				errs.add(path+".env", "%s is also set in env", ref.Env)
+			}
+		default:
+			if err := secrets.ValidateFilePath(ref.File); err != nil {
+				errs.add(path+".file", "%v", err)
+			}
+		}
+	}
+
+	if spec.Resources != nil {
+		if spec.Resources.CPUMillicores < 0 {
+			errs.add("resources.cpu_millicores", "can't be negative")
+		}
+		if spec.Resources.MemoryMB < 0 {
+			errs.add("resources.memory_mb", "can't be negative")
+		}
+	}
+
+	if spec.Retries < 0 || spec.Retries > models.MaxJobRetries {
+		errs.add("retries", "must be between 0 and %d", models.MaxJobRetries)
+	}
+
+	if spec.Timeout != "" {
+		d, err := time.ParseDuration(spec.Timeout)
+		switch {
+		case err != nil:
+			errs.add("timeout", "want a duration such as 90s, 10m or 2h, got %q", spec.Timeout)
+		case d < time.Second || d%time.Second != 0:
+			errs.add("timeout", "must be a positive whole number of seconds, got %s", spec.Timeout)
+		}
+	}
+
+	for _, key := range sortedKeys(spec.Labels) {
+		if key == "" {
+			errs.add("labels", "label with an empty name")
+		}
+	}
+	for _, key := range sortedKeys(spec.Constraints) {
+		if key == "" {
+			errs.add("constraints", "constraint with an empty label name")
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// request converts a validated spec into a job request
+func (spec jobSpec) request() pb.JobRequest {
+	req := pb.JobRequest{
+		Command:     spec.Command,
+		Argv:        spec.Argv,
+		Env:         spec.Env,
+		Labels:      spec.Labels,
+		Priority:    spec.Priority,
+		MaxRetries:  spec.Retries,
+		Constraints: spec.Constraints,
+	}
+	if req.Env == nil {
+		req.Env = make(map[string]string)
+	}
+	for _, ref := range spec.Secrets {
+		req.Secrets = append(req.Secrets, pb.SecretRef{Name: ref.Name, Env: ref.Env, File: ref.File})
+	}
+	if spec.Resources != nil {
+		req.Resources = pb.ResourceRequirements{
+			CpuMillicores: spec.Resources.CPUMillicores,
+			MemoryMb:      spec.Resources.MemoryMB,
+		}
+	}
+	if d, err := time.ParseDuration(spec.Timeout); err == nil {
+		req.TimeoutSeconds = int64(d / time.Second)
+	}
+	return req
+}
+
+// specFromStatus recovers the spec a job was submitted with
+func specFromStatus(resp pb.JobStatusResponse) jobSpec {
+	req := pb.JobRequest{
+		Argv:           resp.Argv,
+		Env:            resp.Env,
+		Labels:         resp.Labels,
+		Resources:      resp.Resources,
+		Secrets:        resp.Secrets,
+		Priority:       resp.Priority,
+		MaxRetries:     resp.MaxRetries,
+		TimeoutSeconds: resp.TimeoutSeconds,
+		Constraints:    resp.Constraints,
+	}
+	if len(req.Argv) == 0 {
+		req.Command = resp.Command
+	}
+	return specFromRequest(req)
+}
+
+// specFromRequest converts a job request into a spec
+func specFromRequest(req pb.JobRequest) jobSpec {
+	spec := jobSpec{
+		Command:     req.Command,
+		Argv:        req.Argv,
+		Env:         req.Env,
+		Priority:    req.Priority,
+		Retries:     req.MaxRetries,
+		Labels:      req.Labels,
+		Constraints: req.Constraints,
+	}
+	for _, ref := range req.Secrets {
+		spec.Secrets = append(spec.Secrets, secretSpec{Name: ref.Name, Env: ref.Env, File: ref.File})
+	}
+	if req.Resources.CpuMillicores != 0 || req.Resources.MemoryMb != 0 {
+		spec.Resources = &resourcesSpec{
+			CPUMillicores: req.Resources.CpuMillicores,
+			MemoryMB:      req.Resources.MemoryMb,
+		}
+	}
+	if req.TimeoutSeconds > 0 {
+		spec.Timeout = (time.Duration(req.TimeoutSeconds) * time.Second).String()
+	}
+	return spec
+}
+
+// readSpecFile reads a spec from a file, or from standard input for "-"
+func readSpecFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// submitSpec submits the job described in a spec file
+func submitSpec(client *managerclient.Client, args []string) {
+	fs := flag.NewFlagSet("submit", flag.ExitOnError)
+	file := fs.String("f", "", "Job spec file, YAML or JSON; - reads standard input (required)")
+	dryRun := fs.Bool("dry-run", false, "Validate the spec and print it without submitting")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: client.exe submit -f FILE [--dry-run]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *file == "" || fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	data, err := readSpecFile(*file)
+	if err != nil {
+		fmt.Printf("Error reading job spec: %v\n", err)
+		os.Exit(1)
+	}
+	spec, err := parseJobSpec(data)
+	if err != nil {
+		name := *file
+		if name == "-" {
+			name = "<stdin>"
+		}
+		fmt.Printf("Invalid job spec %s:\n", name)
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+		os.Exit(1)
+	}
+
+	if *dryRun {
+		printSpec(spec, "yaml")
+		return
+	}
+
+	var resp pb.JobResponse
+	if err := client.Call("ManagerService.SubmitJob", spec.request(), &resp); err != nil {
+		fmt.Printf("Error submitting job: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Job submitted successfully!\n")
+	fmt.Printf("Job ID: %s\n", resp.JobId)
+	fmt.Printf("Status: %s\n", resp.Status)
+}
+
+// getJob prints a job: its full status by default, or with -o yaml or
+// -o json the spec it was submitted with, ready for `submit -f`
+func getJob(client *managerclient.Client, args []string) {
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	output := fs.String("o", "", "Output format: yaml or json (default: full status)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: client.exe get <JOB_ID> [-o yaml|json]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	// Allow flags after the job ID as well
+	jobID := fs.Arg(0)
+	if fs.NArg() > 0 {
+		fs.Parse(fs.Args()[1:])
+	}
+	if jobID == "" || fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *output != "" && *output != "yaml" && *output != "json" {
+		fmt.Printf("Unknown output format %q (want yaml or json)\n", *output)
+		os.Exit(1)
+	}
+
+	var resp pb.JobStatusResponse
+	if err := client.Call("ManagerService.GetJobStatus", pb.JobStatusRequest{JobId: jobID}, &resp); err != nil {
+		fmt.Printf("Error getting job status: %v\n", err)
+		os.Exit(1)
+	}
+	if *output == "" {
+		printJobStatus(resp)
+		return
+	}
+	printSpec(specFromStatus(resp), *output)
+}
+
+// printSpec writes a spec to standard output as yaml or json
+func printSpec(spec any, format string) {
+	var err error
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(spec)
+	} else {
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		err = enc.Encode(spec)
+		if err == nil {
+			err = enc.Close()
+		}
+	}
+	if err != nil {
+		fmt.Printf("Error encoding job spec: %v\n", err)
+		os.Exit(1)
+	}
+}
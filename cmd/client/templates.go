@@ -0,0 +1,336 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"titan/pkg/jobtemplate"
+	"titan/pkg/managerclient"
+	pb "titan/pkg/proto"
+)
+
+// templateSpec is a job template as written in a file for
+// `templates create -f`, and as printed by `templates get`
+type templateSpec struct {
+	Name        string      `yaml:"name" json:"name"`
+	Description string      `yaml:"description,omitempty" json:"description,omitempty"`
+	Params      []paramSpec `yaml:"params,omitempty" json:"params,omitempty"`
+	Job         jobSpec     `yaml:"job" json:"job"`
+}
+
+// paramSpec is a template parameter. One without a default is required.
+type paramSpec struct {
+	Name        string  `yaml:"name" json:"name"`
+	Type        string  `yaml:"type,omitempty" json:"type,omitempty"`
+	Default     *string `yaml:"default,omitempty" json:"default,omitempty"`
+	Description string  `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// The fields of a template file and of each of its parameters
+var (
+	templateFields = []string{"name", "description", "params", "job"}
+	paramFields    = []string{"name", "type", "default", "description"}
+)
+
+// parseTemplateSpec parses and validates a template file. Like
+// parseJobSpec, it reports every problem at once.
+func parseTemplateSpec(data []byte) (templateSpec, error) {
+	var spec templateSpec
+	root, err := parseDocument(data)
+	if err != nil {
+		return spec, err
+	}
+
+	errs := &specErrors{lines: make(map[string]int)}
+	checkFields(root, "", templateFields, errs)
+	for i := 0; root.Kind == yaml.MappingNode && i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		switch key.Value {
+		case "params":
+			if value.Kind != yaml.SequenceNode {
+				break
+			}
+			for j, item := range value.Content {
+				path := fmt.Sprintf("params[%d]", j)
+				errs.lines[path] = item.Line
+				checkFields(item, path, paramFields, errs)
+			}
+		case "job":
+			checkSpecFields(value, "job", errs)
+		}
+	}
+	if err := errs.err(); err != nil {
+		return spec, err
+	}
+
+	if err := decodeNode(root, &spec); err != nil {
+		return spec, err
+	}
+	spec.validate(errs)
+	return spec, errs.err()
+}
+
+// validate checks the template's name and parameters, its job, and that
+// the job only refers to declared parameters
+func (spec templateSpec) validate(errs *specErrors) {
+	if _, ok := errs.lines["name"]; !ok {
+		errs.add("", "name is required")
+	} else if err := jobtemplate.ValidateName(spec.Name); err != nil {
+		errs.add("name", "%v", err)
+	}
+
+	declared := make(map[string]bool)
+	for i, p := range spec.Params {
+		path := fmt.Sprintf("params[%d]", i)
+		if err := jobtemplate.ValidateParamName(p.Name); err != nil {
+			errs.add(path+".name", "%v", err)
+		} else if declared[p.Name] {
+			errs.add(path+".name", "parameter %s is declared twice", p.Name)
+		}
+		declared[p.Name] = true
+		typ, err := jobtemplate.NormalizeType(p.Type)
+		if err != nil {
+			errs.add(path+".type", "%v", err)
+			continue
+		}
+		if p.Default != nil {
+			if _, err := jobtemplate.CheckValue(typ, *p.Default); err != nil {
+				errs.add(path+".default", "%v", err)
+			}
+		}
+	}
+
+	errs.prefix = "job"
+	spec.Job.validate(errs)
+	req := spec.Job.request()
+	for _, s := range templatedStrings(req) {
+		for _, name := range jobtemplate.Placeholders(s) {
+			if !declared[name] {
+				errs.add("", "refers to undeclared parameter {{%s}}", name)
+				declared[name] = true // Report each once
+			}
+		}
+	}
+	errs.prefix = ""
+}
+
+// templatedStrings returns the strings of a job request that may hold
+// placeholders, matching the manager: the command, argv, and the values
+// of env, labels and constraints
+func templatedStrings(req pb.JobRequest) []string {
+	strs := append([]string{req.Command}, req.Argv...)
+	for _, m := range []map[string]string{req.Env, req.Labels, req.Constraints} {
+		for _, key := range sortedKeys(m) {
+			strs = append(strs, m[key])
+		}
+	}
+	return strs
+}
+
+// request converts a validated template spec into a request
+func (spec templateSpec) request() pb.PutTemplateRequest {
+	req := pb.PutTemplateRequest{
+		Name:        spec.Name,
+		Description: spec.Description,
+		Job:         spec.Job.request(),
+	}
+	for _, p := range spec.Params {
+		param := pb.TemplateParam{Name: p.Name, Type: p.Type, Description: p.Description, Required: p.Default == nil}
+		if p.Default != nil {
+			param.DefaultValue = *p.Default
+		}
+		req.Params = append(req.Params, param)
+	}
+	return req
+}
+
+// templateSpecFrom converts a stored template back into a spec
+func templateSpecFrom(t pb.JobTemplate) templateSpec {
+	spec := templateSpec{
+		Name:        t.Name,
+		Description: t.Description,
+		Job:         specFromRequest(t.Job),
+	}
+	for _, p := range t.Params {
+		param := paramSpec{Name: p.Name, Type: p.Type, Description: p.Description}
+		if !p.Required {
+			def := p.DefaultValue
+			param.Default = &def
+		}
+		spec.Params = append(spec.Params, param)
+	}
+	return spec
+}
+
+// manageTemplates runs the templates subcommands
+func manageTemplates(client *managerclient.Client, args []string) {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	switch args[0] {
+	case "list":
+		listTemplates(client)
+	case "get":
+		getTemplate(client, args[1:])
+	case "create":
+		createTemplate(client, args[1:])
+	case "submit":
+		submitFromTemplate(client, args[1:])
+	case "delete":
+		if len(args) != 2 {
+			fmt.Println("Usage: client.exe templates delete <NAME>")
+			os.Exit(1)
+		}
+		deleteTemplate(client, args[1])
+	default:
+		fmt.Println("Usage: client.exe templates list|get|create|submit|delete")
+		os.Exit(1)
+	}
+}
+
+// listTemplates prints a table of every job template
+func listTemplates(client *managerclient.Client) {
+	var resp pb.ListTemplatesResponse
+	if err := client.Call("ManagerService.ListTemplates", pb.ListTemplatesRequest{}, &resp); err != nil {
+		fmt.Printf("Error listing templates: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPARAMS\tUPDATED\tDESCRIPTION")
+	for _, t := range resp.Templates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			t.Name,
+			orDash(formatParams(t.Params)),
+			time.Unix(t.UpdatedAt, 0).Format(time.RFC3339),
+			t.Description)
+	}
+	w.Flush()
+}
+
+// formatParams renders parameters as name:type, with =default for those
+// that have one
+func formatParams(params []pb.TemplateParam) string {
+	out := make([]string, len(params))
+	for i, p := range params {
+		out[i] = p.Name + ":" + p.Type
+		if !p.Required {
+			out[i] += "=" + p.DefaultValue
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// getTemplate prints a template as a file `templates create -f` accepts
+func getTemplate(client *managerclient.Client, args []string) {
+	fs := flag.NewFlagSet("templates get", flag.ExitOnError)
+	output := fs.String("o", "yaml", "Output format: yaml or json")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: client.exe templates get <NAME> [-o yaml|json]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	// Allow flags after the name as well
+	name := fs.Arg(0)
+	if fs.NArg() > 0 {
+		fs.Parse(fs.Args()[1:])
+	}
+	if name == "" || fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *output != "yaml" && *output != "json" {
+		fmt.Printf("Unknown output format %q (want yaml or json)\n", *output)
+		os.Exit(1)
+	}
+
+	var resp pb.JobTemplate
+	if err := client.Call("ManagerService.GetTemplate", pb.GetTemplateRequest{Name: name}, &resp); err != nil {
+		fmt.Printf("Error getting template: %v\n", err)
+		os.Exit(1)
+	}
+	printSpec(templateSpecFrom(resp), *output)
+}
+
+// createTemplate creates or replaces the template described in a file
+func createTemplate(client *managerclient.Client, args []string) {
+	fs := flag.NewFlagSet("templates create", flag.ExitOnError)
+	file := fs.String("f", "", "Template file, YAML or JSON; - reads standard input (required)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: client.exe templates create -f FILE")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *file == "" || fs.NArg() > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	data, err := readSpecFile(*file)
+	if err != nil {
+		fmt.Printf("Error reading template: %v\n", err)
+		os.Exit(1)
+	}
+	spec, err := parseTemplateSpec(data)
+	if err != nil {
+		name := *file
+		if name == "-" {
+			name = "<stdin>"
+		}
+		fmt.Printf("Invalid template %s:\n", name)
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+		os.Exit(1)
+	}
+
+	var resp pb.JobTemplate
+	if err := client.Call("ManagerService.PutTemplate", spec.request(), &resp); err != nil {
+		fmt.Printf("Error creating template: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Template %s saved (%s)\n", resp.Name, orDash(formatParams(resp.Params)))
+}
+
+// submitFromTemplate submits a job from a template with parameter values
+// given as NAME=VALUE arguments
+func submitFromTemplate(client *managerclient.Client, args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Println("Usage: client.exe templates submit <NAME> [PARAM=VALUE ...]")
+		os.Exit(1)
+	}
+	req := pb.SubmitFromTemplateRequest{Name: args[0], Params: make(map[string]string)}
+	for _, arg := range args[1:] {
+		k, v, ok := strings.Cut(arg, "=")
+		if !ok || k == "" {
+			fmt.Printf("Invalid parameter %q (want PARAM=VALUE)\n", arg)
+			os.Exit(1)
+		}
+		req.Params[k] = v
+	}
+
+	var resp pb.JobResponse
+	if err := client.Call("ManagerService.SubmitFromTemplate", req, &resp); err != nil {
+		fmt.Printf("Error submitting job: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Job submitted successfully!\n")
+	fmt.Printf("Job ID: %s\n", resp.JobId)
+	fmt.Printf("Status: %s\n", resp.Status)
+}
+
+// deleteTemplate deletes a template
+func deleteTemplate(client *managerclient.Client, name string) {
+	var resp pb.JobTemplate
+	if err := client.Call("ManagerService.DeleteTemplate", pb.DeleteTemplateRequest{Name: name}, &resp); err != nil {
+		fmt.Printf("Error deleting template: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Template %s deleted\n", resp.Name)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"titan/pkg/managerclient"
+	pb "titan/pkg/proto"
+)
+
+// listWorkers prints a table of every worker registered with the manager
+func listWorkers(client *managerclient.Client) {
+	var req pb.ListWorkersRequest
+	var resp pb.ListWorkersResponse
+	if err := client.Call("ManagerService.ListWorkers", req, &resp); err != nil {
+		fmt.Printf("Error listing workers: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tADDRESS\tSTATUS\tCPU (used/total)\tMEMORY MB (used/total)\tLAST HEARTBEAT\tTASKS\tLABELS")
+	for _, worker := range resp.Workers {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d/%d\t%d/%d\t%s\t%d\t%s\n",
+			worker.WorkerId,
+			worker.Address,
+			workerState(worker),
+			worker.Usage.UsedCpuMillicores, worker.Capacity.TotalCpuMillicores,
+			worker.Usage.UsedMemoryMb, worker.Capacity.TotalMemoryMb,
+			formatAge(worker.LastHeartbeat),
+			len(worker.RunningTasks),
+			orDash(formatLabels(worker.Labels)))
+	}
+	w.Flush()
+}
+
+// showWorker prints the details of a single worker
+func showWorker(client *managerclient.Client, workerID string) {
+	req := pb.GetWorkerRequest{WorkerId: workerID}
+	var resp pb.WorkerStatusResponse
+	if err := client.Call("ManagerService.GetWorker", req, &resp); err != nil {
+		fmt.Printf("Error getting worker: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Worker ID:\t%s\n", resp.WorkerId)
+	fmt.Fprintf(w, "Address:\t%s\n", resp.Address)
+	fmt.Fprintf(w, "Status:\t%s\n", workerState(resp))
+	if resp.RevokedReason != "" {
+		fmt.Fprintf(w, "Revoked:\t%s\n", resp.RevokedReason)
+	}
+	fmt.Fprintf(w, "CPU (millicores):\t%d used / %d total\n", resp.Usage.UsedCpuMillicores, resp.Capacity.TotalCpuMillicores)
+	fmt.Fprintf(w, "Memory (MB):\t%d used / %d total\n", resp.Usage.UsedMemoryMb, resp.Capacity.TotalMemoryMb)
+	fmt.Fprintf(w, "Labels:\t%s\n", orDash(formatLabels(resp.Labels)))
+	fmt.Fprintf(w, "Last Heartbeat:\t%s\n", formatAge(resp.LastHeartbeat))
+	fmt.Fprintf(w, "Registered:\t%s\n", time.Unix(resp.RegisteredAt, 0).Format(time.RFC3339))
+	tasks := "-"
+	if len(resp.RunningTasks) > 0 {
+		tasks = strings.Join(resp.RunningTasks, ", ")
+	}
+	fmt.Fprintf(w, "Running Tasks:\t%s\n", tasks)
+	w.Flush()
+}
+
+// drainWorker stops placing new tasks on a worker, or resumes it
+func drainWorker(client *managerclient.Client, workerID string, resume bool) {
+	req := pb.DrainWorkerRequest{WorkerId: workerID, Resume: resume}
+	var resp pb.WorkerStatusResponse
+	if err := client.Call("ManagerService.DrainWorker", req, &resp); err != nil {
+		fmt.Printf("Error draining worker: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Worker %s is %s with %d running tasks\n", resp.WorkerId, workerState(resp), len(resp.RunningTasks))
+}
+
+// revokeWorker bars a worker from the cluster, or lifts the ban
+func revokeWorker(client *managerclient.Client, workerID, reason string, restore bool) {
+	req := pb.RevokeWorkerRequest{WorkerId: workerID, Reason: reason, Restore: restore}
+	var resp pb.WorkerStatusResponse
+	if err := client.Call("ManagerService.RevokeWorker", req, &resp); err != nil {
+		fmt.Printf("Error revoking worker: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Worker %s is %s\n", resp.WorkerId, workerState(resp))
+}
+
+// workerState is a worker's status, noting if it is revoked or drained
+func workerState(worker pb.WorkerStatusResponse) string {
+	if worker.Revoked {
+		return worker.Status + " (revoked)"
+	}
+	if worker.Draining {
+		return worker.Status + " (draining)"
+	}
+	return worker.Status
+}
+
+// orDash returns s, or "-" if it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// formatAge renders a Unix timestamp as a duration relative to now
+func formatAge(unix int64) string {
+	if unix <= 0 {
+		return "never"
+	}
+	return time.Since(time.Unix(unix, 0)).Round(time.Second).String() + " ago"
+}
+
+// printAttempts prints a table of a job's task attempts
+func printAttempts(attempts []pb.TaskAttempt) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  #\tTASK ID\tWORKER\tSTATUS\tSTARTED\tFINISHED\tEXIT CODE")
+	for _, a := range attempts {
+		fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\t%s\t%d\n",
+			a.Attempt,
+			a.TaskId,
+			a.WorkerId,
+			a.Status,
+			formatTime(a.StartedAt),
+			formatTime(a.FinishedAt),
+			a.ExitCode)
+	}
+	w.Flush()
+}
+
+// formatTime renders a Unix timestamp, or "-" if it is unset
+func formatTime(unix int64) string {
+	if unix <= 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).Format(time.RFC3339)
+}
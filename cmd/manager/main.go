@@ -1,21 +1,101 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"flag"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"titan/pkg/auth"
+	"titan/pkg/certs"
+	"titan/pkg/grpcserver"
 	"titan/pkg/logger"
 	"titan/pkg/manager"
+	"titan/pkg/models"
+	"titan/pkg/rpcserver"
+	"titan/pkg/secrets"
 )
 
 const (
 	defaultPort = "8080"
+
+	// policyReloadInterval is how often the role policy file is checked
+	// for changes
+	policyReloadInterval = 5 * time.Second
 )
 
 func main() {
+	shutdownTimeout := flag.Duration("shutdown-timeout", 15*time.Second, "Time to wait for in-flight RPCs on shutdown")
+	storeBackend := flag.String("store", manager.StoreBackendMemory, "State store backend: memory or bolt")
+	dataDir := flag.String("data-dir", "data/manager", "Directory for durable store backends and Raft state")
+	nodeID := flag.String("node-id", "", "ID of this replica in a Raft manager cluster")
+	peerList := flag.String("peers", "", "Raft manager cluster as id=rpc-address/raft-address,...; replaces --store")
+	retainAge := flag.Duration("retain-age", 0, "Evict finished jobs older than this (0 keeps them)")
+	retainCount := flag.Int("retain-count", 0, "Keep at most this many finished jobs (0 means no limit)")
+	retainStatuses := flag.String("retain-statuses", "", "Comma-separated terminal statuses subject to eviction (default all)")
+	archiveDir := flag.String("archive-dir", "", "Archive evicted jobs as compressed JSONL in this directory")
+	gcInterval := flag.Duration("gc-interval", time.Minute, "How often to enforce job retention")
+	auditDir := flag.String("audit-dir", "", "Record cluster-mutating calls in an audit log in this directory")
+	auditMaxSize := flag.Int64("audit-max-size", 100, "Rotate the audit log when it reaches this many MB")
+	auditMaxFiles := flag.Int("audit-max-files", 0, "Rotated audit logs to keep (0 keeps all)")
+	grpcPort := flag.String("grpc-port", "", "Also serve the API over gRPC on this port")
+	httpPort := flag.String("http-port", "", "Also serve the REST API over HTTP on this port")
+	tlsFiles := certs.RegisterFlags(flag.CommandLine)
+	tokensFile := flag.String("tokens-file", "", "Require API tokens for ManagerService calls, accepting the static tokens in this file")
+	tokenSecretFile := flag.String("token-secret-file", "", "Require API tokens for ManagerService calls, accepting tokens signed with this secret (see titan token)")
+	joinTokensFile := flag.String("join-tokens-file", "", "Admit only workers presenting one of the join tokens in this file, or a worker certificate (see titan join-token)")
+	policyFile := flag.String("policy-file", "", "Bind roles to clients from this file, reloaded when it changes; without it every client may do everything")
+	secretsKeyFile := flag.String("secrets-key-file", "", "Enable job secrets, encrypted with the key in this file; every replica needs the same key (see titan secrets-key)")
+	flag.Parse()
+
+	tlsConfig, err := tlsFiles.Load()
+	if err != nil {
+		logger.Error("Invalid TLS configuration", "error", err)
+		os.Exit(1)
+	}
+
+	tokens, err := loadTokens(*tokensFile, *tokenSecretFile)
+	if err != nil {
+		logger.Error("Invalid API token configuration", "error", err)
+		os.Exit(1)
+	}
+
+	var roles auth.Roles
+	if *policyFile != "" {
+		policy, err := auth.LoadPolicyFile(*policyFile)
+		if err != nil {
+			logger.Error("Invalid role policy", "error", err)
+			os.Exit(1)
+		}
+		go watchPolicy(policy, policyReloadInterval)
+		roles = policy
+	}
+
+	var joinTokens *auth.JoinTokens
+	if *joinTokensFile != "" {
+		if joinTokens, err = auth.LoadJoinTokens(*joinTokensFile); err != nil {
+			logger.Error("Invalid join tokens", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var secretsBox *secrets.Box
+	if *secretsKeyFile != "" {
+		if secretsBox, err = secrets.LoadBox(*secretsKeyFile); err != nil {
+			logger.Error("Invalid secrets key", "error", err)
+			os.Exit(1)
+		}
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = defaultPort
@@ -23,38 +103,202 @@ func main() {
 
 	address := fmt.Sprintf("0.0.0.0:%s", port)
 
-	logger.Info("Starting Titan Manager", "address", address)
+	logger.Info("Starting Titan Manager", "address", address, "tls", tlsConfig != nil, "tokens", tokens != nil, "policy", *policyFile, "join_tokens", joinTokens != nil, "secrets", secretsBox != nil)
+
+	store, err := openStore(*storeBackend, *dataDir, *nodeID, *peerList, tlsConfig)
+	if err != nil {
+		logger.Error("Failed to open store", "error", err)
+		os.Exit(1)
+	}
+
+	retention, err := retentionPolicy(*retainAge, *retainCount, *retainStatuses, *archiveDir, *gcInterval)
+	if err != nil {
+		logger.Error("Invalid retention policy", "error", err)
+		os.Exit(1)
+	}
 
-	server := manager.NewServer()
+	server, err := manager.NewServer(store, manager.Config{
+		Retention:  retention,
+		Audit:      manager.AuditConfig{Dir: *auditDir, MaxSize: *auditMaxSize << 20, MaxFiles: *auditMaxFiles},
+		TLS:        tlsConfig,
+		Tokens:     tokens,
+		Roles:      roles,
+		JoinTokens: joinTokens,
+		Secrets:    secretsBox,
+	})
+	if err != nil {
+		logger.Error("Failed to create manager", "error", err)
+		os.Exit(1)
+	}
 	server.Start()
 
 	// Create net/rpc server
-	rpcServer := server.GetRPCServer()
+	rpcServer := rpcserver.NewPerConn(server.RPCServer)
 
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
 		logger.Error("Failed to listen", "error", err)
 		os.Exit(1)
 	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	var grpcServer *grpc.Server
+	if *grpcPort != "" {
+		grpcServer = grpcserver.NewServer(tlsConfig)
+		server.RegisterGRPC(grpcServer)
+		reflection.Register(grpcServer) // Lets tools such as grpcurl discover the API
+		grpcAddress := fmt.Sprintf("0.0.0.0:%s", *grpcPort)
+		grpcListener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			logger.Error("Failed to listen", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Manager serving gRPC", "address", grpcAddress)
+		go grpcServer.Serve(grpcListener)
+	}
 
+	var httpServer *http.Server
+	if *httpPort != "" {
+		httpServer = &http.Server{
+			Addr:              fmt.Sprintf("0.0.0.0:%s", *httpPort),
+			Handler:           server.HTTPHandler(),
+			ReadHeaderTimeout: 10 * time.Second,
+			TLSConfig:         tlsConfig,
+		}
+		httpListener, err := net.Listen("tcp", httpServer.Addr)
+		if err != nil {
+			logger.Error("Failed to listen", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Manager serving HTTP", "address", httpServer.Addr)
+		if tlsConfig != nil {
+			go httpServer.ServeTLS(httpListener, "", "")
+		} else {
+			go httpServer.Serve(httpListener)
+		}
+	}
+
+	shutdownDone := make(chan struct{})
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
 		logger.Info("Shutting down Manager...")
-		os.Exit(0)
+
+		ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+		defer cancel()
+
+		// Release watchers, then let in-flight calls finish before the
+		// scheduler stops, so none of them hands it work after it has
+		server.Drain()
+		if httpServer != nil {
+			if err := httpServer.Shutdown(ctx); err != nil {
+				logger.Warn("In-flight HTTP requests did not finish before the deadline", "error", err)
+			}
+		}
+		if grpcServer != nil {
+			if err := grpcserver.Shutdown(ctx, grpcServer); err != nil {
+				logger.Warn("In-flight gRPC calls did not finish before the deadline", "error", err)
+			}
+		}
+		if err := rpcServer.Shutdown(ctx); err != nil {
+			logger.Warn("In-flight RPCs did not finish before the deadline", "error", err)
+		}
+		server.Stop()
+		if err := server.Close(); err != nil {
+			logger.Error("Failed to close manager", "error", err)
+		}
+		close(shutdownDone)
 	}()
 
 	logger.Info("Manager listening", "address", address)
 
-	// Accept connections
-	for {
-		conn, err := listener.Accept()
+	// Accept connections until shutdown
+	rpcServer.Serve(listener)
+	<-shutdownDone
+	logger.Info("Manager stopped")
+}
+
+// openStore opens the replicated Raft store if a peer list is given, and
+// the selected single-node backend otherwise
+func openStore(backend, dataDir, nodeID, peerList string, tlsConfig *tls.Config) (manager.Store, error) {
+	if peerList == "" {
+		return manager.OpenStore(backend, dataDir)
+	}
+	peers, err := manager.ParsePeers(peerList)
+	if err != nil {
+		return nil, err
+	}
+	return manager.OpenRaftStore(manager.RaftConfig{
+		NodeID:  nodeID,
+		DataDir: dataDir,
+		Peers:   peers,
+		TLS:     tlsConfig,
+	})
+}
+
+// loadTokens returns the API token authenticator for the given files, or
+// nil if neither is set
+func loadTokens(tokensFile, secretFile string) (auth.Authenticator, error) {
+	var tokens auth.Authenticators
+	if tokensFile != "" {
+		static, err := auth.LoadStaticTokens(tokensFile)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, static)
+	}
+	if secretFile != "" {
+		secret, err := auth.LoadHMACSecret(secretFile)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, auth.NewHMACTokens(secret))
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return tokens, nil
+}
+
+// watchPolicy reloads the role policy whenever its file changes. A policy
+// that fails to load is logged and the previous one stays in force.
+func watchPolicy(policy *auth.PolicyFile, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		reloaded, err := policy.Reload()
 		if err != nil {
-			logger.Error("Accept error", "error", err)
+			logger.Error("Failed to reload role policy; keeping the previous one", "file", policy.Path(), "error", err)
+		} else if reloaded {
+			logger.Info("Reloaded role policy", "file", policy.Path())
+		}
+	}
+}
+
+// retentionPolicy builds the job retention policy from its flags
+func retentionPolicy(maxAge time.Duration, maxJobs int, statuses, archiveDir string, interval time.Duration) (manager.RetentionPolicy, error) {
+	policy := manager.RetentionPolicy{
+		MaxAge:     maxAge,
+		MaxJobs:    maxJobs,
+		ArchiveDir: archiveDir,
+		Interval:   interval,
+	}
+	for _, name := range strings.Split(statuses, ",") {
+		if name = strings.TrimSpace(name); name == "" {
 			continue
 		}
-		go rpcServer.ServeConn(conn)
+		status, err := models.ParseJobStatus(strings.ToUpper(name))
+		if err != nil {
+			return policy, err
+		}
+		if !status.IsTerminal() {
+			return policy, fmt.Errorf("status %s is not terminal", status)
+		}
+		policy.Statuses = append(policy.Statuses, status)
 	}
+	return policy, nil
 }
@@ -1,19 +1,30 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"net/rpc"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
+	"titan/pkg/certs"
+	"titan/pkg/logger"
+	"titan/pkg/managerclient"
 	pb "titan/pkg/proto"
 )
 
 // Orchestrator manages the distributed rendering job
 func main() {
-	managerAddr := "localhost:8080"
+	managerAddr := flag.String("manager", "localhost:8080", "Manager address, or a comma-separated list of manager replicas")
+	transport := flag.String("transport", "rpc", "Protocol for talking to the manager: rpc or grpc")
+	tlsFiles := certs.RegisterFlags(flag.CommandLine)
+	token := flag.String("token", "", "API token for the manager (default $TITAN_TOKEN)")
+	flag.Parse()
+	if *token == "" {
+		*token = os.Getenv("TITAN_TOKEN")
+	}
 	outputDir := "fractals"
 	
 	// Image parameters (4K resolution)
@@ -33,12 +44,19 @@ func main() {
 		panic(err)
 	}
 
-	// Connect to Manager
-	client, err := rpc.Dial("tcp", managerAddr)
+	// Connect to Manager, keeping connection logs out of the progress output
+	logger.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	managerTransport, err := managerclient.ParseTransport(*transport)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	tlsConfig, err := tlsFiles.Load()
 	if err != nil {
-		fmt.Printf("Error connecting to manager: %v\n", err)
+		fmt.Println(err)
 		os.Exit(1)
 	}
+	client := managerclient.NewWithOptions(managerclient.Options{Transport: managerTransport, TLS: tlsConfig, Token: *token}, managerclient.SplitAddrs(*managerAddr)...)
 	defer client.Close()
 
 	fmt.Printf("=== Starting Distributed Fractal Rendering ===\n")
@@ -91,21 +109,16 @@ func main() {
 					return
 				}
 				
-				// Poll for completion
-				for {
-					statusReq := pb.JobStatusRequest{JobId: resp.JobId}
-					var statusResp pb.JobStatusResponse
-					client.Call("ManagerService.GetJobStatus", statusReq, &statusResp)
-					
-					if statusResp.Status == "COMPLETED" {
-						fmt.Printf("[Tile %d,%d] ✅ Finished (Worker: %s)\n", r, c, statusResp.WorkerId)
-						break
-					} else if statusResp.Status == "FAILED" {
-						fmt.Printf("[Tile %d,%d] ❌ Failed: %s\n", r, c, statusResp.Output)
-						break
-					}
-					
-					time.Sleep(500 * time.Millisecond)
+				// Wait for completion
+				statusResp, err := client.WaitForJob(resp.JobId)
+				if err != nil {
+					fmt.Printf("[Tile %d,%d] ❌ Lost track of job: %v\n", r, c, err)
+					return
+				}
+				if statusResp.Status == "COMPLETED" {
+					fmt.Printf("[Tile %d,%d] ✅ Finished (Worker: %s)\n", r, c, statusResp.WorkerId)
+				} else {
+					fmt.Printf("[Tile %d,%d] ❌ %s: %s\n", r, c, statusResp.Status, statusResp.Output)
 				}
 			}(r, c, cmd)
 		}
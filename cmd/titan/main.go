@@ -0,0 +1,258 @@
+// Command titan holds administrative helpers for a Titan cluster
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"titan/pkg/auth"
+	"titan/pkg/certs"
+	"titan/pkg/secrets"
+)
+
+const usage = `Usage: titan <command> [flags]
+
+Commands:
+  certs       Create a development CA and issue node certificates
+  token       Issue a signed API token
+  join-token  Create a token that admits workers
+  secrets-key Create the key managers encrypt job secrets with
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "certs":
+		err = runCerts(os.Args[2:])
+	case "token":
+		err = runToken(os.Args[2:])
+	case "join-token":
+		err = runJoinToken(os.Args[2:])
+	case "secrets-key":
+		err = runSecretsKey(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+// runCerts issues a certificate for every KIND:NAME argument, signed by the
+// CA in the output directory, which is created first if it doesn't exist
+func runCerts(args []string) error {
+	fs := flag.NewFlagSet("certs", flag.ExitOnError)
+	dir := fs.String("dir", "certs", "Directory for the CA and the issued certificates")
+	hosts := fs.String("hosts", "localhost,127.0.0.1", "Comma-separated DNS names and IPs the nodes are reached at")
+	validFor := fs.Duration("valid-for", 365*24*time.Hour, "How long certificates are valid")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: titan certs [flags] KIND:NAME...")
+		fmt.Fprintln(fs.Output(), "\nKIND is manager, worker or client. A worker's NAME must be its worker ID.")
+		fmt.Fprintln(fs.Output(), "Example: titan certs manager:manager worker:worker-1 client:admin")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	type node struct {
+		kind auth.Kind
+		name string
+	}
+	var nodes []node
+	for _, arg := range fs.Args() {
+		kindName, name, ok := strings.Cut(arg, ":")
+		if !ok || name == "" {
+			return fmt.Errorf("invalid node %q (want KIND:NAME)", arg)
+		}
+		kind, err := auth.ParseKind(kindName)
+		if err != nil {
+			return err
+		}
+		if name == "ca" || strings.ContainsAny(name, `/\`) {
+			return fmt.Errorf("invalid node name %q", name)
+		}
+		nodes = append(nodes, node{kind: kind, name: name})
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		return err
+	}
+	ca, err := loadOrCreateCA(*dir, *validFor)
+	if err != nil {
+		return err
+	}
+
+	var hostList []string
+	for _, host := range strings.Split(*hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hostList = append(hostList, host)
+		}
+	}
+	for _, n := range nodes {
+		pair, err := ca.Issue(n.kind, n.name, hostList, *validFor)
+		if err != nil {
+			return fmt.Errorf("failed to issue certificate for %s: %w", n.name, err)
+		}
+		certFile := filepath.Join(*dir, n.name+".crt")
+		keyFile := filepath.Join(*dir, n.name+".key")
+		if err := pair.Save(certFile, keyFile); err != nil {
+			return err
+		}
+		fmt.Printf("Issued %s certificate for %q: %s, %s\n", n.kind, n.name, certFile, keyFile)
+	}
+	return nil
+}
+
+// loadOrCreateCA returns the CA in dir, creating it if there is none
+func loadOrCreateCA(dir string, validFor time.Duration) (*certs.Authority, error) {
+	certFile := filepath.Join(dir, "ca.crt")
+	keyFile := filepath.Join(dir, "ca.key")
+	if _, err := os.Stat(certFile); err == nil {
+		return certs.LoadAuthority(certFile, keyFile)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	ca, err := certs.NewAuthority("Titan development CA", validFor)
+	if err != nil {
+		return nil, err
+	}
+	if err := ca.Save(certFile, keyFile); err != nil {
+		return nil, err
+	}
+	fmt.Printf("Created CA: %s, %s\n", certFile, keyFile)
+	return ca, nil
+}
+
+// runToken prints an API token for NAME signed with the manager's token
+// secret, which is created first if the file doesn't exist
+func runToken(args []string) error {
+	fs := flag.NewFlagSet("token", flag.ExitOnError)
+	secretFile := fs.String("secret-file", "token.secret", "Signing secret shared with the managers (--token-secret-file)")
+	ttl := fs.Duration("ttl", 24*time.Hour, "How long the token is valid (0 never expires)")
+	groups := fs.String("groups", "", "Comma-separated groups the holder belongs to, for role bindings in the manager's --policy-file")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: titan token [flags] NAME")
+		fmt.Fprintln(fs.Output(), "\nNAME is recorded as the owner of the jobs submitted with the token.")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	secret, err := auth.LoadHMACSecret(*secretFile)
+	if errors.Is(err, os.ErrNotExist) {
+		if secret, err = auth.NewHMACSecret(); err != nil {
+			return err
+		}
+		if err := os.WriteFile(*secretFile, append(secret, '\n'), 0o600); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Created token secret: %s\n", *secretFile)
+	} else if err != nil {
+		return err
+	}
+
+	token, err := auth.NewHMACTokens(secret).Issue(fs.Arg(0), auth.SplitGroups(*groups), *ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
+
+// runJoinToken creates a join token, appends it to the manager's join
+// tokens file and prints it
+func runJoinToken(args []string) error {
+	fs := flag.NewFlagSet("join-token", flag.ExitOnError)
+	file := fs.String("file", "join.tokens", "Join tokens file read by the managers (--join-tokens-file)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: titan join-token [flags]")
+		fmt.Fprintln(fs.Output(), "\nPass the printed token to workers with --join-token. Delete its line")
+		fmt.Fprintln(fs.Output(), "from the file and restart the managers to stop admitting new workers with it.")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	token, err := auth.NewJoinToken()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(*file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, token); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Added join token to %s\n", *file)
+	fmt.Println(token)
+	return nil
+}
+
+// runSecretsKey writes a new secrets key file, refusing to replace an
+// existing one: secrets encrypted with the old key would be lost
+func runSecretsKey(args []string) error {
+	fs := flag.NewFlagSet("secrets-key", flag.ExitOnError)
+	file := fs.String("file", "secrets.key", "Key file read by the managers (--secrets-key-file)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: titan secrets-key [flags]")
+		fmt.Fprintln(fs.Output(), "\nCopy the file to every manager replica and keep it safe: secrets stored")
+		fmt.Fprintln(fs.Output(), "by the managers can't be decrypted without it.")
+		fmt.Fprintln(fs.Output(), "\nFlags:")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	key, err := secrets.NewKey()
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(*file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists; remove it first to replace the key", *file)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(key, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Created secrets key: %s\n", *file)
+	return nil
+}
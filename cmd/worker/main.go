@@ -1,14 +1,26 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
+	"time"
 
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"titan/pkg/auth"
+	"titan/pkg/certs"
+	"titan/pkg/grpcserver"
 	"titan/pkg/logger"
+	"titan/pkg/managerclient"
+	"titan/pkg/rpcserver"
 	"titan/pkg/worker"
 )
 
@@ -16,25 +28,80 @@ const (
 	defaultManagerAddr = "localhost:8080"
 )
 
+// labelFlag collects repeated --label key=value flags
+type labelFlag map[string]string
+
+func (l labelFlag) String() string {
+	pairs := make([]string, 0, len(l))
+	for k, v := range l {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (l labelFlag) Set(value string) error {
+	k, v, ok := strings.Cut(value, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	l[k] = v
+	return nil
+}
+
 func main() {
 	workerID := flag.String("id", "", "Worker ID (required)")
 	port := flag.String("port", "8081", "Worker port")
-	managerAddr := flag.String("manager", defaultManagerAddr, "Manager address")
+	managerAddr := flag.String("manager", defaultManagerAddr, "Manager address, or a comma-separated list of manager replicas")
+	stateDir := flag.String("state-dir", "", "Directory for durable worker state (default: data/<id>)")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 30*time.Second, "Time to wait for running tasks on shutdown")
+	transport := flag.String("transport", "rpc", "Protocol for talking to the manager: rpc or grpc")
+	grpcPort := flag.String("grpc-port", "", "Also serve the WorkerService over gRPC on this port")
+	tlsFiles := certs.RegisterFlags(flag.CommandLine)
+	joinToken := flag.String("join-token", "", "Token admitting the worker to a manager that requires one (default $TITAN_JOIN_TOKEN)")
+	labels := labelFlag{}
+	flag.Var(labels, "label", "Label the worker for job constraints, as key=value (repeatable)")
 	flag.Parse()
 
+	if *joinToken == "" {
+		*joinToken = os.Getenv("TITAN_JOIN_TOKEN")
+	}
+
 	if *workerID == "" {
 		logger.Error("Worker ID is required (use --id flag)")
 		os.Exit(1)
 	}
 
+	managerTransport, err := managerclient.ParseTransport(*transport)
+	if err != nil {
+		logger.Error("Invalid --transport", "error", err)
+		os.Exit(1)
+	}
+
+	tlsConfig, err := tlsFiles.Load()
+	if err != nil {
+		logger.Error("Invalid TLS configuration", "error", err)
+		os.Exit(1)
+	}
+	// Only managers may start and stop tasks on this worker
+	var serverTLS *tls.Config
+	if tlsConfig != nil {
+		serverTLS = certs.RequirePeer(tlsConfig, certs.RequireKind(auth.KindManager))
+	}
+
 	address := fmt.Sprintf("localhost:%s", *port)
+	if *stateDir == "" {
+		*stateDir = filepath.Join("data", *workerID)
+	}
 
 	logger.Info("Starting Titan Worker",
 		"worker_id", *workerID,
 		"address", address,
-		"manager", *managerAddr)
+		"manager", *managerAddr,
+		"labels", labels.String(),
+		"tls", tlsConfig != nil)
 
-	server, err := worker.NewServer(*workerID, address, *managerAddr)
+	managerOpts := managerclient.Options{Transport: managerTransport, TLS: tlsConfig}
+	server, err := worker.NewServer(*workerID, address, *joinToken, labels, managerclient.SplitAddrs(*managerAddr), managerOpts, *stateDir)
 	if err != nil {
 		logger.Error("Failed to create worker server", "error", err)
 		os.Exit(1)
@@ -46,32 +113,54 @@ func main() {
 	}
 
 	// Create net/rpc server
-	rpcServer := server.GetRPCServer()
+	rpcServer := rpcserver.New(server.GetRPCServer())
 
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
 		logger.Error("Failed to listen", "error", err)
 		os.Exit(1)
 	}
+	if serverTLS != nil {
+		listener = tls.NewListener(listener, serverTLS)
+	}
+
+	var grpcServer *grpc.Server
+	if *grpcPort != "" {
+		grpcServer = grpcserver.NewServer(serverTLS)
+		server.RegisterGRPC(grpcServer)
+		reflection.Register(grpcServer) // Lets tools such as grpcurl discover the API
+		grpcAddress := fmt.Sprintf("localhost:%s", *grpcPort)
+		grpcListener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			logger.Error("Failed to listen", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Worker serving gRPC", "worker_id", *workerID, "address", grpcAddress)
+		go grpcServer.Serve(grpcListener)
+	}
 
+	shutdownDone := make(chan struct{})
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
 		logger.Info("Shutting down Worker...", "worker_id", *workerID)
-		os.Exit(0)
+		server.Shutdown(*shutdownTimeout)
+
+		// Task reports are done; stop serving the manager
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if grpcServer != nil {
+			grpcserver.Shutdown(ctx, grpcServer)
+		}
+		rpcServer.Shutdown(ctx)
+		close(shutdownDone)
 	}()
 
 	logger.Info("Worker listening", "worker_id", *workerID, "address", address)
 
-	// Accept connections
-	for {
-		conn, err := listener.Accept()
-		if err != nil {
-			logger.Error("Accept error", "error", err)
-			continue
-		}
-		go rpcServer.ServeConn(conn)
-	}
+	// Accept connections until shutdown
+	rpcServer.Serve(listener)
+	<-shutdownDone
 }
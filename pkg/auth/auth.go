@@ -0,0 +1,79 @@
+// Package auth identifies the caller of an RPC. Callers authenticate with a
+// certificate issued by the cluster CA, whose common name is the caller's
+// name and whose organizational unit says what kind of node the caller is,
+// or with an API token (see tokens.go). What a client may do is decided by
+// the role a policy binds to it (see policy.go).
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// Kind is the role a certificate was issued for
+type Kind string
+
+const (
+	KindManager Kind = "manager" // Manager replicas, which dispatch tasks
+	KindWorker  Kind = "worker"  // Workers, named by their worker ID
+	KindClient  Kind = "client"  // Users and tools calling the ManagerService
+)
+
+// ParseKind parses a certificate kind
+func ParseKind(name string) (Kind, error) {
+	switch k := Kind(name); k {
+	case KindManager, KindWorker, KindClient:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown kind %q (want manager, worker or client)", name)
+}
+
+// Caller is the authenticated identity behind a call
+type Caller struct {
+	Name    string
+	Kind    Kind
+	Groups  []string  // Groups named by the caller's token, for role bindings
+	Expires time.Time // When the credential expires; zero if it doesn't
+}
+
+func (c Caller) String() string {
+	return fmt.Sprintf("%s %q", c.Kind, c.Name)
+}
+
+type callerKey struct{}
+
+// NewContext returns a copy of ctx carrying the caller
+func NewContext(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// FromContext returns the caller carried by ctx, if any
+func FromContext(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// FromCertificate returns the caller a certificate identifies. Its kind is
+// the first organizational unit that names one, and empty if none does.
+func FromCertificate(cert *x509.Certificate) Caller {
+	caller := Caller{Name: cert.Subject.CommonName, Expires: cert.NotAfter}
+	for _, unit := range cert.Subject.OrganizationalUnit {
+		if kind, err := ParseKind(unit); err == nil {
+			caller.Kind = kind
+			break
+		}
+	}
+	return caller
+}
+
+// FromTLS returns the caller behind a TLS connection. Only a peer whose
+// certificate chain was verified is identified.
+func FromTLS(state *tls.ConnectionState) (Caller, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return Caller{}, false
+	}
+	return FromCertificate(state.VerifiedChains[0][0]), true
+}
@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Role is what a client may do. Each role includes everything the roles
+// before it may do.
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"    // Read jobs, workers and history
+	RoleSubmitter Role = "submitter" // Also submit jobs and cancel their own
+	RoleOperator  Role = "operator"  // Also cancel any job and drain workers
+	RoleAdmin     Role = "admin"     // Everything
+)
+
+// roleRanks orders the roles from least to most privileged
+var roleRanks = map[Role]int{
+	RoleViewer:    1,
+	RoleSubmitter: 2,
+	RoleOperator:  3,
+	RoleAdmin:     4,
+}
+
+// ParseRole parses a role name
+func ParseRole(name string) (Role, error) {
+	r := Role(name)
+	if _, ok := roleRanks[r]; !ok {
+		return "", fmt.Errorf("unknown role %q (want viewer, submitter, operator or admin)", name)
+	}
+	return r, nil
+}
+
+// Includes reports whether r may do everything other may. The empty role
+// includes nothing.
+func (r Role) Includes(other Role) bool {
+	rank, ok := roleRanks[r]
+	return ok && rank >= roleRanks[other]
+}
+
+// Roles decides which role a caller has
+type Roles interface {
+	// RoleOf returns the caller's role, or "" if it has none
+	RoleOf(caller Caller) Role
+}
+
+// Policy binds roles to users and groups
+type Policy struct {
+	users    map[string]Role
+	groups   map[string]Role
+	everyone Role // Role of every authenticated caller, if any
+}
+
+// ParsePolicy reads a policy. Every line holds a role followed by the
+// subjects it is bound to, separated by whitespace: a user name,
+// group:NAME for the members of a group, or * for every authenticated
+// caller. Blank lines and lines starting with # are ignored. For example:
+//
+//	admin     alice
+//	operator  group:oncall
+//	submitter group:ci bob
+//	viewer    *
+func ParsePolicy(name, text string) (*Policy, error) {
+	p := &Policy{users: make(map[string]Role), groups: make(map[string]Role)}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		role, err := ParseRole(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, lineNo, err)
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s:%d: role %s is bound to no one", name, lineNo, role)
+		}
+		for _, subject := range fields[1:] {
+			if subject == "*" {
+				p.everyone = maxRole(p.everyone, role)
+			} else if group, ok := strings.CutPrefix(subject, "group:"); ok && group != "" {
+				p.groups[group] = maxRole(p.groups[group], role)
+			} else if ok {
+				return nil, fmt.Errorf("%s:%d: empty group name", name, lineNo)
+			} else {
+				p.users[subject] = maxRole(p.users[subject], role)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// RoleOf returns the most privileged role bound to the caller, by name,
+// by any of its groups or to everyone
+func (p *Policy) RoleOf(caller Caller) Role {
+	role := maxRole(p.everyone, p.users[caller.Name])
+	for _, group := range caller.Groups {
+		role = maxRole(role, p.groups[group])
+	}
+	return role
+}
+
+func maxRole(a, b Role) Role {
+	if roleRanks[b] > roleRanks[a] {
+		return b
+	}
+	return a
+}
+
+// PolicyFile is a policy read from a file, which can be reloaded while
+// it is in use
+type PolicyFile struct {
+	path string
+
+	mu      sync.RWMutex
+	policy  *Policy
+	modTime time.Time
+	size    int64
+}
+
+// LoadPolicyFile reads the policy in path
+func LoadPolicyFile(path string) (*PolicyFile, error) {
+	f := &PolicyFile{path: path}
+	if _, err := f.Reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path returns the file the policy is read from
+func (f *PolicyFile) Path() string {
+	return f.path
+}
+
+// Reload rereads the file if it changed since it was last read, and
+// reports whether it did. If the new policy is invalid, the old one stays
+// in force and the error is reported once, not again until the file
+// changes.
+func (f *PolicyFile) Reload() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.policy != nil && info.ModTime().Equal(f.modTime) && info.Size() == f.size {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return false, err
+	}
+	policy, err := ParsePolicy(f.path, string(data))
+	f.modTime, f.size = info.ModTime(), info.Size()
+	if err != nil {
+		return false, err
+	}
+	f.policy = policy
+	return true, nil
+}
+
+func (f *PolicyFile) RoleOf(caller Caller) Role {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.policy.RoleOf(caller)
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestParsePolicyRejects checks that malformed policies are refused rather
+// than read as granting less, or more, than they say
+func TestParsePolicyRejects(t *testing.T) {
+	for name, text := range map[string]string{
+		"unknown role":  "superuser alice",
+		"no subjects":   "admin",
+		"empty group":   "operator group:",
+		"misspelt role": "admin alice\nviewers *",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if p, err := ParsePolicy("policy", text); err == nil {
+				t.Errorf("ParsePolicy(%q) = %+v, want an error", text, p)
+			}
+		})
+	}
+}
+
+// TestPolicyRoleOf checks that callers get the most privileged role bound
+// to them, and no role if none is
+func TestPolicyRoleOf(t *testing.T) {
+	p, err := ParsePolicy("policy", "# roles\nadmin alice\noperator group:oncall\nsubmitter group:ci bob\n")
+	if err != nil {
+		t.Fatalf("ParsePolicy: %v", err)
+	}
+	cases := []struct {
+		caller Caller
+		want   Role
+	}{
+		{Caller{Name: "alice"}, RoleAdmin},
+		{Caller{Name: "bob"}, RoleSubmitter},
+		{Caller{Name: "bob", Groups: []string{"oncall"}}, RoleOperator},
+		{Caller{Name: "carol", Groups: []string{"ci"}}, RoleSubmitter},
+		{Caller{Name: "mallory"}, ""},
+		{Caller{Name: "mallory", Groups: []string{"group:oncall", "admin"}}, ""},
+		{Caller{Name: "group:oncall"}, ""},
+	}
+	for _, tc := range cases {
+		if got := p.RoleOf(tc.caller); got != tc.want {
+			t.Errorf("RoleOf(%s, groups %v) = %q, want %q", tc.caller, tc.caller.Groups, got, tc.want)
+		}
+	}
+	if role := p.RoleOf(Caller{Name: "mallory"}); role.Includes(RoleViewer) {
+		t.Errorf("a caller without a role includes %s", RoleViewer)
+	}
+
+	everyone, err := ParsePolicy("policy", "viewer *\nadmin alice")
+	if err != nil {
+		t.Fatalf("ParsePolicy: %v", err)
+	}
+	if got := everyone.RoleOf(Caller{Name: "mallory"}); got != RoleViewer {
+		t.Errorf("RoleOf(anyone) = %q, want %q", got, RoleViewer)
+	}
+}
+
+// TestPolicyFileReload checks that a reload picks up changes, and that a
+// broken policy leaves the previous one in force
+func TestPolicyFileReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy")
+	write := func(text string, age time.Duration) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		// Give each version its own modification time, however coarse the
+		// file system's clock
+		mtime := time.Now().Add(-age)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("admin alice\n", 2*time.Hour)
+	f, err := LoadPolicyFile(path)
+	if err != nil {
+		t.Fatalf("LoadPolicyFile: %v", err)
+	}
+	if changed, err := f.Reload(); changed || err != nil {
+		t.Errorf("Reload of an unchanged file = %v, %v", changed, err)
+	}
+
+	write("viewer alice\n", time.Hour)
+	if changed, err := f.Reload(); !changed || err != nil {
+		t.Fatalf("Reload = %v, %v; want the new policy", changed, err)
+	}
+	if got := f.RoleOf(Caller{Name: "alice"}); got != RoleViewer {
+		t.Fatalf("alice is %q after reload, want %q", got, RoleViewer)
+	}
+
+	write("admin alice\nroot mallory\n", 0)
+	if changed, err := f.Reload(); changed || err == nil {
+		t.Fatalf("Reload of a broken policy = %v, %v; want an error", changed, err)
+	}
+	if got := f.RoleOf(Caller{Name: "alice"}); got != RoleViewer {
+		t.Errorf("alice is %q after a failed reload, want the old %q", got, RoleViewer)
+	}
+	if changed, err := f.Reload(); changed || err != nil {
+		t.Errorf("second Reload of the broken policy = %v, %v; want it reported once", changed, err)
+	}
+
+	os.Remove(path)
+	if _, err := f.Reload(); err == nil {
+		t.Error("Reload of a deleted policy succeeded")
+	}
+	if got := f.RoleOf(Caller{Name: "alice"}); got != RoleViewer {
+		t.Errorf("alice is %q after the policy was deleted, want the old %q", got, RoleViewer)
+	}
+}
@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// hmacTokenPrefix starts every token issued by HMACTokens
+const hmacTokenPrefix = "titan."
+
+var (
+	// ErrUnknownToken is returned for tokens no authenticator recognizes
+	ErrUnknownToken = errors.New("unknown token")
+
+	// ErrTokenExpired is returned for signed tokens past their expiry
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// TokenClaims is what a valid token says about its holder
+type TokenClaims struct {
+	Subject   string    // Name of the holder
+	Groups    []string  // Groups the holder belongs to
+	ExpiresAt time.Time // Zero if the token doesn't expire
+}
+
+// Authenticator checks API tokens
+type Authenticator interface {
+	Authenticate(token string) (TokenClaims, error)
+}
+
+// Authenticators accepts a token if any of its authenticators does
+type Authenticators []Authenticator
+
+func (as Authenticators) Authenticate(token string) (TokenClaims, error) {
+	err := ErrUnknownToken
+	for _, a := range as {
+		claims, aerr := a.Authenticate(token)
+		if aerr == nil {
+			return claims, nil
+		}
+		if !errors.Is(aerr, ErrUnknownToken) {
+			// Recognized but rejected, e.g. expired; report that
+			err = aerr
+		}
+	}
+	return TokenClaims{}, err
+}
+
+// StaticTokens is a fixed set of tokens that never expire
+type StaticTokens struct {
+	claims map[[sha256.Size]byte]TokenClaims // By token hash, so lookups don't leak timing
+}
+
+// LoadStaticTokens reads a tokens file. Every line holds a token, the name
+// of its holder and optionally a comma-separated list of the holder's
+// groups, separated by whitespace; blank lines and lines starting with #
+// are ignored.
+func LoadStaticTokens(path string) (*StaticTokens, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := &StaticTokens{claims: make(map[[sha256.Size]byte]TokenClaims)}
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 && len(fields) != 3 {
+			return nil, fmt.Errorf("%s:%d: want a token, a name and optionally groups", path, lineNo)
+		}
+		hash := sha256.Sum256([]byte(fields[0]))
+		if _, ok := t.claims[hash]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate token", path, lineNo)
+		}
+		claims := TokenClaims{Subject: fields[1]}
+		if len(fields) == 3 {
+			claims.Groups = SplitGroups(fields[2])
+		}
+		t.claims[hash] = claims
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *StaticTokens) Authenticate(token string) (TokenClaims, error) {
+	claims, ok := t.claims[sha256.Sum256([]byte(token))]
+	if !ok {
+		return TokenClaims{}, ErrUnknownToken
+	}
+	return claims, nil
+}
+
+// JoinTokens is the set of tokens that admit workers to the cluster
+type JoinTokens struct {
+	hashes map[[sha256.Size]byte]bool // By token hash, so lookups don't leak timing
+}
+
+// LoadJoinTokens reads a join tokens file, one token per line; blank lines
+// and lines starting with # are ignored
+func LoadJoinTokens(path string) (*JoinTokens, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := &JoinTokens{hashes: make(map[[sha256.Size]byte]bool)}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		t.hashes[sha256.Sum256([]byte(line))] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(t.hashes) == 0 {
+		return nil, fmt.Errorf("%s holds no join tokens", path)
+	}
+	return t, nil
+}
+
+// NewJoinToken returns a random join token
+func NewJoinToken() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "join." + base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// Check reports whether token admits a worker
+func (t *JoinTokens) Check(token string) bool {
+	return token != "" && t.hashes[sha256.Sum256([]byte(token))]
+}
+
+// HMACTokens issues and checks self-contained tokens signed with a shared
+// secret, so any manager holding the secret accepts them without a lookup.
+// A token is "titan.<claims>.<signature>", both parts base64url encoded.
+type HMACTokens struct {
+	secret []byte
+}
+
+// hmacClaims is the signed part of a token
+type hmacClaims struct {
+	Subject   string   `json:"sub"`
+	Groups    []string `json:"grp,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"` // Unix timestamp
+}
+
+// NewHMACTokens creates an authenticator for tokens signed with secret
+func NewHMACTokens(secret []byte) *HMACTokens {
+	return &HMACTokens{secret: secret}
+}
+
+// LoadHMACSecret reads a token signing secret from a file
+func LoadHMACSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	secret := []byte(strings.TrimSpace(string(data)))
+	if len(secret) < 32 {
+		return nil, fmt.Errorf("token secret in %s is too short (want at least 32 bytes)", path)
+	}
+	return secret, nil
+}
+
+// NewHMACSecret returns a random secret, hex encoded
+func NewHMACSecret() ([]byte, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	return []byte(hex.EncodeToString(raw)), nil
+}
+
+// Issue signs a token for subject, a member of groups. It expires after
+// ttl, or never if ttl is zero.
+func (h *HMACTokens) Issue(subject string, groups []string, ttl time.Duration) (string, error) {
+	claims := hmacClaims{Subject: subject, Groups: groups}
+	if ttl > 0 {
+		claims.ExpiresAt = time.Now().Add(ttl).Unix()
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return hmacTokenPrefix + body + "." + base64.RawURLEncoding.EncodeToString(h.sign(body)), nil
+}
+
+func (h *HMACTokens) Authenticate(token string) (TokenClaims, error) {
+	rest, ok := strings.CutPrefix(token, hmacTokenPrefix)
+	if !ok {
+		return TokenClaims{}, ErrUnknownToken
+	}
+	body, sig, ok := strings.Cut(rest, ".")
+	if !ok {
+		return TokenClaims{}, fmt.Errorf("%w: malformed", ErrUnknownToken)
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, h.sign(body)) {
+		return TokenClaims{}, fmt.Errorf("%w: bad signature", ErrUnknownToken)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return TokenClaims{}, fmt.Errorf("%w: malformed", ErrUnknownToken)
+	}
+	var claims hmacClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Subject == "" {
+		return TokenClaims{}, fmt.Errorf("%w: malformed", ErrUnknownToken)
+	}
+	result := TokenClaims{Subject: claims.Subject, Groups: claims.Groups}
+	if claims.ExpiresAt != 0 {
+		result.ExpiresAt = time.Unix(claims.ExpiresAt, 0)
+		if !time.Now().Before(result.ExpiresAt) {
+			return TokenClaims{}, fmt.Errorf("%w at %s", ErrTokenExpired, result.ExpiresAt.Format(time.RFC3339))
+		}
+	}
+	return result, nil
+}
+
+func (h *HMACTokens) sign(body string) []byte {
+	mac := hmac.New(sha256.New, h.secret)
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+type tokenKey struct{}
+
+// NewTokenContext returns a copy of ctx carrying the token a call was made
+// with. The token is checked by whoever serves the call.
+func NewTokenContext(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
+}
+
+// TokenFromContext returns the token carried by ctx, if any
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey{}).(string)
+	return token, ok && token != ""
+}
+
+// SplitGroups parses a comma-separated list of groups
+func SplitGroups(list string) []string {
+	var groups []string
+	for _, group := range strings.Split(list, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// ParseBearer extracts the token from an "Authorization: Bearer" value
+func ParseBearer(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
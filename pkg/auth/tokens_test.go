@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// signToken returns a token for claims signed with h's secret, which Issue
+// can't produce for claims that are already expired
+func signToken(t *testing.T, h *HMACTokens, claims hmacClaims) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return hmacTokenPrefix + body + "." + base64.RawURLEncoding.EncodeToString(h.sign(body))
+}
+
+// TestHMACTokensReject checks that expired, tampered, foreign and malformed
+// tokens are all refused
+func TestHMACTokensReject(t *testing.T) {
+	h := NewHMACTokens(testSecret)
+	valid, err := h.Issue("alice", []string{"oncall"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	claims, err := h.Authenticate(valid)
+	if err != nil || claims.Subject != "alice" || len(claims.Groups) != 1 || claims.ExpiresAt.IsZero() {
+		t.Fatalf("Authenticate(valid) = %+v, %v", claims, err)
+	}
+
+	body, sig, _ := strings.Cut(strings.TrimPrefix(valid, hmacTokenPrefix), ".")
+	forged, _ := json.Marshal(hmacClaims{Subject: "mallory", Groups: []string{"admins"}})
+	foreign, err := NewHMACTokens([]byte("another secret, just as long....")).Issue("alice", nil, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	denied := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"expired", signToken(t, h, hmacClaims{Subject: "alice", ExpiresAt: time.Now().Add(-time.Minute).Unix()}), ErrTokenExpired},
+		{"claims swapped", hmacTokenPrefix + base64.RawURLEncoding.EncodeToString(forged) + "." + sig, ErrUnknownToken},
+		{"signature altered", hmacTokenPrefix + body + "." + base64.RawURLEncoding.EncodeToString([]byte("not the signature")), ErrUnknownToken},
+		{"signature dropped", hmacTokenPrefix + body, ErrUnknownToken},
+		{"signed with another secret", foreign, ErrUnknownToken},
+		{"no subject", signToken(t, h, hmacClaims{Groups: []string{"admins"}}), ErrUnknownToken},
+		{"not an HMAC token", "alice", ErrUnknownToken},
+		{"empty", "", ErrUnknownToken},
+	}
+	for _, tc := range denied {
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := h.Authenticate(tc.token)
+			if !errors.Is(err, tc.want) {
+				t.Errorf("Authenticate = %+v, %v; want %v", claims, err, tc.want)
+			}
+		})
+	}
+}
+
+// TestAuthenticatorsReportExpiry checks that a token one authenticator
+// recognizes but refuses is reported as such, not as unknown
+func TestAuthenticatorsReportExpiry(t *testing.T) {
+	h := NewHMACTokens(testSecret)
+	as := Authenticators{&StaticTokens{}, h}
+	expired := signToken(t, h, hmacClaims{Subject: "alice", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	if _, err := as.Authenticate(expired); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("Authenticate(expired) = %v, want %v", err, ErrTokenExpired)
+	}
+	if _, err := as.Authenticate("unknown"); !errors.Is(err, ErrUnknownToken) {
+		t.Errorf("Authenticate(unknown) = %v, want %v", err, ErrUnknownToken)
+	}
+}
+
+// TestJoinTokensCheck checks that only the listed join tokens admit a
+// worker
+func TestJoinTokensCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "join-tokens")
+	if err := os.WriteFile(path, []byte("# workers\njoin.one\n\n  join.two  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := LoadJoinTokens(path)
+	if err != nil {
+		t.Fatalf("LoadJoinTokens: %v", err)
+	}
+	for token, want := range map[string]bool{
+		"join.one":   true,
+		"join.two":   true,
+		"join.three": false,
+		"join.on":    false,
+		"# workers":  false,
+		"":           false,
+	} {
+		if got := tokens.Check(token); got != want {
+			t.Errorf("Check(%q) = %v, want %v", token, got, want)
+		}
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	if err := os.WriteFile(empty, []byte("# nothing yet\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadJoinTokens(empty); err == nil {
+		t.Error("LoadJoinTokens accepted a file without tokens")
+	}
+}
@@ -0,0 +1,154 @@
+// Package grpcserver serves the net/rpc style handlers of the manager and
+// worker over gRPC, using the messages generated from proto/titan.proto
+package grpcserver
+
+import (
+	"context"
+	"crypto/tls"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"titan/pkg/auth"
+	pb "titan/pkg/proto"
+)
+
+// NewServer creates a gRPC server. With a TLS configuration it serves
+// TLS only. The context of every call carries the caller identified by the
+// client's certificate, and the bearer token sent in its metadata, if any.
+func NewServer(cfg *tls.Config) *grpc.Server {
+	opts := []grpc.ServerOption{grpc.UnaryInterceptor(withCaller), grpc.StreamInterceptor(withStreamCaller)}
+	if cfg != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg)))
+	}
+	return grpc.NewServer(opts...)
+}
+
+// withCaller adds the caller behind the call's connection and the call's
+// token to its context
+func withCaller(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(callerContext(ctx), req)
+}
+
+// withStreamCaller is withCaller for streaming calls
+func withStreamCaller(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &callerStream{ServerStream: stream, ctx: callerContext(stream.Context())})
+}
+
+// callerStream is a server stream whose context carries the caller
+type callerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *callerStream) Context() context.Context {
+	return s.ctx
+}
+
+// callerContext adds the caller behind a call's connection and the call's
+// token to the call's context
+func callerContext(ctx context.Context) context.Context {
+	if p, ok := peer.FromContext(ctx); ok {
+		if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			if caller, ok := auth.FromTLS(&tlsInfo.State); ok {
+				ctx = auth.NewContext(ctx, caller)
+			}
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			if token, ok := auth.ParseBearer(value); ok {
+				ctx = auth.NewTokenContext(ctx, token)
+				break
+			}
+		}
+	}
+	return ctx
+}
+
+// Handle serves one gRPC call with a net/rpc style handler: it converts the
+// generated request message in into the handler's request type, calls the
+// handler and converts its reply into out. Handler errors are returned with
+// the status code chosen by code, or Unknown if code is nil.
+func Handle[Req, Resp any, Out any](in any, out Out, handler func(Req, *Resp) error, code func(error) codes.Code) (Out, error) {
+	return HandleContext(context.Background(), in, out, func(_ context.Context, req Req, resp *Resp) error {
+		return handler(req, resp)
+	}, code)
+}
+
+// HandleContext is Handle for handlers that take the call's context
+func HandleContext[Req, Resp any, Out any](ctx context.Context, in any, out Out, handler func(context.Context, Req, *Resp) error, code func(error) codes.Code) (Out, error) {
+	var zero Out
+	var req Req
+	if err := pb.Convert(&req, in); err != nil {
+		return zero, status.Error(codes.Internal, err.Error())
+	}
+	var resp Resp
+	if err := handler(ctx, req, &resp); err != nil {
+		c := codes.Unknown
+		if code != nil {
+			c = code(err)
+		}
+		return zero, status.Error(c, err.Error())
+	}
+	if err := pb.Convert(out, &resp); err != nil {
+		return zero, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+// HandleStream serves one server-streaming gRPC call with a handler that
+// sends plain responses: it converts the request message in for the
+// handler, and each response the handler sends into a new message from
+// newOut, which it passes to send. Errors are mapped as by Handle.
+func HandleStream[Req, Resp any, Out any](ctx context.Context, in any, newOut func() Out, send func(Out) error, handler func(context.Context, Req, func(*Resp) error) error, code func(error) codes.Code) error {
+	var req Req
+	if err := pb.Convert(&req, in); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	var sendErr error
+	err := handler(ctx, req, func(resp *Resp) error {
+		out := newOut()
+		if err := pb.Convert(out, resp); err != nil {
+			sendErr = status.Error(codes.Internal, err.Error())
+			return sendErr
+		}
+		sendErr = send(out)
+		return sendErr
+	})
+	switch {
+	case err == nil:
+		return nil
+	case err == sendErr:
+		// Already a status, from the stream or the conversion
+		return err
+	}
+	c := codes.Unknown
+	if code != nil {
+		c = code(err)
+	}
+	return status.Error(c, err.Error())
+}
+
+// Shutdown stops server from accepting calls and waits for in-flight calls
+// to finish or for ctx to expire, whichever comes first. All connections
+// are closed before it returns.
+func Shutdown(ctx context.Context, server *grpc.Server) error {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,104 @@
+// Package jobtemplate checks and expands the parameters of job templates.
+// A template's job refers to a parameter with a {{name}} placeholder in
+// its string fields.
+package jobtemplate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Parameter types
+const (
+	TypeString = "string"
+	TypeInt    = "int"
+	TypeFloat  = "float"
+	TypeBool   = "bool"
+)
+
+var (
+	// validName matches template names
+	validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+	// validParamName matches parameter names
+	validParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// placeholder matches a reference to a parameter. Anything else in
+	// braces, such as {{.ID}}, is left alone.
+	placeholder = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+)
+
+// ValidateName checks a template name
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid template name %q (want letters, digits, '.', '_' or '-', at most 128)", name)
+	}
+	return nil
+}
+
+// ValidateParamName checks a parameter name
+func ValidateParamName(name string) error {
+	if !validParamName.MatchString(name) {
+		return fmt.Errorf("invalid parameter name %q (want letters, digits and '_', not starting with a digit)", name)
+	}
+	return nil
+}
+
+// NormalizeType returns the canonical name of a parameter type, which
+// defaults to string
+func NormalizeType(typ string) (string, error) {
+	switch typ {
+	case "", TypeString:
+		return TypeString, nil
+	case TypeInt, TypeFloat, TypeBool:
+		return typ, nil
+	}
+	return "", fmt.Errorf("unknown parameter type %q (want string, int, float or bool)", typ)
+}
+
+// CheckValue checks a value against a parameter type and returns it in
+// canonical form, e.g. "007" as "7" for an int
+func CheckValue(typ, value string) (string, error) {
+	switch typ {
+	case TypeInt:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not an int", value)
+		}
+		return strconv.FormatInt(n, 10), nil
+	case TypeFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a float", value)
+		}
+		return strconv.FormatFloat(f, 'g', -1, 64), nil
+	case TypeBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a bool", value)
+		}
+		return strconv.FormatBool(b), nil
+	}
+	return value, nil
+}
+
+// Placeholders returns the names of the parameters s refers to
+func Placeholders(s string) []string {
+	var names []string
+	for _, m := range placeholder.FindAllStringSubmatch(s, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// Expand replaces each placeholder in s by its parameter's value.
+// Placeholders of parameters without a value are left as they are.
+func Expand(s string, values map[string]string) string {
+	return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+		if value, ok := values[placeholder.FindStringSubmatch(m)[1]]; ok {
+			return value
+		}
+		return m
+	})
+}
@@ -0,0 +1,137 @@
+package manager
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"titan/pkg/logger"
+	"titan/pkg/models"
+)
+
+// archiveFilePattern matches the archive files, one per UTC day
+const archiveFilePattern = "jobs-*.jsonl.gz"
+
+// ErrArchiveDisabled is returned by history searches on a manager that has
+// no archive configured
+var ErrArchiveDisabled = errors.New("job archival is not enabled on this manager")
+
+// ArchivedJob is an evicted job as stored in the archive: one JSON object
+// per line
+type ArchivedJob struct {
+	Job        *models.Job
+	Tasks      []*models.Task
+	ArchivedAt time.Time
+}
+
+// Archive stores evicted jobs as gzip-compressed JSON lines on disk. Each
+// append adds a gzip member to the current day's file, so a crash can at
+// worst lose the member being written.
+type Archive struct {
+	dir string
+	mu  sync.Mutex // Serializes appends
+}
+
+// OpenArchive opens the archive in dir, creating the directory if needed
+func OpenArchive(dir string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	return &Archive{dir: dir}, nil
+}
+
+// Append writes jobs to the archive file for the day of now and syncs it
+func (a *Archive) Append(jobs []ArchivedJob, now time.Time) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	name := filepath.Join(a.dir, "jobs-"+now.UTC().Format("2006-01-02")+".jsonl.gz")
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	zw := gzip.NewWriter(f)
+	enc := json.NewEncoder(zw)
+	for i := range jobs {
+		if err := enc.Encode(&jobs[i]); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return f.Sync()
+}
+
+// Search returns up to limit archived jobs matching f, most recently
+// archived first. A limit of 0 returns every match.
+func (a *Archive) Search(f JobFilter, limit int) ([]ArchivedJob, error) {
+	files, err := filepath.Glob(filepath.Join(a.dir, archiveFilePattern))
+	if err != nil {
+		return nil, err
+	}
+	// Day-stamped names sort chronologically; read the newest first
+	sort.Sort(sort.Reverse(sort.StringSlice(files)))
+
+	var matches []ArchivedJob
+	for _, name := range files {
+		fileMatches, err := searchArchiveFile(name, f)
+		if err != nil {
+			return nil, err
+		}
+		// Lines within a file are oldest first
+		for i := len(fileMatches) - 1; i >= 0; i-- {
+			matches = append(matches, fileMatches[i])
+			if limit > 0 && len(matches) == limit {
+				return matches, nil
+			}
+		}
+	}
+	return matches, nil
+}
+
+// searchArchiveFile returns the matching jobs in one archive file. A
+// truncated last member, left by a crash during Append, ends the file.
+func searchArchiveFile(name string, filter JobFilter) ([]ArchivedJob, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	zr, err := gzip.NewReader(bufio.NewReader(f))
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer zr.Close()
+
+	var matches []ArchivedJob
+	dec := json.NewDecoder(zr)
+	for {
+		var record ArchivedJob
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return matches, nil
+		}
+		if err != nil {
+			logger.Warn("Stopped reading damaged archive file", "file", name, "error", err)
+			return matches, nil
+		}
+		if record.Job != nil && filter.Matches(record.Job) {
+			matches = append(matches, record)
+		}
+	}
+}
@@ -0,0 +1,343 @@
+package manager
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"titan/pkg/auth"
+	"titan/pkg/logger"
+	pb "titan/pkg/proto"
+)
+
+const (
+	// auditFileName is the audit log being written. Rotated files are
+	// renamed to audit-<UTC time>.log, so they sort chronologically.
+	auditFileName    = "audit.log"
+	auditFilePattern = "audit-*.log"
+
+	// redacted replaces secret values in audited arguments
+	redacted = "[REDACTED]"
+)
+
+// ErrAuditDisabled is returned by audit searches on a manager that has no
+// audit log configured
+var ErrAuditDisabled = errors.New("audit logging is not enabled on this manager")
+
+// AuditConfig configures the audit log
+type AuditConfig struct {
+	Dir      string // Directory for the log files; empty disables auditing
+	MaxSize  int64  // Rotate before the log grows beyond this many bytes; 0 never rotates
+	MaxFiles int    // Rotated files to keep; 0 keeps them all
+}
+
+// AuditRecord is one audited call as stored in the log: one JSON object
+// per line
+type AuditRecord struct {
+	Time       time.Time       `json:"time"`
+	Caller     string          `json:"caller,omitempty"`
+	CallerKind auth.Kind       `json:"caller_kind,omitempty"`
+	Method     string          `json:"method"`
+	Target     string          `json:"target,omitempty"`
+	Args       json.RawMessage `json:"args,omitempty"`
+	Result     string          `json:"result"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// AuditFilter selects audit records. Zero fields match everything.
+type AuditFilter struct {
+	Since  time.Time
+	Until  time.Time
+	Caller string
+	Method string
+}
+
+// Matches reports whether the record passes every set criterion
+func (f AuditFilter) Matches(r *AuditRecord) bool {
+	if !f.Since.IsZero() && r.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && r.Time.After(f.Until) {
+		return false
+	}
+	if f.Caller != "" && r.Caller != f.Caller {
+		return false
+	}
+	if f.Method != "" && !strings.EqualFold(r.Method, f.Method) {
+		return false
+	}
+	return true
+}
+
+// AuditLog is an append-only log of cluster-mutating calls, kept apart from
+// the manager's operational log. Every record is synced to disk before the
+// call returns. The log is rotated by size; rotated files are never
+// written again.
+type AuditLog struct {
+	cfg AuditConfig
+
+	mu   sync.Mutex // Serializes appends, rotation and searches
+	file *os.File
+	size int64
+}
+
+// OpenAuditLog opens the audit log in cfg.Dir, creating the directory if
+// needed
+func OpenAuditLog(cfg AuditConfig) (*AuditLog, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	l := &AuditLog{cfg: cfg}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the current log file for appending
+func (l *AuditLog) open() error {
+	f, err := os.OpenFile(filepath.Join(l.cfg.Dir, auditFileName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// Append writes a record to the log and syncs it
+func (l *AuditLog) Append(rec AuditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return errors.New("audit log is closed")
+	}
+	if l.cfg.MaxSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.cfg.MaxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return l.file.Sync()
+}
+
+// rotate moves the current file aside and starts a new one, then drops the
+// oldest rotated files beyond MaxFiles. mu must be held.
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+	l.file = nil
+	rotated := filepath.Join(l.cfg.Dir, "audit-"+time.Now().UTC().Format("20060102T150405.000000000Z")+".log")
+	if err := os.Rename(filepath.Join(l.cfg.Dir, auditFileName), rotated); err != nil {
+		// Keep appending to the current file rather than lose records
+		logger.Error("Failed to rotate audit log", "error", err)
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+
+	if l.cfg.MaxFiles <= 0 {
+		return nil
+	}
+	files, err := l.rotatedFiles()
+	if err != nil {
+		logger.Warn("Failed to list old audit logs", "error", err)
+		return nil
+	}
+	for len(files) > l.cfg.MaxFiles {
+		if err := os.Remove(files[0]); err != nil {
+			logger.Warn("Failed to remove old audit log", "file", files[0], "error", err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+// rotatedFiles returns the rotated log files, oldest first
+func (l *AuditLog) rotatedFiles() ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(l.cfg.Dir, auditFilePattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Search returns up to limit records matching f, newest first. A limit of
+// 0 returns every match.
+func (l *AuditLog) Search(f AuditFilter, limit int) ([]AuditRecord, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := l.rotatedFiles()
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, filepath.Join(l.cfg.Dir, auditFileName))
+
+	var matches []AuditRecord
+	for i := len(files) - 1; i >= 0; i-- {
+		fileMatches, err := searchAuditFile(files[i], f)
+		if err != nil {
+			return nil, err
+		}
+		// Lines within a file are oldest first
+		for j := len(fileMatches) - 1; j >= 0; j-- {
+			matches = append(matches, fileMatches[j])
+			if limit > 0 && len(matches) == limit {
+				return matches, nil
+			}
+		}
+	}
+	return matches, nil
+}
+
+// searchAuditFile returns the matching records in one log file. A damaged
+// line, e.g. left by a crash during Append, ends the file.
+func searchAuditFile(name string, filter AuditFilter) ([]AuditRecord, error) {
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var matches []AuditRecord
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var record AuditRecord
+		err := dec.Decode(&record)
+		if errors.Is(err, io.EOF) {
+			return matches, nil
+		}
+		if err != nil {
+			logger.Warn("Stopped reading damaged audit log", "file", name, "error", err)
+			return matches, nil
+		}
+		if filter.Matches(&record) {
+			matches = append(matches, record)
+		}
+	}
+}
+
+// Close closes the log
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// audit records a cluster-mutating call and its outcome, err, in the
+// audit log, if the manager keeps one. target is the ID of the job or
+// worker the call acted on. A record that can't be written is logged; the
+// call has already taken effect.
+func (s *Server) audit(ctx context.Context, method, target string, req any, err error) {
+	var notLeader *pb.NotLeaderError
+	if s.auditLog == nil || errors.As(err, &notLeader) {
+		// A replica that is not the leader did nothing; the leader audits the retry
+		return
+	}
+	rec := AuditRecord{
+		Time:   time.Now(),
+		Method: method,
+		Target: target,
+		Args:   redactArgs(req),
+		Result: "OK",
+	}
+	if caller, ok, cerr := s.caller(ctx); cerr == nil && ok {
+		rec.Caller, rec.CallerKind = caller.Name, caller.Kind
+	}
+	if err != nil {
+		rec.Result = grpcCode(err).String()
+		rec.Error = err.Error()
+	}
+	if err := s.auditLog.Append(rec); err != nil {
+		logger.Error("Failed to write audit record", "method", method, "target", target, "error", err)
+	}
+}
+
+// redactArgs renders a request as JSON for the audit log. Values of fields
+// whose names mention a token, secret or password are replaced, and so are
+// all environment variable values, which often carry credentials.
+func redactArgs(req any) json.RawMessage {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil
+	}
+	data, err = json.Marshal(redactValue(v))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func redactValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for key, child := range t {
+			switch {
+			case key == "Secrets":
+				// References to stored secrets by name, not their values
+				t[key] = redactValue(child)
+			case isSecretField(key):
+				if child != nil && child != "" {
+					t[key] = redacted
+				}
+			case key == "Env":
+				if env, ok := child.(map[string]any); ok {
+					for name := range env {
+						env[name] = redacted
+					}
+				}
+			default:
+				t[key] = redactValue(child)
+			}
+		}
+	case []any:
+		for i := range t {
+			t[i] = redactValue(t[i])
+		}
+	}
+	return v
+}
+
+// isSecretField reports whether a field name suggests a secret value
+func isSecretField(name string) bool {
+	name = strings.ToLower(name)
+	return strings.Contains(name, "token") || strings.Contains(name, "secret") || strings.Contains(name, "password")
+}
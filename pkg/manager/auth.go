@@ -0,0 +1,212 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"titan/pkg/auth"
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+)
+
+var (
+	// ErrUnauthenticated is returned for calls without valid credentials
+	// when the manager requires them
+	ErrUnauthenticated = errors.New("unauthenticated")
+
+	// ErrPermissionDenied is returned for calls the caller may not make
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// caller returns who made a call: the holder of the call's API token if
+// tokens are enabled and the call carries one, and otherwise the owner of
+// the connection's certificate. It reports false for anonymous calls.
+func (s *Server) caller(ctx context.Context) (auth.Caller, bool, error) {
+	if token, ok := auth.TokenFromContext(ctx); ok && s.tokens != nil {
+		claims, err := s.tokens.Authenticate(token)
+		if err != nil {
+			return auth.Caller{}, false, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+		}
+		return auth.Caller{Name: claims.Subject, Kind: auth.KindClient, Groups: claims.Groups, Expires: claims.ExpiresAt}, true, nil
+	}
+	caller, ok := auth.FromContext(ctx)
+	return caller, ok, nil
+}
+
+// authorize checks that the caller of a call is of the given kind and
+// returns it. Calls without a caller are let through, with a nil caller,
+// unless the manager requires certificates or, for client calls, tokens.
+func (s *Server) authorize(ctx context.Context, kind auth.Kind) (*auth.Caller, error) {
+	caller, ok, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		switch {
+		case kind == auth.KindClient && s.tokens != nil:
+			return nil, fmt.Errorf("%w: an API token is required", ErrUnauthenticated)
+		case s.requireCert:
+			return nil, fmt.Errorf("%w: a client certificate is required", ErrUnauthenticated)
+		}
+		return nil, nil
+	}
+	if caller.Kind != kind {
+		return nil, fmt.Errorf("%w: %s may not call this method", ErrPermissionDenied, caller)
+	}
+	return &caller, nil
+}
+
+// methodRoles is the role each ManagerService method requires when a role
+// policy is in force
+var methodRoles = map[string]auth.Role{
+	"GetJobStatus":  auth.RoleViewer,
+	"ListJobs":      auth.RoleViewer,
+	"WatchJobs":     auth.RoleViewer,
+	"StreamJobs":    auth.RoleViewer,
+	"SearchHistory": auth.RoleViewer,
+	"ListWorkers":   auth.RoleViewer,
+	"GetWorker":     auth.RoleViewer,
+	"SubmitJob":     auth.RoleSubmitter,
+	"CancelJob":     auth.RoleSubmitter, // Operators may cancel others' jobs
+	"DrainWorker":   auth.RoleOperator,
+	"RevokeWorker":  auth.RoleAdmin,
+	"SearchAudit":   auth.RoleAdmin,
+	"ListSecrets":   auth.RoleSubmitter,
+	"PutSecret":     auth.RoleAdmin,
+	"DeleteSecret":  auth.RoleAdmin,
+
+	"GetTemplate":        auth.RoleViewer,
+	"ListTemplates":      auth.RoleViewer,
+	"PutTemplate":        auth.RoleOperator,
+	"DeleteTemplate":     auth.RoleOperator,
+	"SubmitFromTemplate": auth.RoleSubmitter,
+}
+
+// authorizeClient checks that a client may call the ManagerService method
+// and returns it with its role. Without a role policy every client is an
+// admin; with one, anonymous calls are rejected, since they have no role.
+func (s *Server) authorizeClient(ctx context.Context, method string) (*auth.Caller, auth.Role, error) {
+	caller, err := s.authorize(ctx, auth.KindClient)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.roles == nil {
+		return caller, auth.RoleAdmin, nil
+	}
+	if caller == nil {
+		return nil, "", fmt.Errorf("%w: %s requires a token or client certificate to check its role", ErrUnauthenticated, method)
+	}
+	need, ok := methodRoles[method]
+	if !ok {
+		need = auth.RoleAdmin
+	}
+	role := s.roles.RoleOf(*caller)
+	if !role.Includes(need) {
+		have := "no role"
+		if role != "" {
+			have = "role " + string(role)
+		}
+		return nil, "", fmt.Errorf("%w: %s has %s; %s requires %s", ErrPermissionDenied, caller, have, method, need)
+	}
+	return caller, role, nil
+}
+
+// authorizeWorker checks that the caller is the worker workerID and
+// returns it, or nil if the call has no certificate. A worker is bound to
+// the common name of its certificate, so it can't register, heartbeat or
+// deregister as another worker.
+func (s *Server) authorizeWorker(ctx context.Context, workerID string) (*auth.Caller, error) {
+	caller, err := s.authorize(ctx, auth.KindWorker)
+	if err != nil {
+		return nil, err
+	}
+	if caller != nil && caller.Name != workerID {
+		return nil, fmt.Errorf("%w: %s may not act as worker %q", ErrPermissionDenied, caller, workerID)
+	}
+	return caller, nil
+}
+
+// authorizeWorkerToken is authorizeWorker for calls that name the worker
+// they act for. Without a worker certificate the call must carry a valid
+// join token if the manager requires them, or anyone could deregister or
+// heartbeat any worker.
+func (s *Server) authorizeWorkerToken(ctx context.Context, workerID, joinToken string) error {
+	caller, err := s.authorizeWorker(ctx, workerID)
+	if err != nil {
+		return err
+	}
+	if caller == nil && s.joinTokens != nil && !s.joinTokens.Check(joinToken) {
+		return fmt.Errorf("%w: worker %q needs a valid join token or worker certificate", ErrUnauthenticated, workerID)
+	}
+	return nil
+}
+
+// reportingWorker returns the worker a task status update comes from and
+// whether its identity was proven. A worker certificate proves the caller's
+// name; otherwise the update names its worker, which needs a valid join
+// token if the manager requires them. Without either, the named worker is
+// taken on trust, and may be empty.
+func (s *Server) reportingWorker(caller *auth.Caller, req pb.TaskStatusUpdate) (string, bool, error) {
+	switch {
+	case caller != nil:
+		if req.WorkerId != "" && req.WorkerId != caller.Name {
+			return "", false, fmt.Errorf("%w: %s may not act as worker %q", ErrPermissionDenied, caller, req.WorkerId)
+		}
+		return caller.Name, true, nil
+	case s.joinTokens != nil:
+		if req.WorkerId == "" || !s.joinTokens.Check(req.JoinToken) {
+			return "", false, fmt.Errorf("%w: task status needs a worker ID with a valid join token or worker certificate", ErrUnauthenticated)
+		}
+		return req.WorkerId, true, nil
+	}
+	return req.WorkerId, false, nil
+}
+
+// admitWorker checks that a registering worker may join the cluster: it
+// must not be revoked and, if join tokens are required, must present one
+// or a worker certificate
+func (s *Server) admitWorker(caller *auth.Caller, req pb.WorkerInfo, existing *models.Worker) error {
+	if existing != nil && existing.Revoked {
+		return errRevoked(existing)
+	}
+	if s.joinTokens != nil && caller == nil && !s.joinTokens.Check(req.JoinToken) {
+		return fmt.Errorf("%w: worker %q needs a valid join token or worker certificate", ErrUnauthenticated, req.WorkerId)
+	}
+	return nil
+}
+
+// errRevoked is the error for calls from a revoked worker
+func errRevoked(worker *models.Worker) error {
+	if worker.RevokedReason != "" {
+		return fmt.Errorf("%w: worker %q has been revoked: %s", ErrPermissionDenied, worker.ID, worker.RevokedReason)
+	}
+	return fmt.Errorf("%w: worker %q has been revoked", ErrPermissionDenied, worker.ID)
+}
+
+// Authenticate checks a token and reports who it identifies. Without a
+// token it reports who the call is authenticated as, if anyone. Any replica
+// answers, so clients can authenticate before they find the leader.
+func (s *Server) Authenticate(ctx context.Context, req pb.AuthRequest, resp *pb.AuthResponse) error {
+	if req.Token != "" {
+		ctx = auth.NewTokenContext(ctx, req.Token)
+	}
+	caller, ok, err := s.caller(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		*resp = pb.AuthResponse{}
+		return nil
+	}
+	*resp = pb.AuthResponse{
+		Name:      caller.Name,
+		Kind:      string(caller.Kind),
+		ExpiresAt: unixOrZero(caller.Expires),
+		Groups:    caller.Groups,
+	}
+	if caller.Kind == auth.KindClient && s.roles != nil {
+		resp.Role = string(s.roles.RoleOf(caller))
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"titan/pkg/auth"
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+)
+
+const testJoinToken = "join.test-token"
+
+// newJoinTokens returns join tokens admitting only the given tokens
+func newJoinTokens(t *testing.T, tokens ...string) *auth.JoinTokens {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "join-tokens")
+	if err := os.WriteFile(path, []byte(strings.Join(tokens, "\n")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	joinTokens, err := auth.LoadJoinTokens(path)
+	if err != nil {
+		t.Fatalf("LoadJoinTokens: %v", err)
+	}
+	return joinTokens
+}
+
+// newTestServer returns a manager on a memory store that isn't started
+func newTestServer(t *testing.T, cfg Config) *Server {
+	t.Helper()
+	s, err := NewServer(NewMemoryStore(), cfg)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// asCaller returns a context authenticated by a certificate of name
+func asCaller(name string, kind auth.Kind) context.Context {
+	return auth.NewContext(context.Background(), auth.Caller{Name: name, Kind: kind})
+}
+
+// TestWorkerCallsNeedJoinToken checks that with join tokens required, a
+// worker can only be heartbeated or deregistered with a valid token or its
+// own certificate
+func TestWorkerCallsNeedJoinToken(t *testing.T) {
+	s := newTestServer(t, Config{JoinTokens: newJoinTokens(t, testJoinToken)})
+	info := pb.WorkerInfo{WorkerId: "w1", Address: "127.0.0.1:1", JoinToken: testJoinToken}
+	if err := s.RegisterWorker(context.Background(), info, &pb.RegistrationResponse{}); err != nil {
+		t.Fatalf("RegisterWorker: %v", err)
+	}
+
+	denied := []struct {
+		name  string
+		ctx   context.Context
+		token string
+		want  error
+	}{
+		{"no token", context.Background(), "", ErrUnauthenticated},
+		{"bad token", context.Background(), "join.wrong", ErrUnauthenticated},
+		{"another worker's certificate", asCaller("w2", auth.KindWorker), "", ErrPermissionDenied},
+		{"client certificate", asCaller("w1", auth.KindClient), testJoinToken, ErrPermissionDenied},
+	}
+	for _, tc := range denied {
+		t.Run(tc.name, func(t *testing.T) {
+			heartbeat := pb.HeartbeatRequest{WorkerId: "w1", JoinToken: tc.token}
+			if err := s.Heartbeat(tc.ctx, heartbeat, &pb.HeartbeatResponse{}); !errors.Is(err, tc.want) {
+				t.Errorf("Heartbeat = %v, want %v", err, tc.want)
+			}
+			deregister := pb.DeregisterRequest{WorkerId: "w1", JoinToken: tc.token}
+			if err := s.DeregisterWorker(tc.ctx, deregister, &pb.Ack{}); !errors.Is(err, tc.want) {
+				t.Errorf("DeregisterWorker = %v, want %v", err, tc.want)
+			}
+			if _, ok := s.store.GetWorker("w1"); !ok {
+				t.Fatal("worker was deregistered")
+			}
+		})
+	}
+
+	var resp pb.HeartbeatResponse
+	if err := s.Heartbeat(asCaller("w1", auth.KindWorker), pb.HeartbeatRequest{WorkerId: "w1"}, &resp); err != nil || !resp.Acknowledged {
+		t.Errorf("Heartbeat with the worker's certificate = %v, %+v", err, resp)
+	}
+	if err := s.Heartbeat(context.Background(), pb.HeartbeatRequest{WorkerId: "w1", JoinToken: testJoinToken}, &resp); err != nil || !resp.Acknowledged {
+		t.Errorf("Heartbeat with a join token = %v, %+v", err, resp)
+	}
+	if err := s.DeregisterWorker(context.Background(), pb.DeregisterRequest{WorkerId: "w1", JoinToken: testJoinToken}, &pb.Ack{}); err != nil {
+		t.Errorf("DeregisterWorker with a join token: %v", err)
+	}
+	if _, ok := s.store.GetWorker("w1"); ok {
+		t.Error("worker still registered after deregistering")
+	}
+}
+
+// TestAuthorizeClientDenies checks who a role policy turns away: anonymous
+// callers, bad tokens, callers that aren't clients, and clients whose role
+// is too low for the method or who have none
+func TestAuthorizeClientDenies(t *testing.T) {
+	tokens := auth.NewHMACTokens([]byte("0123456789abcdef0123456789abcdef"))
+	issue := func(subject string, ttl time.Duration) context.Context {
+		t.Helper()
+		token, err := tokens.Issue(subject, nil, ttl)
+		if err != nil {
+			t.Fatalf("Issue: %v", err)
+		}
+		return auth.NewTokenContext(context.Background(), token)
+	}
+	policy, err := auth.ParsePolicy("policy", "admin alice\nsubmitter bob\nviewer carol\n")
+	if err != nil {
+		t.Fatalf("ParsePolicy: %v", err)
+	}
+	s := newTestServer(t, Config{Tokens: tokens, Roles: policy})
+
+	forged, err := auth.NewHMACTokens([]byte("not the manager's secret, either")).Issue("alice", nil, time.Hour)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	denied := []struct {
+		name   string
+		ctx    context.Context
+		method string
+		want   error
+	}{
+		{"anonymous", context.Background(), "ListJobs", ErrUnauthenticated},
+		// Expiry is kept in whole seconds, so this token is already past it
+		{"expired token", issue("alice", time.Nanosecond), "ListJobs", ErrUnauthenticated},
+		{"forged token", auth.NewTokenContext(context.Background(), forged), "ListJobs", ErrUnauthenticated},
+		{"worker certificate", asCaller("alice", auth.KindWorker), "ListJobs", ErrPermissionDenied},
+		{"manager certificate", asCaller("alice", auth.KindManager), "ListJobs", ErrPermissionDenied},
+		{"no role", issue("mallory", time.Hour), "ListJobs", ErrPermissionDenied},
+		{"certificate without a role", asCaller("mallory", auth.KindClient), "GetJobStatus", ErrPermissionDenied},
+		{"viewer submitting", issue("carol", time.Hour), "SubmitJob", ErrPermissionDenied},
+		{"submitter draining", issue("bob", time.Hour), "DrainWorker", ErrPermissionDenied},
+		{"submitter calling an unlisted method", issue("bob", time.Hour), "Unlisted", ErrPermissionDenied},
+	}
+	for _, tc := range denied {
+		t.Run(tc.name, func(t *testing.T) {
+			if caller, role, err := s.authorizeClient(tc.ctx, tc.method); !errors.Is(err, tc.want) {
+				t.Errorf("authorizeClient(%s) = %v, %q, %v; want %v", tc.method, caller, role, err, tc.want)
+			}
+		})
+	}
+
+	if _, role, err := s.authorizeClient(issue("carol", time.Hour), "ListJobs"); err != nil || role != auth.RoleViewer {
+		t.Errorf("viewer ListJobs = %q, %v", role, err)
+	}
+	if _, role, err := s.authorizeClient(asCaller("alice", auth.KindClient), "RevokeWorker"); err != nil || role != auth.RoleAdmin {
+		t.Errorf("admin RevokeWorker = %q, %v", role, err)
+	}
+
+	var resp pb.JobResponse
+	if err := s.SubmitJob(issue("carol", time.Hour), pb.JobRequest{Command: "true"}, &resp); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("viewer SubmitJob = %v, want %v", err, ErrPermissionDenied)
+	}
+	if jobs := s.store.GetAllJobs(); len(jobs) != 0 {
+		t.Errorf("a denied SubmitJob created %d jobs", len(jobs))
+	}
+}
+
+// TestRegisterWorkerAdmission checks that with join tokens required, a
+// worker can't join without a valid token or its own certificate, nor
+// rejoin once revoked
+func TestRegisterWorkerAdmission(t *testing.T) {
+	s := newTestServer(t, Config{JoinTokens: newJoinTokens(t, testJoinToken)})
+
+	denied := []struct {
+		name  string
+		ctx   context.Context
+		token string
+		want  error
+	}{
+		{"no token", context.Background(), "", ErrUnauthenticated},
+		{"bad token", context.Background(), "join.wrong", ErrUnauthenticated},
+		{"token of another cluster", context.Background(), testJoinToken + "x", ErrUnauthenticated},
+		{"another worker's certificate", asCaller("w2", auth.KindWorker), testJoinToken, ErrPermissionDenied},
+		{"client certificate", asCaller("w1", auth.KindClient), testJoinToken, ErrPermissionDenied},
+	}
+	for _, tc := range denied {
+		t.Run(tc.name, func(t *testing.T) {
+			info := pb.WorkerInfo{WorkerId: "w1", Address: "127.0.0.1:1", JoinToken: tc.token}
+			if err := s.RegisterWorker(tc.ctx, info, &pb.RegistrationResponse{}); !errors.Is(err, tc.want) {
+				t.Errorf("RegisterWorker = %v, want %v", err, tc.want)
+			}
+			if _, ok := s.store.GetWorker("w1"); ok {
+				t.Fatal("worker was registered")
+			}
+		})
+	}
+
+	registerWorker(t, s, "w1", testJoinToken)
+	if _, err := s.store.UpdateWorker("w1", func(w *models.Worker) error {
+		w.Revoked = true
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateWorker: %v", err)
+	}
+	info := pb.WorkerInfo{WorkerId: "w1", Address: "127.0.0.1:1", JoinToken: testJoinToken}
+	if err := s.RegisterWorker(asCaller("w1", auth.KindWorker), info, &pb.RegistrationResponse{}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("RegisterWorker of a revoked worker = %v, want %v", err, ErrPermissionDenied)
+	}
+}
+
+// TestReportTaskStatusChecksWorker checks that a task status update is
+// refused unless it proves the worker it comes from: a certificate for
+// another worker, or a missing or bad join token, must not touch the task
+func TestReportTaskStatusChecksWorker(t *testing.T) {
+	s := newTestServer(t, Config{JoinTokens: newJoinTokens(t, testJoinToken)})
+	registerWorker(t, s, "w1", testJoinToken)
+	addPendingJob(t, s.store, "j1")
+	startAttempt(t, s.store, "j1", "t1", "w1")
+
+	denied := []struct {
+		name   string
+		ctx    context.Context
+		update pb.TaskStatusUpdate
+		want   error
+	}{
+		{"certificate for another worker", asCaller("w2", auth.KindWorker), pb.TaskStatusUpdate{WorkerId: "w1", JoinToken: testJoinToken}, ErrPermissionDenied},
+		{"client certificate", asCaller("w1", auth.KindClient), pb.TaskStatusUpdate{WorkerId: "w1"}, ErrPermissionDenied},
+		{"no token", context.Background(), pb.TaskStatusUpdate{WorkerId: "w1"}, ErrUnauthenticated},
+		{"bad token", context.Background(), pb.TaskStatusUpdate{WorkerId: "w1", JoinToken: "join.wrong"}, ErrUnauthenticated},
+		{"no worker", context.Background(), pb.TaskStatusUpdate{JoinToken: testJoinToken}, ErrUnauthenticated},
+	}
+	for _, tc := range denied {
+		t.Run(tc.name, func(t *testing.T) {
+			update := tc.update
+			update.TaskId, update.JobId, update.Status, update.Seq = "t1", "j1", string(models.JobStatusRunning), 1
+			if err := s.ReportTaskStatus(tc.ctx, update, &pb.Ack{}); !errors.Is(err, tc.want) {
+				t.Errorf("ReportTaskStatus = %v, want %v", err, tc.want)
+			}
+			if task, _ := s.store.GetTask("t1"); task.Status != models.JobStatusScheduled {
+				t.Fatalf("task became %s", task.Status)
+			}
+		})
+	}
+
+	// The task's owner is checked later too; the certificate alone must
+	// already rule out acting for another worker
+	w2 := &auth.Caller{Name: "w2", Kind: auth.KindWorker}
+	if id, proven, err := s.reportingWorker(w2, pb.TaskStatusUpdate{WorkerId: "w1", JoinToken: testJoinToken}); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("reportingWorker(w2 as w1) = %q, %v, %v; want %v", id, proven, err, ErrPermissionDenied)
+	}
+	if id, proven, err := s.reportingWorker(w2, pb.TaskStatusUpdate{}); err != nil || id != "w2" || !proven {
+		t.Errorf("reportingWorker(w2) = %q, %v, %v; want w2, proven", id, proven, err)
+	}
+
+	update := pb.TaskStatusUpdate{TaskId: "t1", JobId: "j1", Status: string(models.JobStatusRunning), Seq: 1}
+	var ack pb.Ack
+	if err := s.ReportTaskStatus(asCaller("w1", auth.KindWorker), update, &ack); err != nil || !ack.Ok {
+		t.Fatalf("ReportTaskStatus with the worker's certificate = %v, %+v", err, ack)
+	}
+	if task, _ := s.store.GetTask("t1"); task.Status != models.JobStatusRunning {
+		t.Errorf("task is %s after its worker reported it running", task.Status)
+	}
+}
@@ -0,0 +1,177 @@
+package manager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"titan/pkg/logger"
+)
+
+// Bucket names in the bolt database. Records are stored as JSON keyed by ID.
+var (
+	jobsBucket      = []byte("jobs")
+	tasksBucket     = []byte("tasks")
+	workersBucket   = []byte("workers")
+	secretsBucket   = []byte("secrets")   // Keyed by name; values stay encrypted
+	templatesBucket = []byte("templates") // Keyed by name
+)
+
+// BoltStore is a durable store backed by an embedded bolt database. All
+// state is also cached in memory: reads never touch the disk, and every
+// change set is written in one bolt transaction before it becomes visible.
+type BoltStore struct {
+	*MemoryStore
+	db *bolt.DB
+}
+
+// OpenBoltStore opens or creates the database at path and loads its state
+func OpenBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	s := &BoltStore{MemoryStore: newMemoryStore(), db: db}
+	s.commit = s.persistAndApply
+
+	if err := s.load(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	logger.Info("Loaded store from disk",
+		"path", path,
+		"jobs", len(s.jobs),
+		"tasks", len(s.tasks),
+		"workers", len(s.workers),
+		"secrets", len(s.secrets),
+		"templates", len(s.templates))
+	return s, nil
+}
+
+// load creates the buckets if needed and reads every record into memory
+func (s *BoltStore) load() error {
+	cs := &ChangeSet{}
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{jobsBucket, tasksBucket, workersBucket, secretsBucket, templatesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		if err := loadBucket(tx, jobsBucket, &cs.Jobs); err != nil {
+			return err
+		}
+		if err := loadBucket(tx, tasksBucket, &cs.Tasks); err != nil {
+			return err
+		}
+		if err := loadBucket(tx, workersBucket, &cs.Workers); err != nil {
+			return err
+		}
+		if err := loadBucket(tx, secretsBucket, &cs.Secrets); err != nil {
+			return err
+		}
+		return loadBucket(tx, templatesBucket, &cs.Templates)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load store: %w", err)
+	}
+	s.reset(cs, 0)
+	return nil
+}
+
+// loadBucket decodes every value in a bucket and appends it to out
+func loadBucket[T any](tx *bolt.Tx, name []byte, out *[]*T) error {
+	return tx.Bucket(name).ForEach(func(k, v []byte) error {
+		record := new(T)
+		if err := json.Unmarshal(v, record); err != nil {
+			return fmt.Errorf("corrupt record %s/%s: %w", name, k, err)
+		}
+		*out = append(*out, record)
+		return nil
+	})
+}
+
+// persistAndApply writes a change set to disk and then applies it in
+// memory. Volatile change sets are only applied in memory.
+func (s *BoltStore) persistAndApply(cs *ChangeSet) error {
+	if !cs.Volatile {
+		err := s.db.Update(func(tx *bolt.Tx) error {
+			for _, job := range cs.Jobs {
+				if err := putRecord(tx, jobsBucket, job.ID, job); err != nil {
+					return err
+				}
+			}
+			for _, task := range cs.Tasks {
+				if err := putRecord(tx, tasksBucket, task.ID, task); err != nil {
+					return err
+				}
+			}
+			for _, worker := range cs.Workers {
+				if err := putRecord(tx, workersBucket, worker.ID, worker); err != nil {
+					return err
+				}
+			}
+			for _, secret := range cs.Secrets {
+				if err := putRecord(tx, secretsBucket, secret.Name, secret); err != nil {
+					return err
+				}
+			}
+			for _, template := range cs.Templates {
+				if err := putRecord(tx, templatesBucket, template.Name, template); err != nil {
+					return err
+				}
+			}
+			if err := deleteRecords(tx, jobsBucket, cs.RemovedJobs); err != nil {
+				return err
+			}
+			if err := deleteRecords(tx, tasksBucket, cs.RemovedTasks); err != nil {
+				return err
+			}
+			if err := deleteRecords(tx, workersBucket, cs.RemovedWorkers); err != nil {
+				return err
+			}
+			if err := deleteRecords(tx, secretsBucket, cs.RemovedSecrets); err != nil {
+				return err
+			}
+			return deleteRecords(tx, templatesBucket, cs.RemovedTemplates)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to persist change: %w", err)
+		}
+	}
+	s.Apply(cs)
+	return nil
+}
+
+// putRecord stores a record as JSON under its ID
+func putRecord(tx *bolt.Tx, bucket []byte, id string, record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucket).Put([]byte(id), data)
+}
+
+// deleteRecords removes the records with the given IDs from a bucket
+func deleteRecords(tx *bolt.Tx, bucket []byte, ids []string) error {
+	for _, id := range ids {
+		if err := tx.Bucket(bucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close waits for in-progress updates and closes the database, which
+// flushes it to disk
+func (s *BoltStore) Close() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.db.Close()
+}
@@ -0,0 +1,123 @@
+package manager
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"titan/pkg/models"
+)
+
+// eventLogSize is how many change events the store retains for watchers
+// resuming from an older resource version
+const eventLogSize = 4096
+
+// ErrVersionExpired is returned when watching from a resource version whose
+// changes are no longer retained. The watcher must re-read current state.
+var ErrVersionExpired = errors.New("resource version expired")
+
+// EventType says how a record changed
+type EventType string
+
+const (
+	EventAdded    EventType = "ADDED"
+	EventModified EventType = "MODIFIED"
+	EventDeleted  EventType = "DELETED"
+)
+
+// Event describes one changed job or worker. Exactly one of Job and Worker
+// is set; for deletions it holds the last stored record. The records are
+// shared with the store and must not be modified.
+type Event struct {
+	Version uint64 // Resource version of the change that caused the event
+	Type    EventType
+	Job     *models.Job
+	Worker  *models.Worker
+}
+
+// eventLog retains the most recent change events and wakes watchers when
+// new ones arrive. Every event after floor is retained.
+type eventLog struct {
+	mu      sync.Mutex
+	events  []Event
+	version uint64        // Version of the latest applied change
+	floor   uint64        // Events after this version are all retained
+	notify  chan struct{} // Closed and replaced on every change
+}
+
+// newEventLog creates an empty event log
+func newEventLog() *eventLog {
+	return &eventLog{notify: make(chan struct{})}
+}
+
+// append records the events of the change at version
+func (l *eventLog) append(version uint64, events []Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.version = version
+	for i := range events {
+		events[i].Version = version
+	}
+	l.events = append(l.events, events...)
+	if over := len(l.events) - eventLogSize; over > 0 {
+		l.floor = l.events[over-1].Version
+		l.events = append([]Event(nil), l.events[over:]...)
+	}
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// reset discards every event, e.g. after the store was replaced wholesale,
+// and continues from version
+func (l *eventLog) reset(version uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = nil
+	l.version = version
+	l.floor = version
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// current returns the latest resource version
+func (l *eventLog) current() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.version
+}
+
+// wait returns the events after since that satisfy match, waiting until
+// there is at least one, timeout expires or cancel is closed. It also
+// returns the version to resume from.
+func (l *eventLog) wait(since uint64, match func(Event) bool, timeout time.Duration, cancel <-chan struct{}) ([]Event, uint64, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		l.mu.Lock()
+		if since < l.floor || since > l.version {
+			l.mu.Unlock()
+			return nil, 0, ErrVersionExpired
+		}
+		var matched []Event
+		for _, event := range l.events {
+			if event.Version > since && match(event) {
+				matched = append(matched, event)
+			}
+		}
+		since = l.version
+		notify := l.notify
+		l.mu.Unlock()
+
+		if len(matched) > 0 {
+			return matched, since, nil
+		}
+		select {
+		case <-notify:
+		case <-timer.C:
+			return nil, since, nil
+		case <-cancel:
+			return nil, since, nil
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package manager
+
+import (
+	"sort"
+	"time"
+
+	"titan/pkg/logger"
+	"titan/pkg/models"
+)
+
+const (
+	// defaultGCInterval is how often retention is enforced by default
+	defaultGCInterval = time.Minute
+
+	// gcBatchSize bounds how many jobs one pass evicts, which keeps each
+	// store update (and Raft log entry) small
+	gcBatchSize = 500
+)
+
+// RetentionPolicy decides which finished jobs the manager keeps. Jobs that
+// haven't reached a terminal status are never evicted.
+type RetentionPolicy struct {
+	MaxAge     time.Duration      // Evict jobs finished longer ago than this; 0 disables
+	MaxJobs    int                // Keep at most this many finished jobs; 0 disables
+	Statuses   []models.JobStatus // Terminal statuses subject to eviction; empty means all
+	ArchiveDir string             // Append evicted jobs here; empty discards them
+	Interval   time.Duration      // How often to collect; 0 means defaultGCInterval
+}
+
+// Enabled reports whether the policy evicts anything
+func (p RetentionPolicy) Enabled() bool {
+	return p.MaxAge > 0 || p.MaxJobs > 0
+}
+
+// appliesTo reports whether a job is subject to eviction
+func (p RetentionPolicy) appliesTo(job *models.Job) bool {
+	if !job.Status.IsTerminal() {
+		return false
+	}
+	if len(p.Statuses) == 0 {
+		return true
+	}
+	for _, status := range p.Statuses {
+		if job.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// garbageCollector periodically evicts finished jobs according to a
+// retention policy, archiving them first if the policy asks for it
+type garbageCollector struct {
+	store    Store
+	policy   RetentionPolicy
+	archive  *Archive
+	stopChan chan struct{}
+	doneChan chan struct{}
+}
+
+// newGarbageCollector creates a collector for policy
+func newGarbageCollector(store Store, policy RetentionPolicy, archive *Archive) *garbageCollector {
+	if policy.Interval <= 0 {
+		policy.Interval = defaultGCInterval
+	}
+	return &garbageCollector{
+		store:    store,
+		policy:   policy,
+		archive:  archive,
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// Run collects garbage until Stop is called
+func (gc *garbageCollector) Run() {
+	defer close(gc.doneChan)
+
+	ticker := time.NewTicker(gc.policy.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			gc.collect(time.Now())
+		case <-gc.stopChan:
+			return
+		}
+	}
+}
+
+// Stop halts the collector and waits for an in-progress pass to finish
+func (gc *garbageCollector) Stop() {
+	close(gc.stopChan)
+	<-gc.doneChan
+}
+
+// collect runs one eviction pass. Jobs are only removed from the store once
+// they have been archived.
+func (gc *garbageCollector) collect(now time.Time) {
+	// Only the leader of a replicated cluster evicts jobs
+	if r, ok := gc.store.(replicatedStore); ok && r.CheckLeader() != nil {
+		return
+	}
+
+	evict := gc.selectEvictions(now)
+	if len(evict) == 0 {
+		return
+	}
+
+	if gc.archive != nil {
+		records := make([]ArchivedJob, len(evict))
+		for i, job := range evict {
+			records[i] = ArchivedJob{
+				Job:        job,
+				Tasks:      gc.store.GetTasksForJob(job.ID),
+				ArchivedAt: now,
+			}
+		}
+		if err := gc.archive.Append(records, now); err != nil {
+			logger.Error("Failed to archive jobs, keeping them", "jobs", len(evict), "error", err)
+			return
+		}
+	}
+
+	ids := make([]string, len(evict))
+	for i, job := range evict {
+		ids[i] = job.ID
+	}
+	removed, err := gc.store.RemoveJobs(ids...)
+	if err != nil {
+		logger.Error("Failed to evict jobs", "jobs", len(ids), "error", err)
+		return
+	}
+	logger.Info("Evicted finished jobs", "jobs", removed, "archived", gc.archive != nil)
+}
+
+// selectEvictions returns the finished jobs the policy no longer retains,
+// oldest first and at most gcBatchSize of them
+func (gc *garbageCollector) selectEvictions(now time.Time) []*models.Job {
+	var finished []*models.Job
+	for _, job := range gc.store.GetAllJobs() {
+		if gc.policy.appliesTo(job) {
+			finished = append(finished, job)
+		}
+	}
+	// Newest first, so the first MaxJobs are the ones kept
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].UpdatedAt.After(finished[j].UpdatedAt)
+	})
+
+	var evict []*models.Job
+	for i := len(finished) - 1; i >= 0 && len(evict) < gcBatchSize; i-- {
+		job := finished[i]
+		tooMany := gc.policy.MaxJobs > 0 && i >= gc.policy.MaxJobs
+		tooOld := gc.policy.MaxAge > 0 && now.Sub(job.UpdatedAt) > gc.policy.MaxAge
+		if tooMany || tooOld {
+			evict = append(evict, job)
+		}
+	}
+	return evict
+}
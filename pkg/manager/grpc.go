@@ -0,0 +1,163 @@
+package manager
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"titan/pkg/grpcserver"
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+	"titan/pkg/proto/titanpb"
+)
+
+// RegisterGRPC registers the server's AuthService, ManagerService and
+// WorkerService with a gRPC server. Every method delegates to the handler of the same
+// name, so both transports behave alike. Create the gRPC server with
+// grpcserver.NewServer so handlers see the caller.
+func (s *Server) RegisterGRPC(server *grpc.Server) {
+	titanpb.RegisterAuthServiceServer(server, &grpcAuthService{s: s})
+	titanpb.RegisterManagerServiceServer(server, &grpcManagerService{s: s})
+	titanpb.RegisterWorkerServiceServer(server, &grpcWorkerService{s: s})
+}
+
+// grpcCode maps a handler error to a gRPC status code. A replica that is
+// not the leader answers Unavailable, so gRPC clients retry elsewhere.
+func grpcCode(err error) codes.Code {
+	var notLeader *pb.NotLeaderError
+	var transition *models.TransitionError
+	switch {
+	case errors.As(err, &notLeader):
+		return codes.Unavailable
+	case errors.Is(err, ErrJobNotFound), errors.Is(err, ErrTaskNotFound), errors.Is(err, ErrWorkerNotFound), errors.Is(err, ErrSecretNotFound), errors.Is(err, ErrTemplateNotFound):
+		return codes.NotFound
+	case errors.Is(err, ErrJobExists), errors.Is(err, ErrWorkerExists):
+		return codes.AlreadyExists
+	case errors.Is(err, ErrInvalidArgument), errors.Is(err, ErrInvalidPageToken), errors.Is(err, models.ErrUnknownStatus):
+		return codes.InvalidArgument
+	case errors.Is(err, ErrStatusConflict), errors.Is(err, ErrArchiveDisabled), errors.Is(err, ErrAuditDisabled), errors.Is(err, ErrSecretsDisabled), errors.As(err, &transition):
+		return codes.FailedPrecondition
+	case errors.Is(err, ErrUnauthenticated):
+		return codes.Unauthenticated
+	case errors.Is(err, ErrPermissionDenied):
+		return codes.PermissionDenied
+	}
+	return codes.Unknown
+}
+
+type grpcAuthService struct {
+	titanpb.UnimplementedAuthServiceServer
+	s *Server
+}
+
+func (g *grpcAuthService) Authenticate(ctx context.Context, in *titanpb.AuthRequest) (*titanpb.AuthResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.AuthResponse{}, g.s.Authenticate, grpcCode)
+}
+
+type grpcManagerService struct {
+	titanpb.UnimplementedManagerServiceServer
+	s *Server
+}
+
+func (g *grpcManagerService) SubmitJob(ctx context.Context, in *titanpb.JobRequest) (*titanpb.JobResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.JobResponse{}, g.s.SubmitJob, grpcCode)
+}
+
+func (g *grpcManagerService) GetJobStatus(ctx context.Context, in *titanpb.JobStatusRequest) (*titanpb.JobStatusResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.JobStatusResponse{}, g.s.GetJobStatus, grpcCode)
+}
+
+func (g *grpcManagerService) ListJobs(ctx context.Context, in *titanpb.ListJobsRequest) (*titanpb.ListJobsResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.ListJobsResponse{}, g.s.ListJobs, grpcCode)
+}
+
+func (g *grpcManagerService) CancelJob(ctx context.Context, in *titanpb.CancelJobRequest) (*titanpb.JobStatusResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.JobStatusResponse{}, g.s.CancelJob, grpcCode)
+}
+
+func (g *grpcManagerService) SearchHistory(ctx context.Context, in *titanpb.HistoryRequest) (*titanpb.HistoryResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.HistoryResponse{}, g.s.SearchHistory, grpcCode)
+}
+
+func (g *grpcManagerService) WatchJobs(ctx context.Context, in *titanpb.WatchRequest) (*titanpb.WatchResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.WatchResponse{}, g.s.WatchJobs, grpcCode)
+}
+
+func (g *grpcManagerService) StreamJobs(in *titanpb.WatchRequest, stream titanpb.ManagerService_StreamJobsServer) error {
+	newOut := func() *titanpb.WatchResponse { return &titanpb.WatchResponse{} }
+	return grpcserver.HandleStream(stream.Context(), in, newOut, stream.Send, g.s.StreamJobs, grpcCode)
+}
+
+func (g *grpcManagerService) ListWorkers(ctx context.Context, in *titanpb.ListWorkersRequest) (*titanpb.ListWorkersResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.ListWorkersResponse{}, g.s.ListWorkers, grpcCode)
+}
+
+func (g *grpcManagerService) GetWorker(ctx context.Context, in *titanpb.GetWorkerRequest) (*titanpb.WorkerStatusResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.WorkerStatusResponse{}, g.s.GetWorker, grpcCode)
+}
+
+func (g *grpcManagerService) DrainWorker(ctx context.Context, in *titanpb.DrainWorkerRequest) (*titanpb.WorkerStatusResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.WorkerStatusResponse{}, g.s.DrainWorker, grpcCode)
+}
+
+func (g *grpcManagerService) RevokeWorker(ctx context.Context, in *titanpb.RevokeWorkerRequest) (*titanpb.WorkerStatusResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.WorkerStatusResponse{}, g.s.RevokeWorker, grpcCode)
+}
+
+func (g *grpcManagerService) SearchAudit(ctx context.Context, in *titanpb.AuditRequest) (*titanpb.AuditResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.AuditResponse{}, g.s.SearchAudit, grpcCode)
+}
+
+func (g *grpcManagerService) PutSecret(ctx context.Context, in *titanpb.PutSecretRequest) (*titanpb.SecretInfo, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.SecretInfo{}, g.s.PutSecret, grpcCode)
+}
+
+func (g *grpcManagerService) ListSecrets(ctx context.Context, in *titanpb.ListSecretsRequest) (*titanpb.ListSecretsResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.ListSecretsResponse{}, g.s.ListSecrets, grpcCode)
+}
+
+func (g *grpcManagerService) DeleteSecret(ctx context.Context, in *titanpb.DeleteSecretRequest) (*titanpb.SecretInfo, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.SecretInfo{}, g.s.DeleteSecret, grpcCode)
+}
+
+func (g *grpcManagerService) PutTemplate(ctx context.Context, in *titanpb.PutTemplateRequest) (*titanpb.JobTemplate, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.JobTemplate{}, g.s.PutTemplate, grpcCode)
+}
+
+func (g *grpcManagerService) GetTemplate(ctx context.Context, in *titanpb.GetTemplateRequest) (*titanpb.JobTemplate, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.JobTemplate{}, g.s.GetTemplate, grpcCode)
+}
+
+func (g *grpcManagerService) ListTemplates(ctx context.Context, in *titanpb.ListTemplatesRequest) (*titanpb.ListTemplatesResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.ListTemplatesResponse{}, g.s.ListTemplates, grpcCode)
+}
+
+func (g *grpcManagerService) DeleteTemplate(ctx context.Context, in *titanpb.DeleteTemplateRequest) (*titanpb.JobTemplate, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.JobTemplate{}, g.s.DeleteTemplate, grpcCode)
+}
+
+func (g *grpcManagerService) SubmitFromTemplate(ctx context.Context, in *titanpb.SubmitFromTemplateRequest) (*titanpb.JobResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.JobResponse{}, g.s.SubmitFromTemplate, grpcCode)
+}
+
+type grpcWorkerService struct {
+	titanpb.UnimplementedWorkerServiceServer
+	s *Server
+}
+
+func (g *grpcWorkerService) RegisterWorker(ctx context.Context, in *titanpb.WorkerInfo) (*titanpb.RegistrationResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.RegistrationResponse{}, g.s.RegisterWorker, grpcCode)
+}
+
+func (g *grpcWorkerService) DeregisterWorker(ctx context.Context, in *titanpb.DeregisterRequest) (*titanpb.Ack, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.Ack{}, g.s.DeregisterWorker, grpcCode)
+}
+
+func (g *grpcWorkerService) Heartbeat(ctx context.Context, in *titanpb.HeartbeatRequest) (*titanpb.HeartbeatResponse, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.HeartbeatResponse{}, g.s.Heartbeat, grpcCode)
+}
+
+func (g *grpcWorkerService) ReportTaskStatus(ctx context.Context, in *titanpb.TaskStatusUpdate) (*titanpb.Ack, error) {
+	return grpcserver.HandleContext(ctx, in, &titanpb.Ack{}, g.s.ReportTaskStatus, grpcCode)
+}
@@ -0,0 +1,556 @@
+package manager
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	rpccode "google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"titan/pkg/auth"
+	"titan/pkg/proto/titanpb"
+)
+
+// maxRequestBody bounds the size of a REST request body
+const maxRequestBody = 1 << 20
+
+// openAPIDocument describes the REST API. Keep it in step with the routes
+// below and the messages in proto/titan.proto.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// Resources are encoded with the canonical protobuf JSON mapping of the
+// messages in proto/titan.proto, using the proto field names
+var (
+	jsonMarshal   = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+	jsonUnmarshal = protojson.UnmarshalOptions{}
+)
+
+// HTTPHandler returns the REST API: jobs and workers as JSON resources
+// under /v1/, and the OpenAPI document at /v1/openapi.json. Requests are
+// served by the same handlers as net/rpc and gRPC.
+func (s *Server) HTTPHandler() http.Handler {
+	api := &httpAPI{grpc: &grpcManagerService{s: s}}
+	api.routes = []httpRoute{
+		{http.MethodGet, "jobs", api.listJobs},
+		{http.MethodPost, "jobs", api.submitJob},
+		{http.MethodGet, "jobs/*", api.getJob},
+		{http.MethodPost, "jobs/*/cancel", api.cancelJob},
+		{http.MethodGet, "jobs/*/logs", api.jobLogs},
+		{http.MethodGet, "workers", api.listWorkers},
+		{http.MethodGet, "workers/*", api.getWorker},
+		{http.MethodPost, "workers/*/drain", api.drainWorker},
+		{http.MethodDelete, "workers/*/drain", api.drainWorker},
+		{http.MethodPost, "workers/*/revoke", api.revokeWorker},
+		{http.MethodDelete, "workers/*/revoke", api.revokeWorker},
+		{http.MethodGet, "audit", api.searchAudit},
+		{http.MethodGet, "secrets", api.listSecrets},
+		{http.MethodPut, "secrets/*", api.putSecret},
+		{http.MethodDelete, "secrets/*", api.deleteSecret},
+		{http.MethodGet, "templates", api.listTemplates},
+		{http.MethodGet, "templates/*", api.getTemplate},
+		{http.MethodPut, "templates/*", api.putTemplate},
+		{http.MethodDelete, "templates/*", api.deleteTemplate},
+		{http.MethodPost, "templates/*/jobs", api.submitFromTemplate},
+		{http.MethodGet, "openapi.json", api.openAPI},
+	}
+	return api
+}
+
+// httpRoute maps a method and path under /v1/ to a handler. A "*" segment
+// in the pattern matches any one path segment, which is passed to the
+// handler in args.
+type httpRoute struct {
+	method  string
+	pattern string
+	handle  func(w http.ResponseWriter, r *http.Request, args []string)
+}
+
+// match reports whether path matches the route's pattern, and the
+// segments matched by its wildcards
+func (rt httpRoute) match(path []string) ([]string, bool) {
+	pattern := strings.Split(rt.pattern, "/")
+	if len(pattern) != len(path) {
+		return nil, false
+	}
+	var args []string
+	for i, segment := range pattern {
+		switch {
+		case segment == "*" && path[i] != "":
+			args = append(args, path[i])
+		case segment != path[i]:
+			return nil, false
+		}
+	}
+	return args, true
+}
+
+type httpAPI struct {
+	grpc   *grpcManagerService
+	routes []httpRoute
+}
+
+func (a *httpAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rest, ok := strings.CutPrefix(r.URL.Path, "/v1/")
+	if !ok {
+		writeHTTPError(w, http.StatusNotFound, codes.NotFound, "no such resource: "+r.URL.Path)
+		return
+	}
+	path := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+
+	var allowed []string
+	for _, rt := range a.routes {
+		args, ok := rt.match(path)
+		if !ok {
+			continue
+		}
+		if rt.method == r.Method {
+			ctx := r.Context()
+			if caller, ok := auth.FromTLS(r.TLS); ok {
+				ctx = auth.NewContext(ctx, caller)
+			}
+			if token, ok := auth.ParseBearer(r.Header.Get("Authorization")); ok {
+				ctx = auth.NewTokenContext(ctx, token)
+			}
+			r = r.WithContext(ctx)
+			r.Body = http.MaxBytesReader(w, r.Body, maxRequestBody)
+			rt.handle(w, r, args)
+			return
+		}
+		allowed = append(allowed, rt.method)
+	}
+	if len(allowed) == 0 {
+		writeHTTPError(w, http.StatusNotFound, codes.NotFound, "no such resource: "+r.URL.Path)
+		return
+	}
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeHTTPError(w, http.StatusMethodNotAllowed, codes.Unimplemented, r.Method+" is not supported on "+r.URL.Path)
+}
+
+func (a *httpAPI) submitJob(w http.ResponseWriter, r *http.Request, args []string) {
+	var req titanpb.JobRequest
+	if err := readJSON(r, &req, true); err != nil {
+		writeError(w, err)
+		return
+	}
+	resp, err := a.grpc.SubmitJob(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/jobs/"+resp.JobId)
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (a *httpAPI) listJobs(w http.ResponseWriter, r *http.Request, args []string) {
+	req, err := listJobsRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp, err := a.grpc.ListJobs(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *httpAPI) getJob(w http.ResponseWriter, r *http.Request, args []string) {
+	resp, err := a.grpc.GetJobStatus(r.Context(), &titanpb.JobStatusRequest{JobId: args[0]})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// cancelJob takes an optional body with the reason for cancelling
+func (a *httpAPI) cancelJob(w http.ResponseWriter, r *http.Request, args []string) {
+	var req titanpb.CancelJobRequest
+	if err := readJSON(r, &req, false); err != nil {
+		writeError(w, err)
+		return
+	}
+	req.JobId = args[0]
+	resp, err := a.grpc.CancelJob(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// jobLogs returns the output of the job's latest attempt as plain text
+func (a *httpAPI) jobLogs(w http.ResponseWriter, r *http.Request, args []string) {
+	resp, err := a.grpc.GetJobStatus(r.Context(), &titanpb.JobStatusRequest{JobId: args[0]})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, resp.Output)
+}
+
+func (a *httpAPI) listWorkers(w http.ResponseWriter, r *http.Request, args []string) {
+	resp, err := a.grpc.ListWorkers(r.Context(), &titanpb.ListWorkersRequest{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *httpAPI) getWorker(w http.ResponseWriter, r *http.Request, args []string) {
+	resp, err := a.grpc.GetWorker(r.Context(), &titanpb.GetWorkerRequest{WorkerId: args[0]})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// drainWorker drains a worker on POST and resumes it on DELETE
+func (a *httpAPI) drainWorker(w http.ResponseWriter, r *http.Request, args []string) {
+	req := &titanpb.DrainWorkerRequest{
+		WorkerId: args[0],
+		Resume:   r.Method == http.MethodDelete,
+	}
+	resp, err := a.grpc.DrainWorker(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// revokeWorker revokes a worker on POST, taking an optional reason in the
+// body, and lifts the revocation on DELETE
+func (a *httpAPI) revokeWorker(w http.ResponseWriter, r *http.Request, args []string) {
+	var req titanpb.RevokeWorkerRequest
+	if err := readJSON(r, &req, false); err != nil {
+		writeError(w, err)
+		return
+	}
+	req.WorkerId = args[0]
+	req.Restore = r.Method == http.MethodDelete
+	resp, err := a.grpc.RevokeWorker(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *httpAPI) searchAudit(w http.ResponseWriter, r *http.Request, args []string) {
+	req, err := auditRequest(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	resp, err := a.grpc.SearchAudit(r.Context(), req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *httpAPI) listSecrets(w http.ResponseWriter, r *http.Request, args []string) {
+	resp, err := a.grpc.ListSecrets(r.Context(), &titanpb.ListSecretsRequest{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// putSecret sets a secret to the base64 encoded value in the body
+func (a *httpAPI) putSecret(w http.ResponseWriter, r *http.Request, args []string) {
+	var req titanpb.PutSecretRequest
+	if err := readJSON(r, &req, true); err != nil {
+		writeError(w, err)
+		return
+	}
+	req.Name = args[0]
+	resp, err := a.grpc.PutSecret(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *httpAPI) deleteSecret(w http.ResponseWriter, r *http.Request, args []string) {
+	resp, err := a.grpc.DeleteSecret(r.Context(), &titanpb.DeleteSecretRequest{Name: args[0]})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *httpAPI) listTemplates(w http.ResponseWriter, r *http.Request, args []string) {
+	resp, err := a.grpc.ListTemplates(r.Context(), &titanpb.ListTemplatesRequest{})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *httpAPI) getTemplate(w http.ResponseWriter, r *http.Request, args []string) {
+	resp, err := a.grpc.GetTemplate(r.Context(), &titanpb.GetTemplateRequest{Name: args[0]})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *httpAPI) putTemplate(w http.ResponseWriter, r *http.Request, args []string) {
+	var req titanpb.PutTemplateRequest
+	if err := readJSON(r, &req, true); err != nil {
+		writeError(w, err)
+		return
+	}
+	req.Name = args[0]
+	resp, err := a.grpc.PutTemplate(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (a *httpAPI) deleteTemplate(w http.ResponseWriter, r *http.Request, args []string) {
+	resp, err := a.grpc.DeleteTemplate(r.Context(), &titanpb.DeleteTemplateRequest{Name: args[0]})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// submitFromTemplate submits a job rendered from a template; the body
+// carries the parameter values
+func (a *httpAPI) submitFromTemplate(w http.ResponseWriter, r *http.Request, args []string) {
+	var req titanpb.SubmitFromTemplateRequest
+	if err := readJSON(r, &req, false); err != nil {
+		writeError(w, err)
+		return
+	}
+	req.Name = args[0]
+	resp, err := a.grpc.SubmitFromTemplate(r.Context(), &req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/jobs/"+resp.JobId)
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (a *httpAPI) openAPI(w http.ResponseWriter, r *http.Request, args []string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+// listJobsRequest builds a ListJobs request from query parameters named
+// after its fields. statuses and labels (as key=value) may be repeated,
+// and statuses may also be comma-separated. Times are Unix seconds or
+// RFC 3339.
+func listJobsRequest(r *http.Request) (*titanpb.ListJobsRequest, error) {
+	query := r.URL.Query()
+	req := &titanpb.ListJobsRequest{
+		WorkerId:  query.Get("worker_id"),
+		Command:   query.Get("command"),
+		PageToken: query.Get("page_token"),
+	}
+	for _, value := range query["statuses"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				req.Statuses = append(req.Statuses, strings.ToUpper(name))
+			}
+		}
+	}
+	for _, label := range query["labels"] {
+		key, value, ok := strings.Cut(label, "=")
+		if !ok || key == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid label %q, want key=value", label)
+		}
+		if req.Labels == nil {
+			req.Labels = make(map[string]string)
+		}
+		req.Labels[key] = value
+	}
+
+	var err error
+	if req.SubmittedAfter, err = queryTime(query.Get("submitted_after")); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid submitted_after: %v", err)
+	}
+	if req.SubmittedBefore, err = queryTime(query.Get("submitted_before")); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid submitted_before: %v", err)
+	}
+	if req.OldestFirst, err = queryBool(query.Get("oldest_first")); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid oldest_first: %v", err)
+	}
+	if req.Summary, err = queryBool(query.Get("summary")); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid summary: %v", err)
+	}
+	if value := query.Get("page_size"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page_size: %v", err)
+		}
+		req.PageSize = int32(size)
+	}
+	return req, nil
+}
+
+// auditRequest builds an AuditRequest from query parameters named after
+// its fields. Times are Unix seconds or RFC 3339.
+func auditRequest(r *http.Request) (*titanpb.AuditRequest, error) {
+	query := r.URL.Query()
+	req := &titanpb.AuditRequest{
+		Caller: query.Get("caller"),
+		Method: query.Get("method"),
+	}
+	var err error
+	if req.Since, err = queryTime(query.Get("since")); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid since: %v", err)
+	}
+	if req.Until, err = queryTime(query.Get("until")); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid until: %v", err)
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid limit: %v", err)
+		}
+		req.Limit = int32(limit)
+	}
+	return req, nil
+}
+
+// queryTime parses a time given as Unix seconds or RFC 3339. Empty means
+// unset, which is 0.
+func queryTime(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return unix, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, fmt.Errorf("want Unix seconds or RFC 3339: %q", value)
+	}
+	return t.Unix(), nil
+}
+
+// queryBool parses a boolean query parameter. Empty means false.
+func queryBool(value string) (bool, error) {
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// readJSON decodes the request body into msg. An empty body is an error
+// only if required is set.
+func readJSON(r *http.Request, msg proto.Message, required bool) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "failed to read request body: %v", err)
+	}
+	if len(body) == 0 {
+		if required {
+			return status.Error(codes.InvalidArgument, "request body is required")
+		}
+		return nil
+	}
+	if err := jsonUnmarshal.Unmarshal(body, msg); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, msg proto.Message) {
+	body, err := jsonMarshal.Marshal(msg)
+	if err != nil {
+		writeHTTPError(w, http.StatusInternalServerError, codes.Internal, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(append(body, '\n'))
+}
+
+// writeError writes a gRPC status error as an HTTP error response
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	code := httpStatus(st.Code())
+	switch code {
+	case http.StatusServiceUnavailable:
+		// Not the leader, or no leader yet
+		w.Header().Set("Retry-After", "1")
+	case http.StatusUnauthorized:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="titan"`)
+	}
+	writeHTTPError(w, code, st.Code(), st.Message())
+}
+
+// httpError is the body of every REST error response
+type httpError struct {
+	Error struct {
+		Code    int    `json:"code"`    // HTTP status
+		Status  string `json:"status"`  // gRPC status code name, e.g. NOT_FOUND
+		Message string `json:"message"` // Human-readable
+	} `json:"error"`
+}
+
+func writeHTTPError(w http.ResponseWriter, code int, grpcCode codes.Code, message string) {
+	var body httpError
+	body.Error.Code = code
+	body.Error.Status = rpccode.Code_name[int32(grpcCode)]
+	body.Error.Message = message
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&body)
+}
+
+// httpStatus maps a gRPC status code to the HTTP status of a REST error
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.FailedPrecondition, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,157 @@
+package manager
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"titan/pkg/models"
+)
+
+const (
+	// defaultPageSize is used when a listing doesn't ask for a page size
+	defaultPageSize = 100
+
+	// maxPageSize caps the page size a listing may ask for
+	maxPageSize = 1000
+)
+
+// ErrInvalidPageToken is returned for a continuation token that wasn't
+// issued by a listing in the same order
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// JobFilter selects jobs. Zero fields match every job.
+type JobFilter struct {
+	JobID           string
+	Statuses        []models.JobStatus // Any of these
+	WorkerID        string
+	Labels          map[string]string // All of these
+	CommandContains string
+	SubmittedAfter  time.Time
+	SubmittedBefore time.Time
+}
+
+// Matches reports whether a job satisfies the filter
+func (f JobFilter) Matches(job *models.Job) bool {
+	if f.JobID != "" && job.ID != f.JobID {
+		return false
+	}
+	if len(f.Statuses) > 0 && !containsStatus(f.Statuses, job.Status) {
+		return false
+	}
+	if f.WorkerID != "" && job.WorkerID != f.WorkerID {
+		return false
+	}
+	for k, v := range f.Labels {
+		if label, ok := job.Labels[k]; !ok || label != v {
+			return false
+		}
+	}
+	if f.CommandContains != "" && !strings.Contains(job.CommandLine(), f.CommandContains) {
+		return false
+	}
+	if !f.SubmittedAfter.IsZero() && !job.CreatedAt.After(f.SubmittedAfter) {
+		return false
+	}
+	if !f.SubmittedBefore.IsZero() && !job.CreatedAt.Before(f.SubmittedBefore) {
+		return false
+	}
+	return true
+}
+
+// containsStatus reports whether status is one of statuses
+func containsStatus(statuses []models.JobStatus, status models.JobStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// pageCursor is the position after the last job of a page. It is handed
+// to clients as an opaque continuation token.
+type pageCursor struct {
+	CreatedAt   int64  `json:"t"` // Unix nanoseconds
+	JobID       string `json:"id"`
+	OldestFirst bool   `json:"o"`
+}
+
+// encode renders the cursor as a continuation token
+func (c pageCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageCursor parses a continuation token
+func decodePageCursor(token string) (pageCursor, error) {
+	var c pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, ErrInvalidPageToken
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidPageToken
+	}
+	return c, nil
+}
+
+// listedBefore reports whether job a is listed before job b. Jobs are ordered by
+// submission time, with the ID breaking ties so the order is total.
+func listedBefore(a, b *models.Job, oldestFirst bool) bool {
+	if !a.CreatedAt.Equal(b.CreatedAt) {
+		if oldestFirst {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return a.CreatedAt.After(b.CreatedAt)
+	}
+	if oldestFirst {
+		return a.ID < b.ID
+	}
+	return a.ID > b.ID
+}
+
+// paginateJobs sorts jobs and returns the page that follows token, along
+// with the token for the next page, which is empty on the last page
+func paginateJobs(jobs []*models.Job, oldestFirst bool, token string, pageSize int) ([]*models.Job, string, error) {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	sort.Slice(jobs, func(i, j int) bool {
+		return listedBefore(jobs[i], jobs[j], oldestFirst)
+	})
+
+	start := 0
+	if token != "" {
+		cursor, err := decodePageCursor(token)
+		if err != nil || cursor.OldestFirst != oldestFirst {
+			return nil, "", ErrInvalidPageToken
+		}
+		last := &models.Job{ID: cursor.JobID, CreatedAt: time.Unix(0, cursor.CreatedAt)}
+		// The cursor's job may since have been evicted, so search by
+		// position rather than by ID
+		start = sort.Search(len(jobs), func(i int) bool {
+			return listedBefore(last, jobs[i], oldestFirst)
+		})
+	}
+
+	end := start + pageSize
+	if end >= len(jobs) {
+		return jobs[start:], "", nil
+	}
+	page := jobs[start:end]
+	last := page[len(page)-1]
+	next := pageCursor{
+		CreatedAt:   last.CreatedAt.UnixNano(),
+		JobID:       last.ID,
+		OldestFirst: oldestFirst,
+	}
+	return page, next.encode(), nil
+}
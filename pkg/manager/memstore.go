@@ -0,0 +1,610 @@
+package manager
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"titan/pkg/models"
+)
+
+// MemoryStore keeps all cluster state in memory. On its own it is the
+// non-durable backend; durable backends wrap it as a cache and persist each
+// change set before it is applied.
+type MemoryStore struct {
+	writeMu   sync.Mutex   // Serializes updates from read through commit
+	mu        sync.RWMutex // Guards the maps
+	jobs      map[string]*models.Job
+	tasks     map[string]*models.Task
+	workers   map[string]*models.Worker
+	secrets   map[string]*models.Secret
+	templates map[string]*models.JobTemplate
+	events    *eventLog
+
+	// commit makes a change set durable and applies it. It is called with
+	// writeMu held.
+	commit func(*ChangeSet) error
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	s := newMemoryStore()
+	s.commit = func(cs *ChangeSet) error {
+		s.Apply(cs)
+		return nil
+	}
+	return s
+}
+
+// newMemoryStore creates an empty store without a commit function
+func newMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs:      make(map[string]*models.Job),
+		tasks:     make(map[string]*models.Task),
+		workers:   make(map[string]*models.Worker),
+		secrets:   make(map[string]*models.Secret),
+		templates: make(map[string]*models.JobTemplate),
+		events:    newEventLog(),
+	}
+}
+
+// Apply writes a committed change set into memory as the next resource
+// version. The store takes ownership of the records in it.
+func (s *MemoryStore) Apply(cs *ChangeSet) {
+	s.applyAt(cs, 0)
+}
+
+// applyAt writes a committed change set into memory as the given resource
+// version, or as the next one if version is 0
+func (s *MemoryStore) applyAt(cs *ChangeSet, version uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version == 0 {
+		version = s.events.current() + 1
+	}
+	s.events.append(version, s.applyLocked(cs))
+}
+
+// applyLocked writes a change set into memory with mu held and returns the
+// events describing it. Heartbeats only produce an event when they bring a
+// worker back from being unhealthy.
+func (s *MemoryStore) applyLocked(cs *ChangeSet) []Event {
+	var events []Event
+	for _, job := range cs.Jobs {
+		events = append(events, Event{Type: changeType(s.jobs[job.ID] != nil), Job: job})
+		s.jobs[job.ID] = job
+	}
+	for _, task := range cs.Tasks {
+		s.tasks[task.ID] = task
+	}
+	for _, worker := range cs.Workers {
+		prev := s.workers[worker.ID]
+		if !cs.Volatile || revives(prev, worker) {
+			events = append(events, Event{Type: changeType(prev != nil), Worker: worker})
+		}
+		s.workers[worker.ID] = worker
+	}
+	for _, secret := range cs.Secrets {
+		s.secrets[secret.Name] = secret
+	}
+	for _, template := range cs.Templates {
+		s.templates[template.Name] = template
+	}
+	for _, id := range cs.RemovedJobs {
+		if job, ok := s.jobs[id]; ok {
+			events = append(events, Event{Type: EventDeleted, Job: job})
+		}
+		delete(s.jobs, id)
+	}
+	for _, id := range cs.RemovedTasks {
+		delete(s.tasks, id)
+	}
+	for _, id := range cs.RemovedWorkers {
+		if worker, ok := s.workers[id]; ok {
+			events = append(events, Event{Type: EventDeleted, Worker: worker})
+		}
+		delete(s.workers, id)
+	}
+	for _, name := range cs.RemovedSecrets {
+		delete(s.secrets, name)
+	}
+	for _, name := range cs.RemovedTemplates {
+		delete(s.templates, name)
+	}
+	return events
+}
+
+// revives reports whether a heartbeat is the first heard of a worker or
+// brings it back from being unhealthy. Other heartbeats produce no events.
+func revives(prev, worker *models.Worker) bool {
+	return prev == nil || worker.LastHeartbeat.Sub(prev.LastHeartbeat) >= heartbeatTimeout
+}
+
+// routine reports whether a volatile change set produces no events, so it
+// changes nothing anyone watches
+func (s *MemoryStore) routine(cs *ChangeSet) bool {
+	if !cs.Volatile {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, worker := range cs.Workers {
+		if revives(s.workers[worker.ID], worker) {
+			return false
+		}
+	}
+	return true
+}
+
+// applyUnversioned writes a routine change set into memory without a new
+// resource version
+func (s *MemoryStore) applyUnversioned(cs *ChangeSet) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applyLocked(cs)
+}
+
+// changeType is the event type for storing a record that did or didn't
+// exist before
+func changeType(existed bool) EventType {
+	if existed {
+		return EventModified
+	}
+	return EventAdded
+}
+
+// Version returns the store's current resource version
+func (s *MemoryStore) Version() uint64 {
+	return s.events.current()
+}
+
+// Watch returns the changes after resource version since that satisfy
+// match, waiting until there is at least one, timeout expires or cancel is
+// closed. It also returns the version to resume from.
+func (s *MemoryStore) Watch(since uint64, match func(Event) bool, timeout time.Duration, cancel <-chan struct{}) ([]Event, uint64, error) {
+	return s.events.wait(since, match, timeout, cancel)
+}
+
+// AddJob stores a new job together with any task records it already has
+func (s *MemoryStore) AddJob(job *models.Job, tasks ...*models.Task) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, ok := s.GetJob(job.ID); ok {
+		return fmt.Errorf("%w: %s", ErrJobExists, job.ID)
+	}
+	cs := &ChangeSet{Jobs: []*models.Job{job.Clone()}}
+	for _, task := range tasks {
+		cs.Tasks = append(cs.Tasks, task.Clone())
+	}
+	return s.commit(cs)
+}
+
+// GetJob retrieves a copy of a job by ID
+func (s *MemoryStore) GetJob(id string) (*models.Job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, false
+	}
+	return job.Clone(), true
+}
+
+// UpdateJob atomically applies fn to a copy of a job and stores the result.
+// Returning an error from fn aborts the update.
+func (s *MemoryStore) UpdateJob(id string, fn func(*models.Job) error) (*models.Job, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	updated, ok := s.GetJob(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if err := fn(updated); err != nil {
+		return nil, err
+	}
+	updated.UpdatedAt = time.Now()
+	if err := s.commit(&ChangeSet{Jobs: []*models.Job{updated}}); err != nil {
+		return nil, err
+	}
+	return updated.Clone(), nil
+}
+
+// CompareAndSwapJob is UpdateJob that only applies fn while the job is
+// still in the expected status. Otherwise it returns ErrStatusConflict.
+func (s *MemoryStore) CompareAndSwapJob(id string, expected models.JobStatus, fn func(*models.Job) error) (*models.Job, error) {
+	return s.UpdateJob(id, func(job *models.Job) error {
+		if job.Status != expected {
+			return fmt.Errorf("%w: job %s is %s, expected %s", ErrStatusConflict, id, job.Status, expected)
+		}
+		return fn(job)
+	})
+}
+
+// TransitionJob atomically moves a job to a new status, validating the
+// transition against the job state machine. mutate, if not nil, is applied
+// to a copy of the job first; returning an error from it aborts the update.
+// The job is only replaced if both mutate and the transition succeed.
+func (s *MemoryStore) TransitionJob(id string, to models.JobStatus, reason string, mutate func(*models.Job) error) (*models.Job, error) {
+	return s.UpdateJob(id, func(job *models.Job) error {
+		if mutate != nil {
+			if err := mutate(job); err != nil {
+				return err
+			}
+		}
+		return job.Transition(to, reason, time.Now())
+	})
+}
+
+// GetAllJobs returns copies of all jobs
+func (s *MemoryStore) GetAllJobs() []*models.Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	jobs := make([]*models.Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job.Clone())
+	}
+	return jobs
+}
+
+// GetPendingJobs returns copies of the jobs that need to be scheduled
+func (s *MemoryStore) GetPendingJobs() []*models.Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pending := make([]*models.Job, 0)
+	for _, job := range s.jobs {
+		if job.Status == models.JobStatusPending {
+			pending = append(pending, job.Clone())
+		}
+	}
+	return pending
+}
+
+// RemoveJobs deletes finished jobs together with all their attempts and
+// returns how many were removed. Unknown and unfinished jobs are skipped.
+func (s *MemoryStore) RemoveJobs(ids ...string) (int, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	cs := &ChangeSet{}
+	s.mu.RLock()
+	for _, id := range ids {
+		job, ok := s.jobs[id]
+		if !ok || !job.Status.IsTerminal() {
+			continue
+		}
+		cs.RemovedJobs = append(cs.RemovedJobs, id)
+		cs.RemovedTasks = append(cs.RemovedTasks, job.TaskIDs...)
+	}
+	s.mu.RUnlock()
+	if len(cs.RemovedJobs) == 0 {
+		return 0, nil
+	}
+	if err := s.commit(cs); err != nil {
+		return 0, err
+	}
+	return len(cs.RemovedJobs), nil
+}
+
+// StartAttempt atomically moves a pending job to SCHEDULED and records task
+// as its new attempt. The task's Attempt number is filled in.
+func (s *MemoryStore) StartAttempt(jobID string, task *models.Task, reason string) (*models.Job, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	updated, ok := s.GetJob(jobID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+	if err := updated.Transition(models.JobStatusScheduled, reason, time.Now()); err != nil {
+		return nil, err
+	}
+	updated.WorkerID = task.WorkerID
+	updated.TaskIDs = append(updated.TaskIDs, task.ID)
+	task.Attempt = len(updated.TaskIDs)
+	cs := &ChangeSet{
+		Jobs:  []*models.Job{updated},
+		Tasks: []*models.Task{task.Clone()},
+	}
+	if err := s.commit(cs); err != nil {
+		return nil, err
+	}
+	return updated.Clone(), nil
+}
+
+// ApplyTaskUpdate atomically applies a status change to a task. mutate is
+// called with copies of the task's job and the task itself; returning an
+// error aborts the update. If the task is the job's current attempt the job
+// is also moved to jobStatus, which must be a legal transition.
+func (s *MemoryStore) ApplyTaskUpdate(taskID string, jobStatus models.JobStatus, reason string, mutate func(*models.Job, *models.Task) error) (*models.Job, *models.Task, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	updatedTask, ok := s.GetTask(taskID)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrTaskNotFound, taskID)
+	}
+	updatedJob, ok := s.GetJob(updatedTask.JobID)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrJobNotFound, updatedTask.JobID)
+	}
+	if err := mutate(updatedJob, updatedTask); err != nil {
+		return nil, nil, err
+	}
+	if updatedJob.CurrentTaskID() == taskID && updatedJob.Status != jobStatus {
+		if err := updatedJob.Transition(jobStatus, reason, time.Now()); err != nil {
+			return nil, nil, err
+		}
+	}
+	cs := &ChangeSet{
+		Jobs:  []*models.Job{updatedJob},
+		Tasks: []*models.Task{updatedTask},
+	}
+	if err := s.commit(cs); err != nil {
+		return nil, nil, err
+	}
+	return updatedJob.Clone(), updatedTask.Clone(), nil
+}
+
+// GetTask retrieves a copy of a task by ID
+func (s *MemoryStore) GetTask(id string) (*models.Task, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	task, ok := s.tasks[id]
+	if !ok {
+		return nil, false
+	}
+	return task.Clone(), true
+}
+
+// GetTasksForJob returns copies of every attempt of a job, oldest first
+func (s *MemoryStore) GetTasksForJob(jobID string) []*models.Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return nil
+	}
+	tasks := make([]*models.Task, 0, len(job.TaskIDs))
+	for _, id := range job.TaskIDs {
+		if task, ok := s.tasks[id]; ok {
+			tasks = append(tasks, task.Clone())
+		}
+	}
+	return tasks
+}
+
+// GetActiveTasksForWorker returns the tasks currently scheduled or running on a worker
+func (s *MemoryStore) GetActiveTasksForWorker(workerID string) []*models.Task {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	active := make([]*models.Task, 0)
+	for _, task := range s.tasks {
+		if task.WorkerID != workerID {
+			continue
+		}
+		if task.Status == models.JobStatusScheduled || task.Status == models.JobStatusRunning {
+			active = append(active, task.Clone())
+		}
+	}
+	return active
+}
+
+// RegisterWorker adds or updates a worker
+func (s *MemoryStore) RegisterWorker(worker *models.Worker) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.commit(&ChangeSet{Workers: []*models.Worker{worker.Clone()}})
+}
+
+// UpdateWorker atomically applies fn to a copy of a worker and stores the
+// result. Returning an error from fn aborts the update.
+func (s *MemoryStore) UpdateWorker(id string, fn func(*models.Worker) error) (*models.Worker, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	updated, ok := s.GetWorker(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrWorkerNotFound, id)
+	}
+	if err := fn(updated); err != nil {
+		return nil, err
+	}
+	if err := s.commit(&ChangeSet{Workers: []*models.Worker{updated}}); err != nil {
+		return nil, err
+	}
+	return updated.Clone(), nil
+}
+
+// RemoveWorker deletes a worker from the cluster
+func (s *MemoryStore) RemoveWorker(id string) (bool, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, ok := s.GetWorker(id); !ok {
+		return false, nil
+	}
+	if err := s.commit(&ChangeSet{RemovedWorkers: []string{id}}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetWorker retrieves a copy of a worker by ID
+func (s *MemoryStore) GetWorker(id string) (*models.Worker, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	worker, ok := s.workers[id]
+	if !ok {
+		return nil, false
+	}
+	return worker.Clone(), true
+}
+
+// UpdateWorkerHeartbeat updates the last heartbeat time for a worker and
+// returns the worker as it was before the update
+func (s *MemoryStore) UpdateWorkerHeartbeat(workerID string, usage *models.Worker) (*models.Worker, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	prev, ok := s.GetWorker(workerID)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrWorkerNotFound, workerID)
+	}
+	updated := prev.Clone()
+	updated.LastHeartbeat = time.Now()
+	if usage != nil {
+		updated.UsedCPU = usage.UsedCPU
+		updated.UsedMemory = usage.UsedMemory
+	}
+	updated.Status = models.WorkerStatusHealthy
+	if err := s.commit(&ChangeSet{Workers: []*models.Worker{updated}, Volatile: true}); err != nil {
+		return nil, err
+	}
+	return prev, nil
+}
+
+// GetHealthyWorkers returns copies of all workers that are healthy
+func (s *MemoryStore) GetHealthyWorkers() []*models.Worker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	healthy := make([]*models.Worker, 0)
+	now := time.Now()
+	for _, worker := range s.workers {
+		if now.Sub(worker.LastHeartbeat) < heartbeatTimeout {
+			healthy = append(healthy, worker.Clone())
+		}
+	}
+	return healthy
+}
+
+// GetAllWorkers returns copies of all registered workers
+func (s *MemoryStore) GetAllWorkers() []*models.Worker {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	workers := make([]*models.Worker, 0, len(s.workers))
+	for _, worker := range s.workers {
+		workers = append(workers, worker.Clone())
+	}
+	return workers
+}
+
+// PutSecret adds or replaces a secret
+func (s *MemoryStore) PutSecret(secret *models.Secret) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.commit(&ChangeSet{Secrets: []*models.Secret{secret.Clone()}})
+}
+
+// GetSecret retrieves a copy of a secret by name
+func (s *MemoryStore) GetSecret(name string) (*models.Secret, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	secret, ok := s.secrets[name]
+	if !ok {
+		return nil, false
+	}
+	return secret.Clone(), true
+}
+
+// GetAllSecrets returns copies of all secrets
+func (s *MemoryStore) GetAllSecrets() []*models.Secret {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	secrets := make([]*models.Secret, 0, len(s.secrets))
+	for _, secret := range s.secrets {
+		secrets = append(secrets, secret.Clone())
+	}
+	return secrets
+}
+
+// RemoveSecret deletes a secret, reporting whether it existed
+func (s *MemoryStore) RemoveSecret(name string) (bool, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, ok := s.GetSecret(name); !ok {
+		return false, nil
+	}
+	if err := s.commit(&ChangeSet{RemovedSecrets: []string{name}}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// PutTemplate adds or replaces a job template
+func (s *MemoryStore) PutTemplate(template *models.JobTemplate) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.commit(&ChangeSet{Templates: []*models.JobTemplate{template.Clone()}})
+}
+
+// GetTemplate retrieves a copy of a job template by name
+func (s *MemoryStore) GetTemplate(name string) (*models.JobTemplate, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	template, ok := s.templates[name]
+	if !ok {
+		return nil, false
+	}
+	return template.Clone(), true
+}
+
+// GetAllTemplates returns copies of all job templates
+func (s *MemoryStore) GetAllTemplates() []*models.JobTemplate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	templates := make([]*models.JobTemplate, 0, len(s.templates))
+	for _, template := range s.templates {
+		templates = append(templates, template.Clone())
+	}
+	return templates
+}
+
+// RemoveTemplate deletes a job template, reporting whether it existed
+func (s *MemoryStore) RemoveTemplate(name string) (bool, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if _, ok := s.GetTemplate(name); !ok {
+		return false, nil
+	}
+	if err := s.commit(&ChangeSet{RemovedTemplates: []string{name}}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Close implements Store. State held only in memory is simply dropped.
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// snapshot returns copies of every record in the store as one change set,
+// and the resource version they reflect
+func (s *MemoryStore) snapshot() (*ChangeSet, uint64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	cs := &ChangeSet{}
+	for _, job := range s.jobs {
+		cs.Jobs = append(cs.Jobs, job.Clone())
+	}
+	for _, task := range s.tasks {
+		cs.Tasks = append(cs.Tasks, task.Clone())
+	}
+	for _, worker := range s.workers {
+		cs.Workers = append(cs.Workers, worker.Clone())
+	}
+	for _, secret := range s.secrets {
+		cs.Secrets = append(cs.Secrets, secret.Clone())
+	}
+	for _, template := range s.templates {
+		cs.Templates = append(cs.Templates, template.Clone())
+	}
+	return cs, s.events.current()
+}
+
+// reset replaces the whole contents of the store with a snapshot taken at
+// resource version. Watchers of earlier versions must re-read state.
+func (s *MemoryStore) reset(cs *ChangeSet, version uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = make(map[string]*models.Job)
+	s.tasks = make(map[string]*models.Task)
+	s.workers = make(map[string]*models.Worker)
+	s.secrets = make(map[string]*models.Secret)
+	s.templates = make(map[string]*models.JobTemplate)
+	s.applyLocked(cs)
+	s.events.reset(version)
+}
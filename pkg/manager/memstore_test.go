@@ -0,0 +1,211 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"titan/pkg/models"
+)
+
+// The stress tests below race many goroutines on the same records. Run
+// them with -race to also check the store for data races.
+
+const (
+	stressGoroutines = 32
+	stressJobs       = 20
+)
+
+// raceAll runs fn on stressGoroutines goroutines, released together so
+// their calls overlap, and waits for all of them
+func raceAll(fn func(g int)) {
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < stressGoroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			<-start
+			fn(g)
+		}(g)
+	}
+	close(start)
+	wg.Wait()
+}
+
+// TestStoreConcurrentStartAttempt has every goroutine try to schedule
+// every job, as several scheduler passes would. Each job must be
+// scheduled exactly once.
+func TestStoreConcurrentStartAttempt(t *testing.T) {
+	for _, backend := range storeBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			s := backend.open(t)
+			defer s.Close()
+			for i := 0; i < stressJobs; i++ {
+				addPendingJob(t, s, fmt.Sprintf("j%d", i))
+			}
+
+			var mu sync.Mutex
+			wins := make(map[string][]string) // Job ID to winning task IDs
+			for i := 0; i < stressJobs; i++ {
+				jobID := fmt.Sprintf("j%d", i)
+				raceAll(func(g int) {
+					task := &models.Task{
+						ID:       fmt.Sprintf("%s-g%d", jobID, g),
+						JobID:    jobID,
+						WorkerID: fmt.Sprintf("w%d", g),
+						Status:   models.JobStatusScheduled,
+					}
+					_, err := s.StartAttempt(jobID, task, "scheduled")
+					var transitionErr *models.TransitionError
+					switch {
+					case err == nil:
+						mu.Lock()
+						wins[jobID] = append(wins[jobID], task.ID)
+						mu.Unlock()
+					case !errors.As(err, &transitionErr):
+						t.Errorf("StartAttempt(%s): %v", jobID, err)
+					}
+				})
+			}
+
+			for i := 0; i < stressJobs; i++ {
+				jobID := fmt.Sprintf("j%d", i)
+				if len(wins[jobID]) != 1 {
+					t.Errorf("%s was scheduled %d times: %v", jobID, len(wins[jobID]), wins[jobID])
+					continue
+				}
+				job, _ := s.GetJob(jobID)
+				if job.Status != models.JobStatusScheduled || len(job.TaskIDs) != 1 || job.TaskIDs[0] != wins[jobID][0] {
+					t.Errorf("%s is %s with attempts %v, want only %s", jobID, job.Status, job.TaskIDs, wins[jobID][0])
+				}
+				if tasks := s.GetTasksForJob(jobID); len(tasks) != 1 || tasks[0].Attempt != 1 {
+					t.Errorf("%s has %d task records", jobID, len(tasks))
+				}
+			}
+			if n := len(s.GetPendingJobs()); n != 0 {
+				t.Errorf("%d jobs still pending", n)
+			}
+		})
+	}
+}
+
+// TestStoreConcurrentCompareAndSwap races swaps out of the same status.
+// Exactly one may win; the rest must see a conflict.
+func TestStoreConcurrentCompareAndSwap(t *testing.T) {
+	for _, backend := range storeBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			s := backend.open(t)
+			defer s.Close()
+			addPendingJob(t, s, "j1")
+
+			var mu sync.Mutex
+			var winners []int
+			raceAll(func(g int) {
+				_, err := s.CompareAndSwapJob("j1", models.JobStatusPending, func(j *models.Job) error {
+					runtime.Gosched() // Let the others read the job meanwhile
+					j.WorkerID = fmt.Sprintf("w%d", g)
+					return j.Transition(models.JobStatusScheduled, "scheduled", time.Now())
+				})
+				switch {
+				case err == nil:
+					mu.Lock()
+					winners = append(winners, g)
+					mu.Unlock()
+				case !errors.Is(err, ErrStatusConflict):
+					t.Errorf("CompareAndSwapJob: %v", err)
+				}
+			})
+
+			if len(winners) != 1 {
+				t.Fatalf("%d swaps won: %v", len(winners), winners)
+			}
+			job, _ := s.GetJob("j1")
+			if job.WorkerID != fmt.Sprintf("w%d", winners[0]) || len(job.History) != 2 {
+				t.Errorf("job placed on %s with %d history entries, want w%d and 2", job.WorkerID, len(job.History), winners[0])
+			}
+		})
+	}
+}
+
+// TestStoreConcurrentUpdateJob checks that concurrent read-modify-write
+// updates of one job lose nothing
+func TestStoreConcurrentUpdateJob(t *testing.T) {
+	const updates = 20
+	for _, backend := range storeBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			s := backend.open(t)
+			defer s.Close()
+			addPendingJob(t, s, "j1")
+
+			raceAll(func(int) {
+				for i := 0; i < updates; i++ {
+					if _, err := s.UpdateJob("j1", func(j *models.Job) error {
+						runtime.Gosched()
+						j.Retries++
+						return nil
+					}); err != nil {
+						t.Errorf("UpdateJob: %v", err)
+					}
+					s.GetJob("j1")
+					s.GetAllJobs()
+				}
+			})
+
+			if job, _ := s.GetJob("j1"); job.Retries != stressGoroutines*updates {
+				t.Errorf("counter is %d after %d increments", job.Retries, stressGoroutines*updates)
+			}
+		})
+	}
+}
+
+// TestStoreConcurrentTaskUpdates delivers a task's status updates many
+// times over, in every order, as retrying workers and outboxes might. The
+// task must end at its last update and its job follow exactly once.
+func TestStoreConcurrentTaskUpdates(t *testing.T) {
+	updates := []models.JobStatus{models.JobStatusRunning, models.JobStatusCompleted}
+	for _, backend := range storeBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			s := backend.open(t)
+			defer s.Close()
+			addPendingJob(t, s, "j1")
+			startAttempt(t, s, "j1", "t1", "w1")
+
+			raceAll(func(g int) {
+				for i := range updates {
+					// Half the goroutines deliver newest first
+					seq := i
+					if g%2 == 1 {
+						seq = len(updates) - 1 - i
+					}
+					_, _, err := reportTask(s, "t1", updates[seq], uint64(seq+1))
+					var transitionErr *models.TransitionError
+					if err != nil && !errors.Is(err, errStaleUpdate) && !errors.As(err, &transitionErr) {
+						t.Errorf("ApplyTaskUpdate: %v", err)
+					}
+				}
+			})
+
+			task, _ := s.GetTask("t1")
+			job, _ := s.GetJob("j1")
+			if task.Status != models.JobStatusCompleted || task.StatusSeq != uint64(len(updates)) {
+				t.Errorf("task ended %s at seq %d", task.Status, task.StatusSeq)
+			}
+			if job.Status != models.JobStatusCompleted {
+				t.Errorf("job ended %s", job.Status)
+			}
+			completed := 0
+			for _, h := range job.History {
+				if h.To == models.JobStatusCompleted {
+					completed++
+				}
+			}
+			if completed != 1 {
+				t.Errorf("job completed %d times", completed)
+			}
+		})
+	}
+}
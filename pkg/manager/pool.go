@@ -0,0 +1,134 @@
+package manager
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"titan/pkg/auth"
+	"titan/pkg/certs"
+	"titan/pkg/logger"
+	"titan/pkg/models"
+)
+
+// dialTimeout bounds how long connecting to a worker may take
+const dialTimeout = 3 * time.Second
+
+// pooledClient is a connection to a worker at a known address
+type pooledClient struct {
+	address string
+	client  *rpc.Client
+}
+
+// workerPool is a thread-safe set of net/rpc connections to workers,
+// dialed on demand and dropped when they fail or the worker goes away
+type workerPool struct {
+	tls *tls.Config // nil for plaintext connections
+
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+}
+
+func newWorkerPool(tlsConfig *tls.Config) *workerPool {
+	return &workerPool{
+		tls:     tlsConfig,
+		clients: make(map[string]*pooledClient),
+	}
+}
+
+// get returns a connection to the worker, dialing one if needed. A worker
+// that re-registered with a new address gets a fresh connection.
+func (p *workerPool) get(worker *models.Worker) (*rpc.Client, error) {
+	p.mu.Lock()
+	if pc, ok := p.clients[worker.ID]; ok {
+		if pc.address == worker.Address {
+			p.mu.Unlock()
+			return pc.client, nil
+		}
+		pc.client.Close()
+		delete(p.clients, worker.ID)
+	}
+	p.mu.Unlock()
+
+	// Dial without holding the lock so one slow worker doesn't block others
+	conn, err := p.dial(worker)
+	if err != nil {
+		return nil, err
+	}
+	client := rpc.NewClient(conn)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if pc, ok := p.clients[worker.ID]; ok && pc.address == worker.Address {
+		// Another dispatch connected first; use its connection
+		client.Close()
+		return pc.client, nil
+	}
+	p.clients[worker.ID] = &pooledClient{address: worker.Address, client: client}
+	return client, nil
+}
+
+// dial connects to a worker. Over TLS, the worker must prove it is the
+// worker it registered as, so tasks can't be sent to an impostor that took
+// over its address.
+func (p *workerPool) dial(worker *models.Worker) (net.Conn, error) {
+	if p.tls == nil {
+		return net.DialTimeout("tcp", worker.Address, dialTimeout)
+	}
+	cfg := certs.RequirePeer(p.tls, func(peer auth.Caller) error {
+		if peer.Kind != auth.KindWorker || peer.Name != worker.ID {
+			return fmt.Errorf("peer %s is not worker %q", peer, worker.ID)
+		}
+		return nil
+	})
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	return tls.DialWithDialer(dialer, "tcp", worker.Address, cfg)
+}
+
+// remove closes and forgets the connection to a worker, if it is still
+// the given client. A nil client removes whatever connection exists.
+func (p *workerPool) remove(workerID string, client *rpc.Client) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pc, ok := p.clients[workerID]
+	if !ok || (client != nil && pc.client != client) {
+		return
+	}
+	pc.client.Close()
+	delete(p.clients, workerID)
+}
+
+// prune drops connections to workers that are no longer registered or
+// healthy according to the store
+func (p *workerPool) prune(store Store) {
+	p.mu.Lock()
+	ids := make([]string, 0, len(p.clients))
+	for id := range p.clients {
+		ids = append(ids, id)
+	}
+	p.mu.Unlock()
+
+	for _, id := range ids {
+		worker, ok := store.GetWorker(id)
+		if ok && time.Since(worker.LastHeartbeat) < heartbeatTimeout {
+			continue
+		}
+		logger.Info("Closing connection to unhealthy worker", "worker_id", id)
+		p.remove(id, nil)
+	}
+}
+
+// closeAll closes every connection
+func (p *workerPool) closeAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for id, pc := range p.clients {
+		if err := pc.client.Close(); err != nil {
+			logger.Warn("Failed to close worker client", "worker_id", id, "error", err)
+		}
+		delete(p.clients, id)
+	}
+}
@@ -0,0 +1,376 @@
+package manager
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"titan/pkg/auth"
+	"titan/pkg/certs"
+	"titan/pkg/logger"
+	pb "titan/pkg/proto"
+)
+
+const (
+	// raftApplyTimeout bounds how long a write waits to be committed
+	raftApplyTimeout = 5 * time.Second
+
+	// raftSnapshotsRetained is how many FSM snapshots are kept on disk
+	raftSnapshotsRetained = 2
+)
+
+// raftTimeout is how long a follower waits to hear from the leader before it
+// calls an election. It is a variable so tests can elect leaders quickly.
+var raftTimeout = time.Second
+
+// Peer is one manager replica in a Raft cluster
+type Peer struct {
+	ID       string
+	RPCAddr  string // Address serving the manager RPC API
+	RaftAddr string // Address used for Raft traffic between replicas
+}
+
+// ParsePeers parses a comma-separated list of peers, each written as
+// id=rpc-address/raft-address
+func ParsePeers(list string) ([]Peer, error) {
+	var peers []Peer
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, addrs, ok := strings.Cut(entry, "=")
+		rpcAddr, raftAddr, ok2 := strings.Cut(addrs, "/")
+		if !ok || !ok2 || id == "" || rpcAddr == "" || raftAddr == "" {
+			return nil, fmt.Errorf("invalid peer %q, expected id=rpc-address/raft-address", entry)
+		}
+		peers = append(peers, Peer{ID: id, RPCAddr: rpcAddr, RaftAddr: raftAddr})
+	}
+	return peers, nil
+}
+
+// RaftConfig configures a replicated store
+type RaftConfig struct {
+	NodeID  string // ID of this replica; must be one of Peers
+	DataDir string // Raft log, stable store and snapshots
+	Peers   []Peer // Every replica in the cluster, including this one
+
+	// TLS, if set, secures Raft traffic; peers must present a manager
+	// certificate
+	TLS *tls.Config
+}
+
+// RaftStore replicates the store across manager replicas with Raft. Every
+// change set is a Raft log entry; each replica applies committed entries to
+// its own in-memory copy. Only the leader accepts updates.
+type RaftStore struct {
+	*MemoryStore
+	raft      *raft.Raft
+	transport *raft.NetworkTransport
+	logStore  *raftboltdb.BoltStore
+	peers     map[raft.ServerAddress]Peer
+
+	// ready is set once this replica is leader and has applied every entry
+	// committed by earlier leaders, so its reads reflect the whole log
+	ready    atomic.Bool
+	mu       sync.Mutex
+	onLeader []func()
+	stopChan chan struct{}
+	doneChan chan struct{}
+}
+
+// OpenRaftStore starts this replica's Raft node. A new cluster is
+// bootstrapped with every configured peer the first time it starts.
+func OpenRaftStore(cfg RaftConfig) (*RaftStore, error) {
+	var self *Peer
+	peers := make(map[raft.ServerAddress]Peer, len(cfg.Peers))
+	servers := make([]raft.Server, 0, len(cfg.Peers))
+	for i, peer := range cfg.Peers {
+		if peer.ID == cfg.NodeID {
+			self = &cfg.Peers[i]
+		}
+		peers[raft.ServerAddress(peer.RaftAddr)] = peer
+		servers = append(servers, raft.Server{
+			ID:      raft.ServerID(peer.ID),
+			Address: raft.ServerAddress(peer.RaftAddr),
+		})
+	}
+	if self == nil {
+		return nil, fmt.Errorf("node %q is not in the peer list", cfg.NodeID)
+	}
+
+	if err := os.MkdirAll(cfg.DataDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	s := &RaftStore{
+		MemoryStore: newMemoryStore(),
+		peers:       peers,
+		stopChan:    make(chan struct{}),
+		doneChan:    make(chan struct{}),
+	}
+	s.commit = s.propose
+
+	config := raft.DefaultConfig()
+	config.LocalID = raft.ServerID(cfg.NodeID)
+	config.HeartbeatTimeout = raftTimeout
+	config.ElectionTimeout = raftTimeout
+	config.LeaderLeaseTimeout = raftTimeout / 2
+	config.Logger = hclog.New(&hclog.LoggerOptions{
+		Name:       "raft",
+		Level:      hclog.Warn,
+		Output:     os.Stdout,
+		JSONFormat: true,
+	})
+
+	logStore, err := raftboltdb.NewBoltStore(filepath.Join(cfg.DataDir, "raft.db"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open raft log: %w", err)
+	}
+	snapshots, err := raft.NewFileSnapshotStore(cfg.DataDir, raftSnapshotsRetained, io.Discard)
+	if err != nil {
+		logStore.Close()
+		return nil, fmt.Errorf("failed to open raft snapshots: %w", err)
+	}
+	bindAddr, err := net.ResolveTCPAddr("tcp", self.RaftAddr)
+	if err != nil {
+		logStore.Close()
+		return nil, fmt.Errorf("invalid raft address %q: %w", self.RaftAddr, err)
+	}
+	transport, err := newRaftTransport(self.RaftAddr, bindAddr, cfg.TLS)
+	if err != nil {
+		logStore.Close()
+		return nil, fmt.Errorf("failed to start raft transport: %w", err)
+	}
+
+	r, err := raft.NewRaft(config, (*raftFSM)(s.MemoryStore), logStore, logStore, snapshots, transport)
+	if err != nil {
+		transport.Close()
+		logStore.Close()
+		return nil, fmt.Errorf("failed to start raft: %w", err)
+	}
+	s.raft = r
+	s.transport = transport
+	s.logStore = logStore
+
+	hasState, err := raft.HasExistingState(logStore, logStore, snapshots)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("failed to inspect raft state: %w", err)
+	}
+	if !hasState {
+		// Every replica bootstraps with the same configuration, which is safe
+		f := r.BootstrapCluster(raft.Configuration{Servers: servers})
+		if err := f.Error(); err != nil && !errors.Is(err, raft.ErrCantBootstrap) {
+			s.Close()
+			return nil, fmt.Errorf("failed to bootstrap cluster: %w", err)
+		}
+	}
+
+	go s.watchLeadership()
+
+	logger.Info("Raft node started", "node_id", cfg.NodeID, "raft_address", self.RaftAddr, "peers", len(cfg.Peers))
+	return s, nil
+}
+
+// newRaftTransport listens for Raft peers on bindAddr, over TLS if
+// tlsConfig is set
+func newRaftTransport(bindAddr string, advertise *net.TCPAddr, tlsConfig *tls.Config) (*raft.NetworkTransport, error) {
+	if tlsConfig == nil {
+		return raft.NewTCPTransport(bindAddr, advertise, 3, 10*time.Second, io.Discard)
+	}
+	tlsConfig = certs.RequirePeer(tlsConfig, certs.RequireKind(auth.KindManager))
+	listener, err := tls.Listen("tcp", bindAddr, tlsConfig)
+	if err != nil {
+		return nil, err
+	}
+	stream := &tlsStreamLayer{Listener: listener, advertise: advertise, config: tlsConfig}
+	return raft.NewNetworkTransport(stream, 3, 10*time.Second, io.Discard), nil
+}
+
+// tlsStreamLayer carries Raft traffic over mutual TLS
+type tlsStreamLayer struct {
+	net.Listener
+	advertise net.Addr
+	config    *tls.Config
+}
+
+func (l *tlsStreamLayer) Dial(address raft.ServerAddress, timeout time.Duration) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	return tls.DialWithDialer(dialer, "tcp", string(address), l.config)
+}
+
+func (l *tlsStreamLayer) Addr() net.Addr {
+	return l.advertise
+}
+
+// watchLeadership tracks leadership changes. On becoming leader it waits
+// until every earlier entry is applied before accepting writes.
+func (s *RaftStore) watchLeadership() {
+	defer close(s.doneChan)
+	for {
+		select {
+		case isLeader := <-s.raft.LeaderCh():
+			s.ready.Store(false)
+			if !isLeader {
+				logger.Info("Lost leadership")
+				continue
+			}
+			if err := s.raft.Barrier(raftApplyTimeout).Error(); err != nil {
+				logger.Warn("Failed to catch up after becoming leader", "error", err)
+				continue
+			}
+			s.ready.Store(true)
+			logger.Info("Became leader")
+
+			s.mu.Lock()
+			callbacks := append([]func(){}, s.onLeader...)
+			s.mu.Unlock()
+			for _, fn := range callbacks {
+				fn()
+			}
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// CheckLeader returns a *pb.NotLeaderError naming the current leader unless
+// this replica is the leader and has caught up with the log
+func (s *RaftStore) CheckLeader() error {
+	if s.raft.State() == raft.Leader && s.ready.Load() {
+		return nil
+	}
+	leaderAddr, _ := s.raft.LeaderWithID()
+	return &pb.NotLeaderError{Leader: s.peers[leaderAddr].RPCAddr}
+}
+
+// OnLeader registers fn to run each time this replica becomes leader
+func (s *RaftStore) OnLeader(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onLeader = append(s.onLeader, fn)
+}
+
+// propose replicates a change set and waits until it has been committed
+// and applied locally. If leadership is lost while waiting, the change may
+// still be committed by the next leader.
+//
+// Routine heartbeats are the exception: only the leader tracks liveness, so
+// they are applied on the leader alone rather than fsynced to every
+// replica's log. Heartbeats that bring a worker back are replicated, so
+// every replica's watchers see it. A new leader starts from the liveness
+// last replicated, as after a restart of the bolt backend.
+func (s *RaftStore) propose(cs *ChangeSet) error {
+	if err := s.CheckLeader(); err != nil {
+		return err
+	}
+	if s.routine(cs) {
+		s.applyUnversioned(cs)
+		return nil
+	}
+	data, err := json.Marshal(cs)
+	if err != nil {
+		return fmt.Errorf("failed to encode change: %w", err)
+	}
+	f := s.raft.Apply(data, raftApplyTimeout)
+	if err := f.Error(); err != nil {
+		if errors.Is(err, raft.ErrNotLeader) || errors.Is(err, raft.ErrLeadershipLost) {
+			return s.CheckLeader()
+		}
+		return fmt.Errorf("failed to replicate change: %w", err)
+	}
+	if err, ok := f.Response().(error); ok {
+		return err
+	}
+	return nil
+}
+
+// Close hands leadership to another replica if this one holds it, then
+// stops the Raft node and closes its log
+func (s *RaftStore) Close() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.raft.State() == raft.Leader {
+		if err := s.raft.LeadershipTransfer().Error(); err != nil {
+			logger.Warn("Failed to transfer leadership", "error", err)
+		}
+	}
+	close(s.stopChan)
+	err := s.raft.Shutdown().Error()
+	<-s.doneChan
+	if cerr := s.transport.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := s.logStore.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// raftFSM applies committed log entries to a replica's in-memory store
+type raftFSM MemoryStore
+
+// Apply decodes a committed change set and applies it
+func (f *raftFSM) Apply(entry *raft.Log) interface{} {
+	var cs ChangeSet
+	if err := json.Unmarshal(entry.Data, &cs); err != nil {
+		logger.Error("Failed to decode raft log entry", "index", entry.Index, "error", err)
+		return err
+	}
+	// The log index is the resource version, so it is the same on every
+	// replica and watchers can resume on a new leader
+	(*MemoryStore)(f).applyAt(&cs, entry.Index)
+	return nil
+}
+
+// Snapshot captures the whole store for log compaction
+func (f *raftFSM) Snapshot() (raft.FSMSnapshot, error) {
+	state, version := (*MemoryStore)(f).snapshot()
+	return &raftSnapshot{Version: version, State: state}, nil
+}
+
+// Restore replaces the store with a snapshot taken by another replica
+func (f *raftFSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	var snapshot raftSnapshot
+	if err := json.NewDecoder(rc).Decode(&snapshot); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if snapshot.State == nil {
+		snapshot.State = &ChangeSet{}
+	}
+	(*MemoryStore)(f).reset(snapshot.State, snapshot.Version)
+	return nil
+}
+
+// raftSnapshot is a point-in-time copy of the store
+type raftSnapshot struct {
+	Version uint64 // Resource version the state reflects
+	State   *ChangeSet
+}
+
+// Persist writes the snapshot to sink
+func (s *raftSnapshot) Persist(sink raft.SnapshotSink) error {
+	if err := json.NewEncoder(sink).Encode(s); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+// Release is a no-op; the snapshot holds no resources
+func (s *raftSnapshot) Release() {}
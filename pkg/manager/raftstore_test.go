@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+)
+
+func init() {
+	// Elect leaders in a fraction of the default time
+	raftTimeout = 200 * time.Millisecond
+}
+
+// freeAddr returns a loopback address nothing listens on
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// openRaftCluster starts n replicas of a new cluster on loopback ports and
+// waits until one leads. The caller closes the stores.
+func openRaftCluster(t *testing.T, n int) ([]*RaftStore, []Peer) {
+	t.Helper()
+	peers := make([]Peer, n)
+	for i := range peers {
+		peers[i] = Peer{ID: fmt.Sprintf("m%d", i+1), RPCAddr: fmt.Sprintf("m%d.test:8080", i+1), RaftAddr: freeAddr(t)}
+	}
+	stores := make([]*RaftStore, n)
+	for i, peer := range peers {
+		s, err := OpenRaftStore(RaftConfig{NodeID: peer.ID, DataDir: t.TempDir(), Peers: peers})
+		if err != nil {
+			t.Fatalf("OpenRaftStore(%s): %v", peer.ID, err)
+		}
+		stores[i] = s
+	}
+	waitForLeader(t, stores)
+	return stores, peers
+}
+
+// waitForLeader returns the index of the replica that leads and has caught
+// up with the log
+func waitForLeader(t *testing.T, stores []*RaftStore) int {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for i, s := range stores {
+			if s.CheckLeader() == nil {
+				return i
+			}
+		}
+	}
+	t.Fatal("no leader elected")
+	return -1
+}
+
+// eventually fails the test unless cond holds within a few seconds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatalf("timed out waiting until %s", what)
+}
+
+// TestRaftCluster runs a three-replica cluster on loopback: followers
+// refuse writes and name the leader, committed state reaches every replica,
+// routine heartbeats stay on the leader, and the state survives the loss
+// of the leader.
+func TestRaftCluster(t *testing.T) {
+	stores, peers := openRaftCluster(t, 3)
+	leader := waitForLeader(t, stores)
+	var followers []*RaftStore
+	for i, s := range stores {
+		if i != leader {
+			followers = append(followers, s)
+		}
+	}
+	defer func() {
+		for _, s := range followers {
+			s.Close()
+		}
+	}()
+
+	for _, s := range followers {
+		var notLeader *pb.NotLeaderError
+		if err := s.CheckLeader(); !errors.As(err, &notLeader) || notLeader.Leader != peers[leader].RPCAddr {
+			t.Errorf("follower CheckLeader = %v, want a redirect to %s", err, peers[leader].RPCAddr)
+		}
+		if err := s.AddJob(models.NewJob("rejected", models.JobStatusPending, "submitted", time.Now())); !errors.As(err, &notLeader) {
+			t.Errorf("follower AddJob = %v, want a redirect", err)
+		}
+	}
+
+	addPendingJob(t, stores[leader], "j1")
+	startAttempt(t, stores[leader], "j1", "t1", "w1")
+	if err := stores[leader].RegisterWorker(&models.Worker{ID: "w1", Address: "127.0.0.1:1", LastHeartbeat: time.Now()}); err != nil {
+		t.Fatalf("RegisterWorker: %v", err)
+	}
+	version := stores[leader].Version()
+	for i, s := range followers {
+		eventually(t, fmt.Sprintf("follower %d has caught up", i), func() bool {
+			job, ok := s.GetJob("j1")
+			_, registered := s.GetWorker("w1")
+			return ok && job.Status == models.JobStatusScheduled && registered && s.Version() == version
+		})
+	}
+
+	if _, err := stores[leader].UpdateWorkerHeartbeat("w1", &models.Worker{UsedCPU: 500}); err != nil {
+		t.Fatalf("UpdateWorkerHeartbeat: %v", err)
+	}
+	if v := stores[leader].Version(); v != version {
+		t.Errorf("a routine heartbeat was replicated: version %d -> %d", version, v)
+	}
+	if w, _ := stores[leader].GetWorker("w1"); w.UsedCPU != 500 {
+		t.Errorf("leader didn't apply the heartbeat: used CPU %d", w.UsedCPU)
+	}
+
+	if err := stores[leader].Close(); err != nil {
+		t.Errorf("Close leader: %v", err)
+	}
+	next := followers[waitForLeader(t, followers)]
+	if job, ok := next.GetJob("j1"); !ok || job.Status != models.JobStatusScheduled || job.CurrentTaskID() != "t1" {
+		t.Fatalf("new leader lost j1: %+v", job)
+	}
+	addPendingJob(t, next, "j2")
+	for _, s := range followers {
+		eventually(t, "j2 is replicated after failover", func() bool {
+			_, ok := s.GetJob("j2")
+			return ok
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package manager
+
+import (
+	"time"
+
+	"titan/pkg/logger"
+)
+
+// reapInterval is how often the manager looks for workers that stopped
+// heartbeating
+const reapInterval = heartbeatTimeout / 3
+
+// reaper finds workers whose heartbeats stopped and has their unfinished
+// tasks recorded as LOST, so their jobs run again elsewhere
+type reaper struct {
+	store    Store
+	lose     func(workerID, reason string) int // Loses a worker's tasks
+	interval time.Duration
+	stopChan chan struct{}
+	doneChan chan struct{}
+
+	// leadingSince is when this replica last became able to reap. Heartbeats
+	// are not persisted, so after a restart or failover every worker gets a
+	// full heartbeatTimeout to check in before it counts as gone.
+	leadingSince time.Time
+}
+
+// newReaper creates a reaper that passes dead workers to lose
+func newReaper(store Store, lose func(workerID, reason string) int) *reaper {
+	return &reaper{
+		store:    store,
+		lose:     lose,
+		interval: reapInterval,
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// Run reaps dead workers until Stop is called
+func (r *reaper) Run() {
+	defer close(r.doneChan)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	r.reap(time.Now())
+	for {
+		select {
+		case <-ticker.C:
+			r.reap(time.Now())
+		case <-r.stopChan:
+			return
+		}
+	}
+}
+
+// Stop halts the reaper and waits for an in-progress pass to finish
+func (r *reaper) Stop() {
+	close(r.stopChan)
+	<-r.doneChan
+}
+
+// reap runs one pass, losing the tasks of every worker that hasn't sent a
+// heartbeat for heartbeatTimeout
+func (r *reaper) reap(now time.Time) {
+	// Only the leader of a replicated cluster reaps workers
+	if rs, ok := r.store.(replicatedStore); ok && rs.CheckLeader() != nil {
+		r.leadingSince = time.Time{}
+		return
+	}
+	if r.leadingSince.IsZero() {
+		r.leadingSince = now
+	}
+
+	for _, worker := range r.store.GetAllWorkers() {
+		lastSeen := worker.LastHeartbeat
+		if lastSeen.Before(r.leadingSince) {
+			lastSeen = r.leadingSince
+		}
+		if now.Sub(lastSeen) < heartbeatTimeout || len(r.store.GetActiveTasksForWorker(worker.ID)) == 0 {
+			continue
+		}
+		if lost := r.lose(worker.ID, "worker "+worker.ID+" stopped heartbeating"); lost > 0 {
+			logger.Warn("Lost tasks of unresponsive worker", "worker_id", worker.ID, "tasks", lost, "last_heartbeat", worker.LastHeartbeat)
+		}
+	}
+}
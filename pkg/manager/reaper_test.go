@@ -0,0 +1,62 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"titan/pkg/models"
+)
+
+// TestReaperLosesTasksOfSilentWorker checks that a worker that stops
+// heartbeating has its task marked LOST and its job requeued, once the
+// reaper has led for a full heartbeat timeout
+func TestReaperLosesTasksOfSilentWorker(t *testing.T) {
+	s := newTestServer(t, Config{})
+	silent := startFakeWorker(t, s, "silent")
+	startFakeWorker(t, s, "alive")
+	addPendingJob(t, s.store, "j1")
+	startAttempt(t, s.store, "j1", "t1", "silent")
+	reportTask(s.store, "t1", models.JobStatusRunning, 1)
+	addPendingJob(t, s.store, "j2")
+	startAttempt(t, s.store, "j2", "t2", "alive")
+
+	now := time.Now()
+	if _, err := s.store.UpdateWorker("silent", func(w *models.Worker) error {
+		w.LastHeartbeat = now.Add(-2 * heartbeatTimeout)
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateWorker: %v", err)
+	}
+
+	// Heartbeats aren't persisted, so a new leader waits before reaping
+	s.reaper.reap(now.Add(-heartbeatTimeout))
+	if task, _ := s.store.GetTask("t1"); task.Status != models.JobStatusRunning {
+		t.Fatalf("task reaped as soon as the reaper started: %s", task.Status)
+	}
+
+	s.reaper.reap(now)
+	select {
+	case stopped := <-silent.stopped:
+		if stopped != "t1" {
+			t.Errorf("stopped %s, want t1", stopped)
+		}
+	case <-time.After(time.Second):
+		t.Error("lost task was not stopped on its worker")
+	}
+
+	task, _ := s.store.GetTask("t1")
+	job, _ := s.store.GetJob("j1")
+	if task.Status != models.JobStatusLost || job.Status != models.JobStatusPending || job.WorkerID != "" || job.Retries != 0 {
+		t.Fatalf("task %s, job %s on %q after %d retries; want LOST, PENDING on none after 0", task.Status, job.Status, job.WorkerID, job.Retries)
+	}
+	var path []models.JobStatus
+	for _, h := range job.History {
+		path = append(path, h.To)
+	}
+	if n := len(path); n < 2 || path[n-2] != models.JobStatusLost || path[n-1] != models.JobStatusPending {
+		t.Errorf("job went through %v, want to end LOST, PENDING", path)
+	}
+	if task, _ := s.store.GetTask("t2"); task.Status != models.JobStatusScheduled {
+		t.Errorf("task of a live worker became %s", task.Status)
+	}
+}
@@ -0,0 +1,163 @@
+package manager
+
+import (
+	"context"
+	"net/rpc"
+	"sync"
+
+	"titan/pkg/auth"
+	pb "titan/pkg/proto"
+)
+
+// RPCServer returns a net/rpc server for one connection, serving the
+// AuthService, ManagerService and WorkerService. Its handlers run with ctx,
+// which carries the connection's caller; use it with rpcserver.NewPerConn.
+func (s *Server) RPCServer(ctx context.Context) *rpc.Server {
+	conn := &rpcConn{s: s, ctx: ctx}
+	server := rpc.NewServer()
+	server.RegisterName("AuthService", &rpcAuthService{conn})
+	server.RegisterName("ManagerService", &rpcManagerService{conn})
+	server.RegisterName("WorkerService", &rpcWorkerService{conn})
+	return server
+}
+
+// rpcConn is the state of one net/rpc connection. net/rpc has no per-call
+// metadata, so a client authenticates its connection once with a token,
+// which every later call on it then carries.
+type rpcConn struct {
+	s   *Server
+	ctx context.Context
+
+	mu    sync.Mutex
+	token string
+}
+
+// context returns the context for a call on the connection
+func (c *rpcConn) context() context.Context {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" {
+		return c.ctx
+	}
+	return auth.NewTokenContext(c.ctx, c.token)
+}
+
+// rpcAuthService adapts the AuthService handler to net/rpc
+type rpcAuthService struct {
+	*rpcConn
+}
+
+func (r *rpcAuthService) Authenticate(req pb.AuthRequest, resp *pb.AuthResponse) error {
+	if err := r.s.Authenticate(r.context(), req, resp); err != nil {
+		return err
+	}
+	if req.Token != "" {
+		r.mu.Lock()
+		r.token = req.Token
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+// rpcManagerService adapts the ManagerService handlers to net/rpc, whose
+// methods must have the signature func(args T1, reply *T2) error
+type rpcManagerService struct {
+	*rpcConn
+}
+
+func (r *rpcManagerService) SubmitJob(req pb.JobRequest, resp *pb.JobResponse) error {
+	return r.s.SubmitJob(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) GetJobStatus(req pb.JobStatusRequest, resp *pb.JobStatusResponse) error {
+	return r.s.GetJobStatus(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) ListJobs(req pb.ListJobsRequest, resp *pb.ListJobsResponse) error {
+	return r.s.ListJobs(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) CancelJob(req pb.CancelJobRequest, resp *pb.JobStatusResponse) error {
+	return r.s.CancelJob(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) SearchHistory(req pb.HistoryRequest, resp *pb.HistoryResponse) error {
+	return r.s.SearchHistory(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) WatchJobs(req pb.WatchRequest, resp *pb.WatchResponse) error {
+	return r.s.WatchJobs(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) ListWorkers(req pb.ListWorkersRequest, resp *pb.ListWorkersResponse) error {
+	return r.s.ListWorkers(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) GetWorker(req pb.GetWorkerRequest, resp *pb.WorkerStatusResponse) error {
+	return r.s.GetWorker(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) DrainWorker(req pb.DrainWorkerRequest, resp *pb.WorkerStatusResponse) error {
+	return r.s.DrainWorker(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) RevokeWorker(req pb.RevokeWorkerRequest, resp *pb.WorkerStatusResponse) error {
+	return r.s.RevokeWorker(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) SearchAudit(req pb.AuditRequest, resp *pb.AuditResponse) error {
+	return r.s.SearchAudit(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) PutSecret(req pb.PutSecretRequest, resp *pb.SecretInfo) error {
+	return r.s.PutSecret(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) ListSecrets(req pb.ListSecretsRequest, resp *pb.ListSecretsResponse) error {
+	return r.s.ListSecrets(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) DeleteSecret(req pb.DeleteSecretRequest, resp *pb.SecretInfo) error {
+	return r.s.DeleteSecret(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) PutTemplate(req pb.PutTemplateRequest, resp *pb.JobTemplate) error {
+	return r.s.PutTemplate(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) GetTemplate(req pb.GetTemplateRequest, resp *pb.JobTemplate) error {
+	return r.s.GetTemplate(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) ListTemplates(req pb.ListTemplatesRequest, resp *pb.ListTemplatesResponse) error {
+	return r.s.ListTemplates(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) DeleteTemplate(req pb.DeleteTemplateRequest, resp *pb.JobTemplate) error {
+	return r.s.DeleteTemplate(r.context(), req, resp)
+}
+
+func (r *rpcManagerService) SubmitFromTemplate(req pb.SubmitFromTemplateRequest, resp *pb.JobResponse) error {
+	return r.s.SubmitFromTemplate(r.context(), req, resp)
+}
+
+// rpcWorkerService adapts the WorkerService handlers to net/rpc
+type rpcWorkerService struct {
+	*rpcConn
+}
+
+func (r *rpcWorkerService) RegisterWorker(req pb.WorkerInfo, resp *pb.RegistrationResponse) error {
+	return r.s.RegisterWorker(r.context(), req, resp)
+}
+
+func (r *rpcWorkerService) DeregisterWorker(req pb.DeregisterRequest, resp *pb.Ack) error {
+	return r.s.DeregisterWorker(r.context(), req, resp)
+}
+
+func (r *rpcWorkerService) Heartbeat(req pb.HeartbeatRequest, resp *pb.HeartbeatResponse) error {
+	return r.s.Heartbeat(r.context(), req, resp)
+}
+
+func (r *rpcWorkerService) ReportTaskStatus(req pb.TaskStatusUpdate, resp *pb.Ack) error {
+	return r.s.ReportTaskStatus(r.context(), req, resp)
+}
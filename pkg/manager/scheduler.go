@@ -1,41 +1,89 @@
 package manager
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net/rpc"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"titan/pkg/logger"
 	"titan/pkg/models"
 	pb "titan/pkg/proto"
+	"titan/pkg/secrets"
 )
 
-// Scheduler is responsible for assigning jobs to workers
+// resyncInterval is how often the scheduler runs without being triggered.
+// It is only a safety net for events that were missed, e.g. retrying
+// dispatches that failed.
+const resyncInterval = 5 * time.Second
+
+// dispatchTimeout bounds how long a worker may take to accept a task. It is
+// a variable so tests can shorten it.
+var dispatchTimeout = 10 * time.Second
+
+// Scheduler is responsible for assigning jobs to workers. Placement runs
+// on a single goroutine; dispatching to workers runs concurrently so one
+// slow worker can't hold up the rest of the cluster.
 type Scheduler struct {
-	store        *Store
-	stopChan     chan struct{}
-	workerClients map[string]*rpc.Client
+	store      Store
+	pool       *workerPool
+	secrets    *secrets.Box // Decrypts job secrets at dispatch; nil if disabled
+	nextWorker int // Round-robin position, carried across passes
+	dispatches sync.WaitGroup
+	wakeChan   chan struct{}
+
+	// mu guards stopped, so calls to workers made outside a scheduling
+	// pass can't be added to dispatches once Stop waits for them
+	mu      sync.Mutex
+	stopped bool
+
+	stopChan   chan struct{}
+	doneChan   chan struct{}
 }
 
-// NewScheduler creates a new scheduler
-func NewScheduler(store *Store) *Scheduler {
+// NewScheduler creates a new scheduler. With a TLS configuration, workers
+// are dialed over TLS and must present the certificate of their worker ID.
+// box decrypts the secrets jobs reference; it may be nil if no job does.
+func NewScheduler(store Store, tlsConfig *tls.Config, box *secrets.Box) *Scheduler {
 	return &Scheduler{
-		store:        store,
-		stopChan:     make(chan struct{}),
-		workerClients: make(map[string]*rpc.Client),
+		store:    store,
+		pool:     newWorkerPool(tlsConfig),
+		secrets:  box,
+		wakeChan: make(chan struct{}, 1),
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// Trigger requests a scheduling pass, e.g. because a job was submitted or
+// capacity was freed. It never blocks: triggers that arrive while a pass
+// is already pending are coalesced into it.
+func (s *Scheduler) Trigger() {
+	select {
+	case s.wakeChan <- struct{}{}:
+	default:
 	}
 }
 
-// Run starts the scheduling loop
+// Run starts the scheduling loop. A pass runs whenever the scheduler is
+// triggered, and periodically as a fallback.
 func (s *Scheduler) Run() {
-	ticker := time.NewTicker(5 * time.Second)
+	defer close(s.doneChan)
+	
+	ticker := time.NewTicker(resyncInterval)
 	defer ticker.Stop()
 	
 	logger.Info("Scheduler started")
 	
 	for {
 		select {
+		case <-s.wakeChan:
+			s.schedule()
 		case <-ticker.C:
+			s.pool.prune(s.store)
 			s.schedule()
 		case <-s.stopChan:
 			logger.Info("Scheduler stopped")
@@ -44,71 +92,252 @@ func (s *Scheduler) Run() {
 	}
 }
 
-// Stop halts the scheduler
+// Stop halts the scheduler and waits for an in-progress scheduling pass
+// and any outstanding dispatches to finish
 func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
 	close(s.stopChan)
+	<-s.doneChan
+	s.dispatches.Wait()
+}
+
+// Close releases the connections to all workers. It must only be called
+// once the scheduler has stopped.
+func (s *Scheduler) Close() {
+	s.pool.closeAll()
 }
 
-// schedule attempts to assign pending jobs to available workers
+// schedule assigns pending jobs to available workers. Each placement is
+// committed to the store before its dispatch starts, so a job can never be
+// placed twice even while earlier dispatches are still in flight.
 func (s *Scheduler) schedule() {
+	// Only the leader of a replicated cluster places jobs
+	if r, ok := s.store.(replicatedStore); ok && r.CheckLeader() != nil {
+		return
+	}
+	
 	pendingJobs := s.store.GetPendingJobs()
 	if len(pendingJobs) == 0 {
 		return
 	}
 	
-	healthyWorkers := s.store.GetHealthyWorkers()
+	// Drained workers finish their tasks but get no new ones, and revoked
+	// workers get nothing at all
+	var healthyWorkers []*models.Worker
+	for _, worker := range s.store.GetHealthyWorkers() {
+		if !worker.Draining && !worker.Revoked {
+			healthyWorkers = append(healthyWorkers, worker)
+		}
+	}
 	if len(healthyWorkers) == 0 {
 		logger.Warn("No healthy workers available for scheduling")
 		return
 	}
+	sort.Slice(healthyWorkers, func(i, j int) bool {
+		return healthyWorkers[i].ID < healthyWorkers[j].ID
+	})
+	
+	// Higher priorities first, then in submission order
+	sort.SliceStable(pendingJobs, func(i, j int) bool {
+		if pendingJobs[i].Priority != pendingJobs[j].Priority {
+			return pendingJobs[i].Priority > pendingJobs[j].Priority
+		}
+		return pendingJobs[i].CreatedAt.Before(pendingJobs[j].CreatedAt)
+	})
 	
-	// Round-robin scheduling
-	workerIndex := 0
+	// Round-robin scheduling over the workers each job fits on. Free
+	// resources are read once and then kept up to date as this pass places
+	// jobs, so placing doesn't go back to the store for every pair.
+	free := s.freeResources(healthyWorkers)
 	for _, job := range pendingJobs {
-		worker := healthyWorkers[workerIndex%len(healthyWorkers)]
-		
-		// Assign job to worker
-		if err := s.assignJobToWorker(job, worker); err != nil {
-			logger.Error("Failed to assign job to worker", 
-				"job_id", job.ID, 
-				"worker_id", worker.ID, 
-				"error", err)
+		worker := s.pickWorker(job, healthyWorkers, free)
+		if worker == nil {
+			logger.Debug("No worker satisfies job constraints with resources free", "job_id", job.ID)
 			continue
 		}
 		
-		// Update job status
-		job.Status = models.JobStatusScheduled
-		job.WorkerID = worker.ID
-		s.store.UpdateJob(job)
+		// Record the attempt before dispatch so status reports from the
+		// worker can't race ahead of it
+		task := &models.Task{
+			ID:        uuid.New().String(),
+			JobID:     job.ID,
+			WorkerID:  worker.ID,
+			Command:   job.Command,
+			Argv:      job.Argv,
+			Env:       job.Env,
+			Timeout:   job.Timeout,
+			Status:    models.JobStatusScheduled,
+			CreatedAt: time.Now(),
+		}
+		if _, err := s.store.StartAttempt(job.ID, task, "assigned to worker "+worker.ID); err != nil {
+			logger.Warn("Skipping job that changed before scheduling", "error", err)
+			continue
+		}
 		
-		logger.Info("Job scheduled", 
-			"job_id", job.ID, 
-			"worker_id", worker.ID)
+		left := free[worker.ID]
+		left.cpu -= job.CPU
+		left.memory -= job.Memory
 		
-		workerIndex++
+		s.dispatches.Add(1)
+		go s.dispatch(job, task, worker)
+	}
+}
+
+// resources are the CPU millicores and memory MB a worker has left
+type resources struct {
+	cpu    int32
+	memory int64
+}
+
+// freeResources returns what each worker has free: its capacity less what
+// the jobs of its active tasks asked for
+func (s *Scheduler) freeResources(workers []*models.Worker) map[string]*resources {
+	free := make(map[string]*resources, len(workers))
+	for _, worker := range workers {
+		left := &resources{cpu: worker.TotalCPU, memory: worker.TotalMemory}
+		for _, task := range s.store.GetActiveTasksForWorker(worker.ID) {
+			if placed, ok := s.store.GetJob(task.JobID); ok {
+				left.cpu -= placed.CPU
+				left.memory -= placed.Memory
+			}
+		}
+		free[worker.ID] = left
+	}
+	return free
+}
+
+// pickWorker returns the next worker in round-robin order that the job fits
+// on, or nil if there is none, and advances the round-robin position past it
+func (s *Scheduler) pickWorker(job *models.Job, workers []*models.Worker, free map[string]*resources) *models.Worker {
+	for i := 0; i < len(workers); i++ {
+		worker := workers[(s.nextWorker+i)%len(workers)]
+		if fits(job, worker, free[worker.ID]) {
+			s.nextWorker += i + 1
+			return worker
+		}
+	}
+	return nil
+}
+
+// fits reports whether a worker has every label the job is constrained to
+// and at least the resources the job asks for free
+func fits(job *models.Job, worker *models.Worker, free *resources) bool {
+	for k, v := range job.Constraints {
+		if label, ok := worker.Labels[k]; !ok || label != v {
+			return false
+		}
 	}
+	return job.CPU <= free.cpu && job.Memory <= free.memory
 }
 
-// assignJobToWorker sends a StartTask RPC to the worker
-func (s *Scheduler) assignJobToWorker(job *models.Job, worker *models.Worker) error {
-	client, err := s.getWorkerClient(worker)
+// dispatch starts a placed task on its worker, requeueing the job if the
+// worker can't be reached or refuses the task
+func (s *Scheduler) dispatch(job *models.Job, task *models.Task, worker *models.Worker) {
+	defer s.dispatches.Done()
+	
+	taskSecrets, err := s.resolveSecrets(job)
 	if err != nil {
-		return fmt.Errorf("failed to get worker client: %w", err)
+		// Retrying can't help until someone fixes the secret
+		logger.Error("Failed to resolve job secrets", "job_id", job.ID, "task_id", task.ID, "error", err)
+		s.fail(task.ID, "secrets unavailable: "+err.Error())
+		return
+	}
+	
+	if err := s.assignTaskToWorker(task, taskSecrets, worker); err != nil {
+		logger.Error("Failed to assign job to worker", 
+			"job_id", job.ID, 
+			"task_id", task.ID, 
+			"worker_id", worker.ID, 
+			"error", err)
+		s.requeue(task.ID, "dispatch failed: "+err.Error())
+		return
 	}
 	
-	taskID := job.ID // Use job ID as task ID for simplicity
+	logger.Info("Job scheduled", 
+		"job_id", job.ID, 
+		"task_id", task.ID, 
+		"attempt", task.Attempt, 
+		"worker_id", worker.ID, 
+		"queued_for", time.Since(job.UpdatedAt).String())
+}
+
+// requeue fails a task that could not be dispatched and returns its job to
+// the pending queue, unless the worker already picked the task up
+func (s *Scheduler) requeue(taskID, reason string) {
+	_, _, err := s.store.ApplyTaskUpdate(taskID, models.JobStatusPending, reason, func(j *models.Job, t *models.Task) error {
+		if t.Status != models.JobStatusScheduled {
+			return fmt.Errorf("task %s is already %s", t.ID, t.Status)
+		}
+		t.Status = models.JobStatusFailed
+		t.Output = reason
+		t.FinishedAt = time.Now()
+		j.WorkerID = ""
+		return nil
+	})
+	if err != nil {
+		logger.Warn("Failed to requeue job", "task_id", taskID, "error", err)
+	}
+}
+
+// fail fails a task that can never be dispatched, and its job with it,
+// unless the task has moved on in the meantime
+func (s *Scheduler) fail(taskID, reason string) {
+	_, _, err := s.store.ApplyTaskUpdate(taskID, models.JobStatusFailed, reason, func(j *models.Job, t *models.Task) error {
+		if t.Status != models.JobStatusScheduled {
+			return fmt.Errorf("task %s is already %s", t.ID, t.Status)
+		}
+		t.Status = models.JobStatusFailed
+		t.Output = reason
+		t.FinishedAt = time.Now()
+		j.Output = reason
+		return nil
+	})
+	if err != nil {
+		logger.Warn("Failed to fail job", "task_id", taskID, "error", err)
+	}
+}
+
+// resolveSecrets decrypts the secrets a job references for its task. The
+// values live only in the StartTask request; they are never stored.
+func (s *Scheduler) resolveSecrets(job *models.Job) ([]pb.TaskSecret, error) {
+	if len(job.Secrets) == 0 {
+		return nil, nil
+	}
+	if s.secrets == nil {
+		return nil, ErrSecretsDisabled
+	}
+	out := make([]pb.TaskSecret, len(job.Secrets))
+	for i, ref := range job.Secrets {
+		secret, ok := s.store.GetSecret(ref.Name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, ref.Name)
+		}
+		value, err := s.secrets.Open(secret.Name, secret.Sealed)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s: %w", ref.Name, err)
+		}
+		out[i] = pb.TaskSecret{Name: ref.Name, Env: ref.Env, File: ref.File, Value: value}
+	}
+	return out, nil
+}
+
+// assignTaskToWorker sends a StartTask RPC to the worker, giving up after
+// dispatchTimeout
+func (s *Scheduler) assignTaskToWorker(task *models.Task, taskSecrets []pb.TaskSecret, worker *models.Worker) error {
 	req := pb.TaskRequest{
-		TaskId:  taskID,
-		JobId:   job.ID,
-		Command: job.Command,
-		Env:     job.Env,
+		TaskId:         task.ID,
+		JobId:          task.JobID,
+		Command:        task.Command,
+		Argv:           task.Argv,
+		Env:            task.Env,
+		Secrets:        taskSecrets,
+		TimeoutSeconds: int64(task.Timeout / time.Second),
 	}
 	
 	var resp pb.TaskResponse
-	err = client.Call("WorkerService.StartTask", req, &resp)
-	if err != nil {
-		// If call fails, remove client to force reconnect next time
-		delete(s.workerClients, worker.ID)
+	if err := s.callWorker(worker, "WorkerService.StartTask", req, &resp); err != nil {
 		return fmt.Errorf("failed to start task on worker: %w", err)
 	}
 	
@@ -119,18 +348,60 @@ func (s *Scheduler) assignJobToWorker(job *models.Job, worker *models.Worker) er
 	return nil
 }
 
-// getWorkerClient returns or creates a net/rpc client for the worker
-func (s *Scheduler) getWorkerClient(worker *models.Worker) (*rpc.Client, error) {
-	if client, ok := s.workerClients[worker.ID]; ok {
-		return client, nil
+// StopTask asks the worker running task to kill it, without waiting for
+// the outcome. Failures are only logged: the task is already recorded as
+// finished, so whatever the worker reports for it is rejected anyway.
+func (s *Scheduler) StopTask(task *models.Task) {
+	worker, ok := s.store.GetWorker(task.WorkerID)
+	if !ok || !s.track() {
+		return
 	}
 	
-	client, err := rpc.Dial("tcp", worker.Address)
+	go func() {
+		defer s.dispatches.Done()
+		var resp pb.StopTaskResponse
+		err := s.callWorker(worker, "WorkerService.StopTask", pb.StopTaskRequest{TaskId: task.ID}, &resp)
+		if err == nil && !resp.Stopped {
+			err = fmt.Errorf("worker is not running it")
+		}
+		if err != nil {
+			logger.Warn("Failed to stop task", "task_id", task.ID, "worker_id", worker.ID, "error", err)
+			return
+		}
+		logger.Info("Task stopped", "task_id", task.ID, "worker_id", worker.ID)
+	}()
+}
+
+// track counts a call to a worker made outside a scheduling pass among the
+// dispatches Stop waits for. It reports false once Stop has begun, and the
+// call must then not be made, as the worker connections may be closed.
+func (s *Scheduler) track() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return false
+	}
+	s.dispatches.Add(1)
+	return true
+}
+
+// callWorker makes an RPC to a worker, giving up after dispatchTimeout
+func (s *Scheduler) callWorker(worker *models.Worker, method string, req any, resp any) error {
+	client, err := s.pool.get(worker)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to get worker client: %w", err)
 	}
 	
-	s.workerClients[worker.ID] = client
-	
-	return client, nil
+	call := client.Go(method, req, resp, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		err = call.Error
+	case <-time.After(dispatchTimeout):
+		err = fmt.Errorf("timed out after %s", dispatchTimeout)
+	}
+	if err != nil {
+		// Drop the connection to force a reconnect next time
+		s.pool.remove(worker.ID, client)
+	}
+	return err
 }
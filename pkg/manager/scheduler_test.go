@@ -0,0 +1,293 @@
+package manager
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/rpc"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"titan/pkg/logger"
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+)
+
+// fakeWorker is an in-process worker that accepts every task and reports
+// it running, then completed, straight to the manager
+type fakeWorker struct {
+	id      string
+	manager *Server
+
+	stopped chan string // IDs of the tasks it was asked to stop
+
+	mu         sync.Mutex
+	running    map[string]chan struct{} // Closed once the job's task is RUNNING
+	slowStarts int                      // Number of StartTask calls to answer late
+	slowDelay  time.Duration
+}
+
+// startFakeWorker serves a fake worker on a loopback port and registers it
+// with the manager
+func startFakeWorker(tb testing.TB, s *Server, id string) *fakeWorker {
+	tb.Helper()
+	w := &fakeWorker{id: id, manager: s, stopped: make(chan string, 16), running: make(map[string]chan struct{})}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("WorkerService", w); err != nil {
+		tb.Fatalf("RegisterName: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatalf("Listen: %v", err)
+	}
+	tb.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn)
+		}
+	}()
+
+	var resp pb.RegistrationResponse
+	info := pb.WorkerInfo{
+		WorkerId: id,
+		Address:  ln.Addr().String(),
+		Capacity: pb.ResourceCapacity{TotalCpuMillicores: 4000, TotalMemoryMb: 8192},
+	}
+	if err := s.RegisterWorker(context.Background(), info, &resp); err != nil {
+		tb.Fatalf("RegisterWorker: %v", err)
+	}
+	return w
+}
+
+// watch returns a channel closed once the job's task reports RUNNING,
+// which may already have happened
+func (w *fakeWorker) watch(jobID string) chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	ch, ok := w.running[jobID]
+	if !ok {
+		ch = make(chan struct{})
+		w.running[jobID] = ch
+	}
+	return ch
+}
+
+// answerLate makes the worker start the next n tasks, but only reply to
+// StartTask after delay
+func (w *fakeWorker) answerLate(n int, delay time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.slowStarts, w.slowDelay = n, delay
+}
+
+// StartTask implements WorkerService.StartTask
+func (w *fakeWorker) StartTask(req pb.TaskRequest, resp *pb.TaskResponse) error {
+	w.mu.Lock()
+	var delay time.Duration
+	if w.slowStarts > 0 {
+		w.slowStarts--
+		delay = w.slowDelay
+	}
+	w.mu.Unlock()
+	time.Sleep(delay)
+
+	*resp = pb.TaskResponse{Accepted: true}
+	go func() {
+		for i, status := range []models.JobStatus{models.JobStatusRunning, models.JobStatusCompleted} {
+			update := pb.TaskStatusUpdate{TaskId: req.TaskId, JobId: req.JobId, Status: string(status), Seq: uint64(i + 1), WorkerId: w.id}
+			var ack pb.Ack
+			if err := w.manager.ReportTaskStatus(context.Background(), update, &ack); err != nil || !ack.Ok {
+				return
+			}
+			if status == models.JobStatusRunning {
+				close(w.watch(req.JobId))
+			}
+		}
+	}()
+	return nil
+}
+
+// StopTask implements WorkerService.StopTask
+func (w *fakeWorker) StopTask(req pb.StopTaskRequest, resp *pb.StopTaskResponse) error {
+	select {
+	case w.stopped <- req.TaskId:
+	default: // Nobody is counting
+	}
+	*resp = pb.StopTaskResponse{Stopped: true}
+	return nil
+}
+
+// TestStopTaskDuringStop races StopTask calls, as RPC handlers make them,
+// with stopping the scheduler. Run it with -race. Calls that lose the race
+// must not reach the worker once Stop has returned.
+func TestStopTaskDuringStop(t *testing.T) {
+	s := newTestServer(t, Config{})
+	s.Start()
+	worker := startFakeWorker(t, s, "w1")
+	task := &models.Task{ID: "t1", JobID: "j1", WorkerID: "w1"}
+
+	stopping := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < stressGoroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-stopping
+			for i := 0; i < 20; i++ {
+				s.scheduler.StopTask(task)
+				runtime.Gosched()
+			}
+		}()
+	}
+	close(stopping)
+	s.Stop()
+	for len(worker.stopped) > 0 {
+		<-worker.stopped
+	}
+	wg.Wait()
+
+	s.scheduler.StopTask(task)
+	select {
+	case <-worker.stopped:
+		t.Error("StopTask reached the worker after Stop")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestDispatchTimeoutStopsLateTask has a worker start a task but answer
+// StartTask after the dispatch timed out. The job must run again, and the
+// late task be stopped when the worker reports it running.
+func TestDispatchTimeoutStopsLateTask(t *testing.T) {
+	defer func(d time.Duration) { dispatchTimeout = d }(dispatchTimeout)
+	dispatchTimeout = 50 * time.Millisecond
+
+	s := newTestServer(t, Config{})
+	s.Start()
+	defer s.Stop()
+	worker := startFakeWorker(t, s, "w1")
+	worker.answerLate(1, 4*dispatchTimeout)
+
+	var resp pb.JobResponse
+	if err := s.SubmitJob(context.Background(), pb.JobRequest{Command: "true"}, &resp); err != nil {
+		t.Fatalf("SubmitJob: %v", err)
+	}
+
+	var stopped string
+	select {
+	case stopped = <-worker.stopped:
+	case <-time.After(resyncInterval):
+		t.Fatal("late task was not stopped")
+	}
+	job, _ := s.store.GetJob(resp.JobId)
+	if stopped != job.TaskIDs[0] {
+		t.Fatalf("stopped %s; job attempts are %v", stopped, job.TaskIDs)
+	}
+	if task, _ := s.store.GetTask(stopped); task.Status != models.JobStatusFailed {
+		t.Errorf("late task is %s, want %s", task.Status, models.JobStatusFailed)
+	}
+
+	// Failed dispatches are retried by the next pass; don't wait for resync
+	s.scheduler.Trigger()
+	select {
+	case <-worker.watch(resp.JobId):
+	case <-time.After(resyncInterval):
+		t.Fatal("job was not run again")
+	}
+	if job, _ := s.store.GetJob(resp.JobId); len(job.TaskIDs) != 2 {
+		t.Errorf("job ran with attempts %v, want 2", job.TaskIDs)
+	}
+}
+
+// TestSchedulePlacesWhatFits runs one scheduling pass over jobs that ask
+// for more than a worker has free. Resources held by an earlier attempt and
+// by jobs placed earlier in the same pass both count; jobs that don't fit
+// stay PENDING.
+func TestSchedulePlacesWhatFits(t *testing.T) {
+	s := newTestServer(t, Config{})
+	startFakeWorker(t, s, "w1") // 4000 millicores, 8192 MB
+	submitted := time.Now()
+	addJob := func(id string, cpu int32, memory int64) {
+		t.Helper()
+		job := models.NewJob(id, models.JobStatusPending, "submitted", submitted)
+		job.Command = "true"
+		job.CPU, job.Memory = cpu, memory
+		if err := s.store.AddJob(job); err != nil {
+			t.Fatalf("AddJob(%s): %v", id, err)
+		}
+		submitted = submitted.Add(time.Millisecond)
+	}
+	addJob("running", 1000, 1024)
+	startAttempt(t, s.store, "running", "t0", "w1")
+	addJob("first", 1500, 1024)
+	addJob("second", 1500, 1024)
+	addJob("no-cpu-left", 1500, 1024)
+	addJob("too-much-memory", 0, 8192)
+
+	s.scheduler.schedule()
+	s.scheduler.dispatches.Wait()
+
+	for id, want := range map[string]bool{"first": true, "second": true, "no-cpu-left": false, "too-much-memory": false} {
+		job, _ := s.store.GetJob(id)
+		if placed := job.Status != models.JobStatusPending; placed != want {
+			t.Errorf("job %s is %s on %q, placed = %v, want %v", id, job.Status, job.WorkerID, placed, want)
+		}
+	}
+}
+
+// BenchmarkSubmitToRunning measures how long a job submitted through
+// SubmitJob takes to be placed and started on an idle worker. Besides the
+// round trip, it reports the time from submission to SCHEDULED and to
+// RUNNING as recorded in each job's history.
+func BenchmarkSubmitToRunning(b *testing.B) {
+	defer func(l *slog.Logger) { logger.Logger = l }(logger.Logger)
+	logger.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	s, err := NewServer(NewMemoryStore(), Config{})
+	if err != nil {
+		b.Fatalf("NewServer: %v", err)
+	}
+	s.Start()
+	defer func() {
+		s.Stop()
+		s.Close()
+	}()
+	worker := startFakeWorker(b, s, "bench")
+
+	var toScheduled, toRunning time.Duration
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var resp pb.JobResponse
+		if err := s.SubmitJob(context.Background(), pb.JobRequest{Command: "true"}, &resp); err != nil {
+			b.Fatalf("SubmitJob: %v", err)
+		}
+		select {
+		case <-worker.watch(resp.JobId):
+		case <-time.After(resyncInterval / 2):
+			// Only the resync ticker would have picked it up
+			b.Fatalf("job %s not running within %s", resp.JobId, resyncInterval/2)
+		}
+
+		job, _ := s.store.GetJob(resp.JobId)
+		submitted := job.History[0].At
+		for _, h := range job.History {
+			switch h.To {
+			case models.JobStatusScheduled:
+				toScheduled += h.At.Sub(submitted)
+			case models.JobStatusRunning:
+				toRunning += h.At.Sub(submitted)
+			}
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(toScheduled.Nanoseconds())/float64(b.N), "ns-to-scheduled/op")
+	b.ReportMetric(float64(toRunning.Nanoseconds())/float64(b.N), "ns-to-running/op")
+}
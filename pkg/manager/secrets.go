@@ -0,0 +1,177 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"titan/pkg/logger"
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+	"titan/pkg/secrets"
+)
+
+// ErrSecretsDisabled is returned by secret calls, and for jobs referencing
+// secrets, on a manager that has no secrets key configured
+var ErrSecretsDisabled = errors.New("secrets are not enabled on this manager")
+
+// PutSecret creates or replaces a secret. The value is encrypted before it
+// reaches the store and is never returned by any call.
+func (s *Server) PutSecret(ctx context.Context, req pb.PutSecretRequest, resp *pb.SecretInfo) (err error) {
+	// Audit the name only; the value must not reach the audit log
+	defer func() { s.audit(ctx, "PutSecret", req.Name, pb.PutSecretRequest{Name: req.Name}, err) }()
+	caller, _, err := s.authorizeClient(ctx, "PutSecret")
+	if err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	if s.secrets == nil {
+		return ErrSecretsDisabled
+	}
+	if err := secrets.ValidateName(req.Name); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	if len(req.Value) > secrets.MaxValueSize {
+		return fmt.Errorf("%w: secret value is larger than %d bytes", ErrInvalidArgument, secrets.MaxValueSize)
+	}
+
+	sealed, err := s.secrets.Seal(req.Name, req.Value)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	now := time.Now()
+	secret := &models.Secret{Name: req.Name, Sealed: sealed, CreatedAt: now, UpdatedAt: now}
+	if prev, ok := s.store.GetSecret(req.Name); ok {
+		secret.CreatedAt = prev.CreatedAt
+	}
+	if caller != nil {
+		secret.UpdatedBy = caller.Name
+	}
+	if err := s.store.PutSecret(secret); err != nil {
+		return err
+	}
+
+	logger.Info("Secret stored", "name", req.Name, "by", secret.UpdatedBy)
+
+	*resp = secretInfo(secret)
+	return nil
+}
+
+// ListSecrets returns every secret without its value
+func (s *Server) ListSecrets(ctx context.Context, req pb.ListSecretsRequest, resp *pb.ListSecretsResponse) error {
+	if _, _, err := s.authorizeClient(ctx, "ListSecrets"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+
+	list := s.store.GetAllSecrets()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	resp.Secrets = make([]pb.SecretInfo, len(list))
+	for i, secret := range list {
+		resp.Secrets[i] = secretInfo(secret)
+	}
+	return nil
+}
+
+// DeleteSecret deletes a secret. Jobs that still reference it fail when
+// they are next dispatched.
+func (s *Server) DeleteSecret(ctx context.Context, req pb.DeleteSecretRequest, resp *pb.SecretInfo) (err error) {
+	defer func() { s.audit(ctx, "DeleteSecret", req.Name, req, err) }()
+	if _, _, err := s.authorizeClient(ctx, "DeleteSecret"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+
+	secret, ok := s.store.GetSecret(req.Name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSecretNotFound, req.Name)
+	}
+	if _, err := s.store.RemoveSecret(req.Name); err != nil {
+		return err
+	}
+
+	logger.Info("Secret deleted", "name", req.Name)
+
+	*resp = secretInfo(secret)
+	return nil
+}
+
+// secretInfo builds the RPC view of a secret, which never includes its value
+func secretInfo(secret *models.Secret) pb.SecretInfo {
+	return pb.SecretInfo{
+		Name:      secret.Name,
+		UpdatedBy: secret.UpdatedBy,
+		CreatedAt: secret.CreatedAt.Unix(),
+		UpdatedAt: secret.UpdatedAt.Unix(),
+	}
+}
+
+// secretRefs validates the secrets a submitted job references and converts
+// them for the store. Every secret must exist, and no two references may
+// deliver to the same variable or file.
+func (s *Server) secretRefs(refs []pb.SecretRef, env map[string]string) ([]models.SecretRef, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	if s.secrets == nil {
+		return nil, ErrSecretsDisabled
+	}
+
+	out := make([]models.SecretRef, len(refs))
+	targets := make(map[string]bool)
+	for i, ref := range refs {
+		if err := secrets.ValidateName(ref.Name); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+		}
+		var target string
+		switch {
+		case (ref.Env == "") == (ref.File == ""):
+			return nil, fmt.Errorf("%w: secret %s needs exactly one of env and file", ErrInvalidArgument, ref.Name)
+		case ref.Env != "":
+			if err := secrets.ValidateEnvName(ref.Env); err != nil {
+				return nil, fmt.Errorf("%w: secret %s: %v", ErrInvalidArgument, ref.Name, err)
+			}
+			if _, ok := env[ref.Env]; ok {
+				return nil, fmt.Errorf("%w: secret %s: environment variable %s is also set in env", ErrInvalidArgument, ref.Name, ref.Env)
+			}
+			target = "env " + ref.Env
+		default:
+			if err := secrets.ValidateFilePath(ref.File); err != nil {
+				return nil, fmt.Errorf("%w: secret %s: %v", ErrInvalidArgument, ref.Name, err)
+			}
+			target = "file " + ref.File
+		}
+		if targets[target] {
+			return nil, fmt.Errorf("%w: more than one secret delivered to %s", ErrInvalidArgument, target)
+		}
+		targets[target] = true
+		if _, ok := s.store.GetSecret(ref.Name); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, ref.Name)
+		}
+		out[i] = models.SecretRef{Name: ref.Name, Env: ref.Env, File: ref.File}
+	}
+	return out, nil
+}
+
+// secretRefsResponse converts a job's secret references for the RPC view
+func secretRefsResponse(refs []models.SecretRef) []pb.SecretRef {
+	if len(refs) == 0 {
+		return nil
+	}
+	out := make([]pb.SecretRef, len(refs))
+	for i, ref := range refs {
+		out[i] = pb.SecretRef{Name: ref.Name, Env: ref.Env, File: ref.File}
+	}
+	return out
+}
@@ -1,119 +1,956 @@
 package manager
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"net/rpc"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"titan/pkg/auth"
 	"titan/pkg/logger"
 	"titan/pkg/models"
 	pb "titan/pkg/proto"
+	"titan/pkg/secrets"
 )
 
+const (
+	// defaultHistoryLimit caps history searches that don't set a limit
+	defaultHistoryLimit = 100
+	
+	// defaultAuditLimit caps audit searches that don't set a limit
+	defaultAuditLimit = 100
+
+	// defaultWatchTimeout and maxWatchTimeout bound how long a WatchJobs
+	// call waits for a change
+	defaultWatchTimeout = 30 * time.Second
+	maxWatchTimeout     = 60 * time.Second
+
+	// cancelRetries bounds how often CancelJob retries a job that changed
+	// under it, e.g. because its task finished at the same moment
+	cancelRetries = 3
+)
+
+var (
+	// ErrInvalidArgument is returned for requests that are malformed or
+	// miss a required field
+	ErrInvalidArgument = errors.New("invalid argument")
+
+	// ErrWorkerExists is returned when a worker registers with the ID of
+	// a live worker at another address
+	ErrWorkerExists = errors.New("worker already registered")
+)
+
+// Config holds the manager's optional behaviour
+type Config struct {
+	Retention RetentionPolicy
+	Audit     AuditConfig
+
+	// TLS, if set, is used to dial workers, and every call must then come
+	// from a caller authenticated by its certificate
+	TLS *tls.Config
+
+	// Tokens, if set, checks API tokens. ManagerService calls must then
+	// carry a valid token, or come from a client certificate.
+	Tokens auth.Authenticator
+
+	// Roles, if set, decides what authenticated clients may do. Without
+	// it every client may call every ManagerService method.
+	Roles auth.Roles
+
+	// JoinTokens, if set, admits only workers that present one of its
+	// tokens or a worker certificate when they register
+	JoinTokens *auth.JoinTokens
+
+	// Secrets, if set, encrypts the secrets jobs can reference. Every
+	// replica must use the same key.
+	Secrets *secrets.Box
+}
+
 // Server implements the Manager RPC service
 type Server struct {
-	store     *Store
+	store     Store
 	scheduler *Scheduler
+	gc        *garbageCollector // nil unless retention is enabled
+	reaper    *reaper           // Loses the tasks of workers that stop heartbeating
+	archive   *Archive          // nil unless archival is configured
+	auditLog  *AuditLog         // nil unless auditing is configured
+	stopChan  chan struct{}     // Closed by Drain to release watchers
+	drainOnce sync.Once
+
+	requireCert bool               // Reject calls without a verified certificate
+	tokens      auth.Authenticator // nil unless API tokens are enabled
+	roles       auth.Roles         // nil unless a role policy is in force
+	joinTokens  *auth.JoinTokens   // nil unless workers need join tokens
+	secrets     *secrets.Box       // nil unless secrets are enabled
 }
 
-// NewServer creates a new Manager server
-func NewServer() *Server {
-	store := NewStore()
-	return &Server{
+// NewServer creates a new Manager server on top of store. The server takes
+// ownership of the store and closes it in Close.
+func NewServer(store Store, cfg Config) (*Server, error) {
+	s := &Server{
 		store:     store,
-		scheduler: NewScheduler(store),
+		scheduler: NewScheduler(store, cfg.TLS, cfg.Secrets),
+		stopChan:  make(chan struct{}),
+
+		requireCert: cfg.TLS != nil,
+		tokens:      cfg.Tokens,
+		roles:       cfg.Roles,
+		joinTokens:  cfg.JoinTokens,
+		secrets:     cfg.Secrets,
+	}
+	if cfg.Retention.ArchiveDir != "" {
+		archive, err := OpenArchive(cfg.Retention.ArchiveDir)
+		if err != nil {
+			return nil, err
+		}
+		s.archive = archive
+	}
+	if cfg.Audit.Dir != "" {
+		auditLog, err := OpenAuditLog(cfg.Audit)
+		if err != nil {
+			return nil, err
+		}
+		s.auditLog = auditLog
+	}
+	if cfg.Retention.Enabled() {
+		s.gc = newGarbageCollector(store, cfg.Retention, s.archive)
+	}
+	s.reaper = newReaper(store, s.loseWorkerTasks)
+	if r, ok := store.(replicatedStore); ok {
+		// A new leader picks up whatever the old one left pending
+		r.OnLeader(s.scheduler.Trigger)
+	}
+	return s, nil
+}
+
+// checkLeader rejects calls on a replica that is not the cluster leader, so
+// the caller retries against the leader. A single manager always passes.
+func (s *Server) checkLeader() error {
+	if r, ok := s.store.(replicatedStore); ok {
+		return r.CheckLeader()
 	}
+	return nil
 }
 
 // Start begins the manager's background tasks
 func (s *Server) Start() {
 	go s.scheduler.Run()
+	go s.reaper.Run()
+	if s.gc != nil {
+		go s.gc.Run()
+	}
 	logger.Info("Manager server started")
 }
 
-// RegisterRPC registers the server with the net/rpc handler
-func (s *Server) RegisterRPC(server *rpc.Server) {
-	server.RegisterName("ManagerService", s)
-	server.RegisterName("WorkerService", s)
+// Drain releases the calls that wait for changes, such as watches, so
+// in-flight calls finish promptly. Call it before shutting down the
+// listeners, and Stop once they are down.
+func (s *Server) Drain() {
+	s.drainOnce.Do(func() { close(s.stopChan) })
+}
+
+// Stop halts the manager's background tasks so no new work is dispatched.
+// It drains the server first if that hasn't been done.
+func (s *Server) Stop() {
+	s.Drain()
+	s.reaper.Stop()
+	s.scheduler.Stop()
+	if s.gc != nil {
+		s.gc.Stop()
+	}
+	logger.Info("Manager server stopped")
+}
+
+// Close releases the manager's resources. It must be called after Stop
+// and once in-flight RPCs have drained.
+func (s *Server) Close() error {
+	s.scheduler.Close()
+	if s.auditLog != nil {
+		if err := s.auditLog.Close(); err != nil {
+			logger.Error("Failed to close audit log", "error", err)
+		}
+	}
+	if err := s.store.Close(); err != nil {
+		return fmt.Errorf("failed to close store: %w", err)
+	}
+	return nil
 }
 
 // SubmitJob handles job submission from clients
-// Signature must be: func (t *T) MethodName(argType T1, replyType *T2) error
-func (s *Server) SubmitJob(req pb.JobRequest, resp *pb.JobResponse) error {
-	jobID := uuid.New().String()
-	
-	job := &models.Job{
-		ID:        jobID,
-		Command:   req.Command,
-		Env:       req.Env,
-		Status:    models.JobStatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+func (s *Server) SubmitJob(ctx context.Context, req pb.JobRequest, resp *pb.JobResponse) (err error) {
+	defer func() { s.audit(ctx, "SubmitJob", resp.JobId, req, err) }()
+	caller, _, err := s.authorizeClient(ctx, "SubmitJob")
+	if err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
 	}
 	
-	s.store.AddJob(job)
-	
-	logger.Info("Job submitted", "job_id", jobID, "command", req.Command)
+	job, err := s.submitJob(req, caller, "submitted")
+	if err != nil {
+		return err
+	}
 	
 	*resp = pb.JobResponse{
-		JobId:  jobID,
+		JobId:  job.ID,
 		Status: string(job.Status),
 	}
 	return nil
 }
 
+// submitJob validates a job request and queues the job on behalf of
+// caller, with reason as its first history entry
+func (s *Server) submitJob(req pb.JobRequest, caller *auth.Caller, reason string) (*models.Job, error) {
+	if err := validateJobRequest(req); err != nil {
+		return nil, err
+	}
+	secretRefs, err := s.secretRefs(req.Secrets, req.Env)
+	if err != nil {
+		return nil, err
+	}
+	
+	job := models.NewJob(uuid.New().String(), models.JobStatusPending, reason, time.Now())
+	job.Command = req.Command
+	job.Argv = req.Argv
+	job.Env = req.Env
+	job.Secrets = secretRefs
+	job.Labels = req.Labels
+	job.CPU = req.Resources.CpuMillicores
+	job.Memory = req.Resources.MemoryMb
+	job.Priority = req.Priority
+	job.MaxRetries = req.MaxRetries
+	job.Timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	job.Constraints = req.Constraints
+	if caller != nil {
+		job.Owner = caller.Name
+	}
+	
+	if err := s.store.AddJob(job); err != nil {
+		return nil, err
+	}
+	s.scheduler.Trigger()
+	
+	logger.Info("Job submitted", "job_id", job.ID, "command", job.CommandLine(), "owner", job.Owner)
+	return job, nil
+}
+
+// validateJobRequest checks the parts of a job submission that don't
+// depend on cluster state
+func validateJobRequest(req pb.JobRequest) error {
+	switch {
+	case len(req.Argv) > 0 && req.Command != "":
+		return fmt.Errorf("%w: set either command or argv, not both", ErrInvalidArgument)
+	case len(req.Argv) > 0 && req.Argv[0] == "":
+		return fmt.Errorf("%w: argv[0] must name the program to run", ErrInvalidArgument)
+	case len(req.Argv) == 0 && strings.TrimSpace(req.Command) == "":
+		return fmt.Errorf("%w: command is required", ErrInvalidArgument)
+	case req.Resources.CpuMillicores < 0 || req.Resources.MemoryMb < 0:
+		return fmt.Errorf("%w: resources can't be negative", ErrInvalidArgument)
+	case req.MaxRetries < 0 || req.MaxRetries > models.MaxJobRetries:
+		return fmt.Errorf("%w: max_retries must be between 0 and %d", ErrInvalidArgument, models.MaxJobRetries)
+	case req.TimeoutSeconds < 0:
+		return fmt.Errorf("%w: timeout can't be negative", ErrInvalidArgument)
+	}
+	for key := range req.Constraints {
+		if key == "" {
+			return fmt.Errorf("%w: constraint with an empty label name", ErrInvalidArgument)
+		}
+	}
+	return nil
+}
+
 // GetJobStatus returns the current status of a job
-func (s *Server) GetJobStatus(req pb.JobStatusRequest, resp *pb.JobStatusResponse) error {
+func (s *Server) GetJobStatus(ctx context.Context, req pb.JobStatusRequest, resp *pb.JobStatusResponse) error {
+	if _, _, err := s.authorizeClient(ctx, "GetJobStatus"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	
+	// Read the version first so watching from it can't miss a change
+	version := s.store.Version()
 	job, ok := s.store.GetJob(req.JobId)
 	if !ok {
-		return fmt.Errorf("job not found: %s", req.JobId)
+		return fmt.Errorf("%w: %s", ErrJobNotFound, req.JobId)
+	}
+	
+	*resp = jobStatusResponse(job, s.store.GetTasksForJob(job.ID))
+	resp.ResourceVersion = version
+	return nil
+}
+
+// ListJobs returns one page of the jobs matching the request's filters
+func (s *Server) ListJobs(ctx context.Context, req pb.ListJobsRequest, resp *pb.ListJobsResponse) error {
+	if _, _, err := s.authorizeClient(ctx, "ListJobs"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	
+	filter := JobFilter{
+		WorkerID:        req.WorkerId,
+		Labels:          req.Labels,
+		CommandContains: req.Command,
+		SubmittedAfter:  unixToTime(req.SubmittedAfter),
+		SubmittedBefore: unixToTime(req.SubmittedBefore),
+	}
+	for _, name := range req.Statuses {
+		status, err := models.ParseJobStatus(name)
+		if err != nil {
+			return err
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+	
+	version := s.store.Version()
+	var matched []*models.Job
+	for _, job := range s.store.GetAllJobs() {
+		if filter.Matches(job) {
+			matched = append(matched, job)
+		}
 	}
 	
-	*resp = pb.JobStatusResponse{
-		JobId:    job.ID,
-		Status:   string(job.Status),
-		WorkerId: job.WorkerID,
-		Output:   job.Output,
-		ExitCode: job.ExitCode,
+	page, next, err := paginateJobs(matched, req.OldestFirst, req.PageToken, int(req.PageSize))
+	if err != nil {
+		return err
+	}
+	
+	resp.Jobs = make([]pb.JobStatusResponse, len(page))
+	for i, job := range page {
+		if req.Summary {
+			resp.Jobs[i] = jobSummaryResponse(job)
+		} else {
+			resp.Jobs[i] = jobStatusResponse(job, s.store.GetTasksForJob(job.ID))
+		}
 	}
+	resp.NextPageToken = next
+	resp.ResourceVersion = version
 	return nil
 }
 
-// ListJobs returns all jobs in the cluster
-func (s *Server) ListJobs(req pb.ListJobsRequest, resp *pb.ListJobsResponse) error {
-	jobs := s.store.GetAllJobs()
+// WatchJobs waits for job and worker changes after the requested resource
+// version and returns them. It returns without events when the timeout
+// expires, the call is cancelled or the manager shuts down, and callers
+// simply watch again.
+func (s *Server) WatchJobs(ctx context.Context, req pb.WatchRequest, resp *pb.WatchResponse) error {
+	if _, _, err := s.authorizeClient(ctx, "WatchJobs"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
 	
-	resp.Jobs = make([]pb.JobStatusResponse, len(jobs))
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultWatchTimeout
+	}
+	if timeout > maxWatchTimeout {
+		timeout = maxWatchTimeout
+	}
 	
-	for i, job := range jobs {
-		resp.Jobs[i] = pb.JobStatusResponse{
-			JobId:    job.ID,
-			Status:   string(job.Status),
-			WorkerId: job.WorkerID,
-			Output:   job.Output,
-			ExitCode: job.ExitCode,
+	ctx, cancel := s.untilStopped(ctx)
+	defer cancel()
+	events, version, err := s.store.Watch(req.ResourceVersion, watchMatch(req), timeout, ctx.Done())
+	if errors.Is(err, ErrVersionExpired) {
+		*resp = pb.WatchResponse{Expired: true, ResourceVersion: s.store.Version()}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	*resp = s.watchResponse(events, version)
+	return nil
+}
+
+// StreamJobs sends job and worker changes after the requested resource
+// version to send as they happen, a batch at a time, until the call is
+// cancelled or the manager shuts down. If the version is no longer
+// retained it sends one expired response and returns.
+func (s *Server) StreamJobs(ctx context.Context, req pb.WatchRequest, send func(*pb.WatchResponse) error) error {
+	if _, _, err := s.authorizeClient(ctx, "StreamJobs"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	
+	ctx, cancel := s.untilStopped(ctx)
+	defer cancel()
+	match := watchMatch(req)
+	version := req.ResourceVersion
+	for ctx.Err() == nil {
+		events, next, err := s.store.Watch(version, match, maxWatchTimeout, ctx.Done())
+		if errors.Is(err, ErrVersionExpired) {
+			return send(&pb.WatchResponse{Expired: true, ResourceVersion: s.store.Version()})
+		}
+		if err != nil {
+			return err
+		}
+		version = next
+		if len(events) == 0 {
+			continue
+		}
+		resp := s.watchResponse(events, version)
+		if err := send(&resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// untilStopped returns a copy of ctx that is also cancelled when the
+// manager stops
+func (s *Server) untilStopped(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-s.stopChan:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// watchMatch returns the filter for the events a watch request asks for
+func watchMatch(req pb.WatchRequest) func(Event) bool {
+	filter := JobFilter{Labels: req.Labels}
+	jobIDs := make(map[string]bool, len(req.JobIds))
+	for _, id := range req.JobIds {
+		jobIDs[id] = true
+	}
+	return func(e Event) bool {
+		if e.Worker != nil {
+			return req.IncludeWorkers
+		}
+		if len(jobIDs) > 0 && !jobIDs[e.Job.ID] {
+			return false
 		}
+		return filter.Matches(e.Job)
 	}
+}
+
+// watchResponse builds the response carrying events, to be continued
+// from version
+func (s *Server) watchResponse(events []Event, version uint64) pb.WatchResponse {
+	resp := pb.WatchResponse{ResourceVersion: version, Events: make([]pb.WatchEvent, len(events))}
+	for i, e := range events {
+		event := pb.WatchEvent{Type: string(e.Type), ResourceVersion: e.Version}
+		if e.Worker != nil {
+			worker := s.workerStatus(e.Worker)
+			event.Worker = &worker
+		} else {
+			job := jobSummaryResponse(e.Job)
+			event.Job = &job
+		}
+		resp.Events[i] = event
+	}
+	return resp
+}
+
+// CancelJob stops a job that has not finished. A task in flight is recorded
+// as cancelled and killed on its worker; whatever the worker reports for it
+// afterwards is rejected.
+func (s *Server) CancelJob(ctx context.Context, req pb.CancelJobRequest, resp *pb.JobStatusResponse) (err error) {
+	defer func() { s.audit(ctx, "CancelJob", req.JobId, req, err) }()
+	caller, role, err := s.authorizeClient(ctx, "CancelJob")
+	if err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	if !role.Includes(auth.RoleOperator) {
+		// Submitters may only cancel their own jobs
+		if job, ok := s.store.GetJob(req.JobId); ok && job.Owner != caller.Name {
+			return fmt.Errorf("%w: %s (role %s) may only cancel its own jobs; cancelling others' requires %s", ErrPermissionDenied, caller, role, auth.RoleOperator)
+		}
+	}
+	
+	reason := "cancelled by user"
+	if req.Reason != "" {
+		reason = "cancelled: " + req.Reason
+	}
+	
+	var job *models.Job
+	var task *models.Task
+	for attempt := 0; ; attempt++ {
+		job, task, err = s.cancelJob(req.JobId, reason)
+		if !errors.Is(err, ErrStatusConflict) || attempt == cancelRetries {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if task != nil {
+		s.scheduler.StopTask(task)
+		s.scheduler.Trigger() // Its resources are free for others
+	}
+	
+	logger.Info("Job cancelled", "job_id", job.ID, "reason", reason)
+	
+	*resp = jobStatusResponse(job, s.store.GetTasksForJob(job.ID))
 	return nil
 }
 
-// RegisterWorker handles worker registration
-func (s *Server) RegisterWorker(req pb.WorkerInfo, resp *pb.RegistrationResponse) error {
+// cancelJob makes one attempt at cancelling a job. It returns the task that
+// was cancelled with it, if the job had one in flight.
+func (s *Server) cancelJob(jobID, reason string) (*models.Job, *models.Task, error) {
+	job, ok := s.store.GetJob(jobID)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobID)
+	}
+	status := job.Status
+	if !status.CanTransitionTo(models.JobStatusCancelled) {
+		return nil, nil, &models.TransitionError{JobID: jobID, From: status, To: models.JobStatusCancelled}
+	}
+	
+	if status != models.JobStatusScheduled && status != models.JobStatusRunning {
+		// Nothing is in flight; the job only leaves the queue
+		job, err := s.store.CompareAndSwapJob(jobID, status, func(j *models.Job) error {
+			return j.Transition(models.JobStatusCancelled, reason, time.Now())
+		})
+		return job, nil, err
+	}
+	
+	now := time.Now()
+	return s.store.ApplyTaskUpdate(job.CurrentTaskID(), models.JobStatusCancelled, reason, func(j *models.Job, t *models.Task) error {
+		if j.Status != status || j.CurrentTaskID() != t.ID || !t.FinishedAt.IsZero() {
+			return fmt.Errorf("%w: job %s is %s", ErrStatusConflict, j.ID, j.Status)
+		}
+		t.Status = models.JobStatusCancelled
+		t.FinishedAt = now
+		return nil
+	})
+}
+
+// SearchHistory searches the archive of jobs evicted by retention
+func (s *Server) SearchHistory(ctx context.Context, req pb.HistoryRequest, resp *pb.HistoryResponse) error {
+	if _, _, err := s.authorizeClient(ctx, "SearchHistory"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	
+	if s.archive == nil {
+		return ErrArchiveDisabled
+	}
+	
+	filter := JobFilter{
+		JobID:           req.JobId,
+		CommandContains: req.Command,
+		SubmittedAfter:  unixToTime(req.SubmittedAfter),
+		SubmittedBefore: unixToTime(req.SubmittedBefore),
+	}
+	if req.Status != "" {
+		status, err := models.ParseJobStatus(req.Status)
+		if err != nil {
+			return err
+		}
+		filter.Statuses = []models.JobStatus{status}
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	
+	archived, err := s.archive.Search(filter, limit)
+	if err != nil {
+		return fmt.Errorf("failed to search history: %w", err)
+	}
+	
+	resp.Jobs = make([]pb.ArchivedJob, len(archived))
+	for i, a := range archived {
+		resp.Jobs[i] = pb.ArchivedJob{
+			Job:        jobStatusResponse(a.Job, a.Tasks),
+			ArchivedAt: a.ArchivedAt.Unix(),
+		}
+	}
+	return nil
+}
+
+// jobStatusResponse builds the RPC view of a job, including its attempts
+func jobStatusResponse(job *models.Job, tasks []*models.Task) pb.JobStatusResponse {
+	history := make([]pb.StatusTransition, len(job.History))
+	for i, t := range job.History {
+		history[i] = pb.StatusTransition{
+			From:   string(t.From),
+			To:     string(t.To),
+			At:     t.At.Unix(),
+			Reason: t.Reason,
+		}
+	}
+	
+	attempts := make([]pb.TaskAttempt, len(tasks))
+	for i, task := range tasks {
+		attempts[i] = pb.TaskAttempt{
+			TaskId:      task.ID,
+			Attempt:     int32(task.Attempt),
+			WorkerId:    task.WorkerID,
+			Status:      string(task.Status),
+			ExitCode:    task.ExitCode,
+			ScheduledAt: unixOrZero(task.CreatedAt),
+			StartedAt:   unixOrZero(task.StartedAt),
+			FinishedAt:  unixOrZero(task.FinishedAt),
+		}
+	}
+	
+	return pb.JobStatusResponse{
+		JobId:     job.ID,
+		Command:   job.Command,
+		Status:    string(job.Status),
+		WorkerId:  job.WorkerID,
+		Output:    job.Output,
+		ExitCode:  job.ExitCode,
+		History:   history,
+		Attempts:  attempts,
+		CreatedAt: job.CreatedAt.Unix(),
+		Labels:    job.Labels,
+		Owner:     job.Owner,
+		Secrets:   secretRefsResponse(job.Secrets),
+		Env:       job.Env,
+		Argv:      job.Argv,
+		Resources: pb.ResourceRequirements{
+			CpuMillicores: job.CPU,
+			MemoryMb:      job.Memory,
+		},
+		Priority:       job.Priority,
+		MaxRetries:     job.MaxRetries,
+		Retries:        job.Retries,
+		TimeoutSeconds: int64(job.Timeout / time.Second),
+		Constraints:    job.Constraints,
+	}
+}
+
+// jobSummaryResponse builds the RPC view of a job without its output,
+// history and attempts
+func jobSummaryResponse(job *models.Job) pb.JobStatusResponse {
+	return pb.JobStatusResponse{
+		JobId:     job.ID,
+		Command:   job.Command,
+		Status:    string(job.Status),
+		WorkerId:  job.WorkerID,
+		ExitCode:  job.ExitCode,
+		CreatedAt: job.CreatedAt.Unix(),
+		Labels:    job.Labels,
+		Owner:     job.Owner,
+		Argv:      job.Argv,
+		Priority:  job.Priority,
+	}
+}
+
+// unixToTime converts a Unix timestamp to a time, mapping 0 to the zero time
+func unixToTime(unix int64) time.Time {
+	if unix == 0 {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0)
+}
+
+// unixOrZero converts a time to a Unix timestamp, mapping the zero time to 0
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// SearchAudit searches the audit log of cluster-mutating calls
+func (s *Server) SearchAudit(ctx context.Context, req pb.AuditRequest, resp *pb.AuditResponse) error {
+	if _, _, err := s.authorizeClient(ctx, "SearchAudit"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	
+	if s.auditLog == nil {
+		return ErrAuditDisabled
+	}
+	
+	filter := AuditFilter{
+		Since:  unixToTime(req.Since),
+		Until:  unixToTime(req.Until),
+		Caller: req.Caller,
+		Method: req.Method,
+	}
+	limit := int(req.Limit)
+	if limit <= 0 {
+		limit = defaultAuditLimit
+	}
+	
+	records, err := s.auditLog.Search(filter, limit)
+	if err != nil {
+		return fmt.Errorf("failed to search audit log: %w", err)
+	}
+	
+	resp.Entries = make([]pb.AuditEntry, len(records))
+	for i, r := range records {
+		resp.Entries[i] = pb.AuditEntry{
+			Time:       r.Time.Unix(),
+			Caller:     r.Caller,
+			CallerKind: string(r.CallerKind),
+			Method:     r.Method,
+			Target:     r.Target,
+			Args:       string(r.Args),
+			Result:     r.Result,
+			Error:      r.Error,
+		}
+	}
+	return nil
+}
+
+// ListWorkers returns all registered workers
+func (s *Server) ListWorkers(ctx context.Context, req pb.ListWorkersRequest, resp *pb.ListWorkersResponse) error {
+	if _, _, err := s.authorizeClient(ctx, "ListWorkers"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	
+	workers := s.store.GetAllWorkers()
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].ID < workers[j].ID
+	})
+	
+	resp.Workers = make([]pb.WorkerStatusResponse, len(workers))
+	
+	for i, worker := range workers {
+		resp.Workers[i] = s.workerStatus(worker)
+	}
+	return nil
+}
+
+// GetWorker returns the current state of a single worker
+func (s *Server) GetWorker(ctx context.Context, req pb.GetWorkerRequest, resp *pb.WorkerStatusResponse) error {
+	if _, _, err := s.authorizeClient(ctx, "GetWorker"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	
+	worker, ok := s.store.GetWorker(req.WorkerId)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrWorkerNotFound, req.WorkerId)
+	}
+	
+	*resp = s.workerStatus(worker)
+	return nil
+}
+
+// DrainWorker stops placing new tasks on a worker, or resumes placing them.
+// Tasks already on the worker are left to finish.
+func (s *Server) DrainWorker(ctx context.Context, req pb.DrainWorkerRequest, resp *pb.WorkerStatusResponse) (err error) {
+	defer func() { s.audit(ctx, "DrainWorker", req.WorkerId, req, err) }()
+	if _, _, err := s.authorizeClient(ctx, "DrainWorker"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	
+	worker, err := s.store.UpdateWorker(req.WorkerId, func(w *models.Worker) error {
+		w.Draining = !req.Resume
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	
+	if req.Resume {
+		logger.Info("Worker resumed", "worker_id", req.WorkerId)
+		s.scheduler.Trigger()
+	} else {
+		logger.Info("Worker draining", "worker_id", req.WorkerId)
+	}
+	
+	*resp = s.workerStatus(worker)
+	return nil
+}
+
+// RevokeWorker bars a worker from registering, heartbeating and receiving
+// tasks, or lifts the ban. Nothing a revoked worker reports is trusted, so
+// its tasks are recorded as lost, stopped, and their jobs requeued.
+func (s *Server) RevokeWorker(ctx context.Context, req pb.RevokeWorkerRequest, resp *pb.WorkerStatusResponse) (err error) {
+	defer func() { s.audit(ctx, "RevokeWorker", req.WorkerId, req, err) }()
+	if _, _, err := s.authorizeClient(ctx, "RevokeWorker"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	
+	worker, err := s.store.UpdateWorker(req.WorkerId, func(w *models.Worker) error {
+		w.Revoked = !req.Restore
+		w.RevokedReason = ""
+		if !req.Restore {
+			w.RevokedReason = req.Reason
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	
+	if req.Restore {
+		logger.Info("Worker revocation lifted", "worker_id", req.WorkerId)
+		s.scheduler.Trigger()
+		*resp = s.workerStatus(worker)
+		return nil
+	}
+	
+	reason := "worker " + worker.ID + " revoked"
+	if req.Reason != "" {
+		reason += ": " + req.Reason
+	}
+	s.loseWorkerTasks(worker.ID, reason)
+	s.scheduler.Trigger()
+	
+	logger.Info("Worker revoked", "worker_id", worker.ID, "reason", req.Reason)
+	
+	*resp = s.workerStatus(worker)
+	return nil
+}
+
+// loseWorkerTasks records the unfinished tasks of a worker that can no
+// longer be trusted to finish them as LOST. Their jobs go through LOST back
+// to the queue, without using up a retry. The worker is asked to stop the
+// tasks in case it is still running them. It returns how many were lost.
+func (s *Server) loseWorkerTasks(workerID, reason string) int {
+	now := time.Now()
+	lost := 0
+	for _, task := range s.store.GetActiveTasksForWorker(workerID) {
+		job, lostTask, err := s.store.ApplyTaskUpdate(task.ID, models.JobStatusLost, reason, func(j *models.Job, t *models.Task) error {
+			if t.WorkerID != workerID || !t.FinishedAt.IsZero() {
+				return fmt.Errorf("%w: task %s is %s", ErrStatusConflict, t.ID, t.Status)
+			}
+			t.Status = models.JobStatusLost
+			t.Output = reason
+			t.FinishedAt = now
+			if j.CurrentTaskID() == t.ID {
+				j.WorkerID = ""
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Warn("Failed to mark task lost", "task_id", task.ID, "worker_id", workerID, "error", err)
+			continue
+		}
+		lost++
+		s.scheduler.StopTask(lostTask)
+		
+		if job.CurrentTaskID() == lostTask.ID {
+			if _, err := s.store.TransitionJob(job.ID, models.JobStatusPending, "rescheduled after its worker was lost", nil); err != nil {
+				// E.g. it was cancelled in the meantime
+				logger.Warn("Failed to requeue lost job", "job_id", job.ID, "error", err)
+			}
+		}
+	}
+	if lost > 0 {
+		s.scheduler.Trigger()
+	}
+	return lost
+}
+
+// workerStatus builds the RPC view of a worker, including the tasks it is running
+func (s *Server) workerStatus(worker *models.Worker) pb.WorkerStatusResponse {
+	status := worker.Status
+	if time.Since(worker.LastHeartbeat) >= heartbeatTimeout {
+		status = models.WorkerStatusUnhealthy
+	}
+	
+	tasks := s.store.GetActiveTasksForWorker(worker.ID)
+	taskIDs := make([]string, len(tasks))
+	for i, task := range tasks {
+		taskIDs[i] = task.ID
+	}
+	sort.Strings(taskIDs)
+	
+	return pb.WorkerStatusResponse{
+		WorkerId:      worker.ID,
+		Address:       worker.Address,
+		Status:        string(status),
+		Draining:      worker.Draining,
+		Revoked:       worker.Revoked,
+		RevokedReason: worker.RevokedReason,
+		Capacity: pb.ResourceCapacity{
+			TotalCpuMillicores: worker.TotalCPU,
+			TotalMemoryMb:      worker.TotalMemory,
+		},
+		Usage: pb.ResourceUsage{
+			UsedCpuMillicores: worker.UsedCPU,
+			UsedMemoryMb:      worker.UsedMemory,
+		},
+		LastHeartbeat: worker.LastHeartbeat.Unix(),
+		RegisteredAt:  worker.RegisteredAt.Unix(),
+		RunningTasks:  taskIDs,
+		Labels:        worker.Labels,
+	}
+}
+
+// RegisterWorker handles worker registration. A worker ID that is live at
+// one address can't be taken over from another, unless the newcomer proves
+// it owns the ID with the worker's certificate.
+func (s *Server) RegisterWorker(ctx context.Context, req pb.WorkerInfo, resp *pb.RegistrationResponse) (err error) {
+	defer func() { s.audit(ctx, "RegisterWorker", req.WorkerId, req, err) }()
+	caller, err := s.authorizeWorker(ctx, req.WorkerId)
+	if err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	
+	existing, _ := s.store.GetWorker(req.WorkerId)
+	if err := s.admitWorker(caller, req, existing); err != nil {
+		logger.Warn("Worker registration rejected", "worker_id", req.WorkerId, "address", req.Address, "error", err)
+		return err
+	}
+	if existing != nil && existing.Address != req.Address && caller == nil && time.Since(existing.LastHeartbeat) < heartbeatTimeout {
+		logger.Warn("Worker registration rejected: ID in use at another address", "worker_id", req.WorkerId, "address", req.Address, "registered_address", existing.Address)
+		return fmt.Errorf("%w: %q is live at %s", ErrWorkerExists, req.WorkerId, existing.Address)
+	}
+	
 	worker := &models.Worker{
 		ID:           req.WorkerId,
 		Address:      req.Address,
 		TotalCPU:     req.Capacity.TotalCpuMillicores,
 		TotalMemory:  req.Capacity.TotalMemoryMb,
+		Labels:       req.Labels,
 		Status:       models.WorkerStatusHealthy,
 		LastHeartbeat: time.Now(),
 		RegisteredAt: time.Now(),
 	}
+	if existing != nil {
+		// A drained worker stays drained when it re-registers
+		worker.Draining = existing.Draining
+	}
 	
-	s.store.RegisterWorker(worker)
+	if err := s.store.RegisterWorker(worker); err != nil {
+		return err
+	}
 	
 	logger.Info("Worker registered", "worker_id", req.WorkerId, "address", req.Address)
 	
+	// Admission checked the join token, if required
+	proven := caller != nil || s.joinTokens != nil
+	for _, task := range req.RunningTasks {
+		s.adoptRunningTask(req.WorkerId, proven, task)
+	}
+	s.scheduler.Trigger()
+	
 	*resp = pb.RegistrationResponse{
 		Accepted: true,
 		Message:  "Worker registered successfully",
@@ -121,14 +958,136 @@ func (s *Server) RegisterWorker(req pb.WorkerInfo, resp *pb.RegistrationResponse
 	return nil
 }
 
+// DeregisterWorker removes a worker that is shutting down so no new tasks
+// are scheduled on it
+func (s *Server) DeregisterWorker(ctx context.Context, req pb.DeregisterRequest, resp *pb.Ack) (err error) {
+	defer func() { s.audit(ctx, "DeregisterWorker", req.WorkerId, req, err) }()
+	if err := s.authorizeWorkerToken(ctx, req.WorkerId, req.JoinToken); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	if worker, ok := s.store.GetWorker(req.WorkerId); ok && worker.Revoked {
+		// The record is what keeps the worker out; it stays
+		return errRevoked(worker)
+	}
+	
+	removed, err := s.store.RemoveWorker(req.WorkerId)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("%w: %s", ErrWorkerNotFound, req.WorkerId)
+	}
+	
+	logger.Info("Worker deregistered", "worker_id", req.WorkerId)
+	
+	*resp = pb.Ack{Ok: true}
+	return nil
+}
+
+// adoptRunningTask rebuilds the manager's view of a task a worker reports as
+// running, e.g. after the manager restarted and lost its in-memory state.
+// A worker may only adopt its own tasks. Only a proven worker may adopt an
+// unassigned task or have an unknown one recovered as a job, as otherwise
+// anyone could claim them under any worker ID.
+func (s *Server) adoptRunningTask(workerID string, proven bool, rt pb.RunningTask) {
+	if task, ok := s.store.GetTask(rt.TaskId); ok {
+		if task.Status == models.JobStatusRunning && task.WorkerID == workerID {
+			return
+		}
+		
+		_, _, err := s.store.ApplyTaskUpdate(rt.TaskId, models.JobStatusRunning, "reported running by worker "+workerID, func(j *models.Job, t *models.Task) error {
+			if t.WorkerID != workerID && (t.WorkerID != "" || !proven) {
+				return fmt.Errorf("%w: task %s is placed on worker %q", ErrPermissionDenied, t.ID, t.WorkerID)
+			}
+			if !t.FinishedAt.IsZero() {
+				return &models.TransitionError{JobID: t.JobID, From: t.Status, To: models.JobStatusRunning}
+			}
+			t.Status = models.JobStatusRunning
+			t.WorkerID = workerID
+			if t.StartedAt.IsZero() {
+				t.StartedAt = time.Now()
+			}
+			if j.CurrentTaskID() == t.ID {
+				j.WorkerID = workerID
+			}
+			return nil
+		})
+		if err != nil {
+			logger.Warn("Ignoring running task reported by worker", "task_id", rt.TaskId, "worker_id", workerID, "error", err)
+		}
+		return
+	}
+	
+	if _, ok := s.store.GetJob(rt.JobId); ok {
+		logger.Warn("Worker reports unknown attempt of a known job", "job_id", rt.JobId, "task_id", rt.TaskId, "worker_id", workerID)
+		return
+	}
+	if !proven {
+		logger.Warn("Not recovering running job reported by unproven worker", "job_id", rt.JobId, "task_id", rt.TaskId, "worker_id", workerID)
+		return
+	}
+	
+	now := time.Now()
+	job := models.NewJob(rt.JobId, models.JobStatusRunning, "recovered from worker "+workerID, now)
+	job.Command = rt.Command
+	job.WorkerID = workerID
+	job.TaskIDs = []string{rt.TaskId}
+	task := &models.Task{
+		ID:        rt.TaskId,
+		JobID:     rt.JobId,
+		WorkerID:  workerID,
+		Attempt:   1,
+		Command:   rt.Command,
+		Status:    models.JobStatusRunning,
+		CreatedAt: now,
+		StartedAt: now,
+	}
+	if err := s.store.AddJob(job, task); err != nil {
+		logger.Warn("Failed to recover running job from worker", "job_id", rt.JobId, "error", err)
+		return
+	}
+	
+	logger.Info("Recovered running job from worker", "job_id", job.ID, "task_id", rt.TaskId, "worker_id", workerID)
+}
+
 // Heartbeat handles worker heartbeats
-func (s *Server) Heartbeat(req pb.HeartbeatRequest, resp *pb.HeartbeatResponse) error {
+func (s *Server) Heartbeat(ctx context.Context, req pb.HeartbeatRequest, resp *pb.HeartbeatResponse) error {
+	if err := s.authorizeWorkerToken(ctx, req.WorkerId, req.JoinToken); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	if worker, ok := s.store.GetWorker(req.WorkerId); ok && worker.Revoked {
+		// Without heartbeats a revoked worker soon counts as unhealthy
+		return errRevoked(worker)
+	}
+	
 	usage := &models.Worker{
 		UsedCPU:    req.CurrentUsage.UsedCpuMillicores,
 		UsedMemory: req.CurrentUsage.UsedMemoryMb,
 	}
 	
-	s.store.UpdateWorkerHeartbeat(req.WorkerId, usage)
+	prev, err := s.store.UpdateWorkerHeartbeat(req.WorkerId, usage)
+	if errors.Is(err, ErrWorkerNotFound) {
+		logger.Warn("Heartbeat from unknown worker", "worker_id", req.WorkerId)
+		*resp = pb.HeartbeatResponse{
+			Acknowledged: false,
+			Reregister:   true,
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	
+	// Capacity was freed if usage dropped or the worker came back
+	if usage.UsedCPU < prev.UsedCPU || time.Since(prev.LastHeartbeat) >= heartbeatTimeout {
+		s.scheduler.Trigger()
+	}
 	
 	*resp = pb.HeartbeatResponse{
 		Acknowledged: true,
@@ -136,49 +1095,203 @@ func (s *Server) Heartbeat(req pb.HeartbeatRequest, resp *pb.HeartbeatResponse)
 	return nil
 }
 
-// ReportTaskStatus handles task status updates from workers
-func (s *Server) ReportTaskStatus(req pb.TaskStatusUpdate, resp *pb.Ack) error {
-	// Find the job associated with this task
-	job, ok := s.store.GetJob(req.TaskId)
+// errStaleUpdate marks a task status update that was already applied
+var errStaleUpdate = errors.New("stale task status update")
+
+// ReportTaskStatus handles task status updates from workers. The task's
+// attempt record is always updated; its job only follows if the task is the
+// job's current attempt. Updates that would make an illegal transition are
+// rejected with Ok set to false. Only the worker a task is placed on may
+// report it, and revoked workers may not report at all.
+func (s *Server) ReportTaskStatus(ctx context.Context, req pb.TaskStatusUpdate, resp *pb.Ack) error {
+	caller, err := s.authorize(ctx, auth.KindWorker)
+	if err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	workerID, proven, err := s.reportingWorker(caller, req)
+	if err != nil {
+		return err
+	}
+	if worker, ok := s.store.GetWorker(workerID); ok && worker.Revoked {
+		return errRevoked(worker)
+	}
+	
+	to, reason, err := jobStatusForTask(req.Status)
+	if err != nil {
+		logger.Warn("Rejected task status", "task_id", req.TaskId, "error", err)
+		*resp = pb.Ack{Ok: false, Message: err.Error()}
+		return nil
+	}
+	
+	task, ok := s.store.GetTask(req.TaskId)
 	if !ok {
-		return fmt.Errorf("job not found: %s", req.TaskId)
+		if !to.IsTerminal() {
+			return fmt.Errorf("%w: %s", ErrTaskNotFound, req.TaskId)
+		}
+		if !proven {
+			workerID = ""
+		}
+		return s.recoverFinishedTask(workerID, req, to, resp)
 	}
 	
-	// Update job status based on task status
-	job.Status = models.JobStatus(req.Status)
-	job.Output = req.Output
-	job.ExitCode = req.ExitCode
+	// A failed or timed out job with retries left goes back to the queue
+	retry := false
+	if to == models.JobStatusFailed || to == models.JobStatusTimedOut {
+		if job, ok := s.store.GetJob(task.JobID); ok && job.CurrentTaskID() == task.ID && job.Retries < job.MaxRetries {
+			retry = true
+			to = models.JobStatusPending
+			if req.Status == string(models.JobStatusTimedOut) {
+				reason = fmt.Sprintf("timed out; retry %d of %d", job.Retries+1, job.MaxRetries)
+			} else {
+				reason = fmt.Sprintf("failed with exit code %d; retry %d of %d", req.ExitCode, job.Retries+1, job.MaxRetries)
+			}
+		}
+	}
 	
-	s.store.UpdateJob(job)
+	now := time.Now()
+	_, _, err = s.store.ApplyTaskUpdate(req.TaskId, to, reason, func(j *models.Job, t *models.Task) error {
+		if workerID != "" && t.WorkerID != workerID {
+			return fmt.Errorf("%w: task %s is not placed on worker %q", ErrPermissionDenied, t.ID, workerID)
+		}
+		// Workers retry until acknowledged, so the same update may arrive
+		// more than once or after a newer one
+		if req.Seq != 0 && req.Seq <= t.StatusSeq {
+			return errStaleUpdate
+		}
+		if !t.FinishedAt.IsZero() {
+			return &models.TransitionError{JobID: t.JobID, From: t.Status, To: models.JobStatus(req.Status)}
+		}
+		
+		t.Status = models.JobStatus(req.Status)
+		t.Output = req.Output
+		t.ExitCode = req.ExitCode
+		t.StatusSeq = req.Seq
+		if t.Status == models.JobStatusRunning {
+			t.StartedAt = now
+		} else {
+			t.FinishedAt = now
+		}
+		
+		if j.CurrentTaskID() == t.ID {
+			if to == models.JobStatusPending {
+				j.WorkerID = ""
+			}
+			if retry {
+				if j.Retries >= j.MaxRetries {
+					return fmt.Errorf("%w: job %s has no retries left", ErrStatusConflict, j.ID)
+				}
+				j.Retries++
+			}
+			j.Output = req.Output
+			j.ExitCode = req.ExitCode
+		}
+		return nil
+	})
 	
-	logger.Info("Task status updated", "task_id", req.TaskId, "status", req.Status)
+	var transitionErr *models.TransitionError
+	switch {
+	case errors.Is(err, errStaleUpdate):
+		logger.Info("Ignoring stale task status", 
+			"task_id", req.TaskId, 
+			"status", req.Status, 
+			"seq", req.Seq)
+		*resp = pb.Ack{Ok: true}
+		return nil
+	case errors.As(err, &transitionErr):
+		logger.Warn("Rejected task status", "task_id", req.TaskId, "error", err)
+		if task, ok := s.store.GetTask(req.TaskId); ok && !task.FinishedAt.IsZero() && to == models.JobStatusRunning {
+			// The task finished while it was being dispatched, e.g. it was
+			// cancelled, or requeued because the dispatch timed out, yet
+			// the worker started it. Its job may run elsewhere by now.
+			s.scheduler.StopTask(task)
+		}
+		*resp = pb.Ack{Ok: false, Message: err.Error()}
+		return nil
+	case err != nil:
+		return err
+	}
+	
+	// A finished or requeued task frees capacity, and a requeued job needs
+	// a new placement
+	if to != models.JobStatusRunning {
+		s.scheduler.Trigger()
+	}
+	
+	logger.Info("Task status updated", "task_id", req.TaskId, "job_id", req.JobId, "status", req.Status)
 	
 	*resp = pb.Ack{Ok: true}
 	return nil
 }
 
-// StartTask is called by scheduler to start a task on a worker
-// Note: This is usually called client->server, but here it's just a placeholder
-// The real StartTask happens in the Worker service
-func (s *Server) StartTask(req pb.TaskRequest, resp *pb.TaskResponse) error {
-	*resp = pb.TaskResponse{
-		Accepted: true,
-		Message:  "Task started",
+// recoverFinishedTask keeps the final result of a task the manager has no
+// record of (e.g. it restarted) rather than dropping it. Only a worker that
+// proved its identity, and is registered and not revoked, may do this, as
+// the report becomes a job as it stands. workerID is empty if the reporter
+// is unproven.
+func (s *Server) recoverFinishedTask(workerID string, req pb.TaskStatusUpdate, status models.JobStatus, resp *pb.Ack) error {
+	reject := func(msg string) error {
+		logger.Warn("Rejected task status", "task_id", req.TaskId, "error", msg)
+		*resp = pb.Ack{Ok: false, Message: msg}
+		return nil
 	}
-	return nil
-}
-
-// StopTask stops a running task
-func (s *Server) StopTask(req pb.StopTaskRequest, resp *pb.StopTaskResponse) error {
-	*resp = pb.StopTaskResponse{
-		Stopped: true,
+	if _, ok := s.store.GetJob(req.JobId); ok || req.JobId == "" {
+		return reject(fmt.Sprintf("unknown task %s for job %q", req.TaskId, req.JobId))
+	}
+	if workerID == "" {
+		return reject(fmt.Sprintf("unknown task %s; only authenticated workers may report it", req.TaskId))
+	}
+	worker, ok := s.store.GetWorker(workerID)
+	if !ok {
+		return reject(fmt.Sprintf("unknown task %s; worker %q is not registered", req.TaskId, workerID))
+	}
+	if worker.Revoked {
+		return reject(errRevoked(worker).Error())
 	}
+	
+	now := time.Now()
+	job := models.NewJob(req.JobId, status, "recovered from report of worker "+worker.ID, now)
+	job.Output = req.Output
+	job.ExitCode = req.ExitCode
+	job.WorkerID = worker.ID
+	job.TaskIDs = []string{req.TaskId}
+	task := &models.Task{
+		ID:         req.TaskId,
+		JobID:      req.JobId,
+		WorkerID:   worker.ID,
+		Attempt:    1,
+		Status:     status,
+		Output:     req.Output,
+		ExitCode:   req.ExitCode,
+		StatusSeq:  req.Seq,
+		CreatedAt:  now,
+		FinishedAt: now,
+	}
+	if err := s.store.AddJob(job, task); err != nil {
+		return err
+	}
+	logger.Info("Recovered finished job from worker report", "job_id", req.JobId, "task_id", req.TaskId, "status", req.Status, "worker_id", worker.ID)
+	
+	*resp = pb.Ack{Ok: true}
 	return nil
 }
 
-// GetRPCServer creates and returns a net/rpc server
-func (s *Server) GetRPCServer() *rpc.Server {
-	server := rpc.NewServer()
-	s.RegisterRPC(server)
-	return server
+// jobStatusForTask maps a status reported by a worker to the job status it
+// leads to, with the reason recorded in the job's history
+func jobStatusForTask(status string) (models.JobStatus, string, error) {
+	switch models.JobStatus(status) {
+	case models.JobStatusInterrupted:
+		// The worker shut down before the task finished; requeue it
+		return models.JobStatusPending, "interrupted by worker shutdown", nil
+	case models.JobStatusRunning:
+		return models.JobStatusRunning, "started by worker", nil
+	case models.JobStatusCompleted, models.JobStatusFailed, models.JobStatusTimedOut:
+		return models.JobStatus(status), "reported by worker", nil
+	}
+	if _, err := models.ParseJobStatus(status); err != nil {
+		return "", "", err
+	}
+	return "", "", fmt.Errorf("task status %s cannot be reported by a worker", status)
 }
@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+)
+
+// registerWorker registers a worker at a made-up address, reporting tasks
+// as running on it
+func registerWorker(t *testing.T, s *Server, id, joinToken string, tasks ...pb.RunningTask) {
+	t.Helper()
+	info := pb.WorkerInfo{WorkerId: id, Address: "127.0.0.1:1", JoinToken: joinToken, RunningTasks: tasks}
+	if err := s.RegisterWorker(context.Background(), info, &pb.RegistrationResponse{}); err != nil {
+		t.Fatalf("RegisterWorker(%s): %v", id, err)
+	}
+}
+
+// TestRegisterAdoptsOnlyOwnTasks checks that re-registering can't move
+// another worker's task, and that only proven workers recover unknown jobs
+func TestRegisterAdoptsOnlyOwnTasks(t *testing.T) {
+	s := newTestServer(t, Config{JoinTokens: newJoinTokens(t, testJoinToken)})
+	registerWorker(t, s, "w1", testJoinToken)
+	addPendingJob(t, s.store, "j1")
+	startAttempt(t, s.store, "j1", "t1", "w1")
+
+	registerWorker(t, s, "w2", testJoinToken, pb.RunningTask{TaskId: "t1", JobId: "j1"})
+	if task, _ := s.store.GetTask("t1"); task.WorkerID != "w1" || task.Status != models.JobStatusScheduled {
+		t.Fatalf("w2 took over t1: now %s on %s", task.Status, task.WorkerID)
+	}
+	if job, _ := s.store.GetJob("j1"); job.WorkerID != "w1" {
+		t.Fatalf("w2 took over j1: now on %s", job.WorkerID)
+	}
+
+	registerWorker(t, s, "w1", testJoinToken, pb.RunningTask{TaskId: "t1", JobId: "j1"})
+	if task, _ := s.store.GetTask("t1"); task.WorkerID != "w1" || task.Status != models.JobStatusRunning {
+		t.Errorf("w1 didn't adopt its own task: %s on %s", task.Status, task.WorkerID)
+	}
+
+	registerWorker(t, s, "w2", testJoinToken, pb.RunningTask{TaskId: "t2", JobId: "j2", Command: "sleep 60"})
+	if job, ok := s.store.GetJob("j2"); !ok || job.WorkerID != "w2" || job.Status != models.JobStatusRunning {
+		t.Errorf("unknown job from a proven worker not recovered: %+v", job)
+	}
+}
+
+// TestRegisterUnprovenWorkerRecoversNothing checks that without join tokens
+// or certificates a worker keeps its own tasks but can't create jobs
+func TestRegisterUnprovenWorkerRecoversNothing(t *testing.T) {
+	s := newTestServer(t, Config{})
+	registerWorker(t, s, "w1", "")
+	addPendingJob(t, s.store, "j1")
+	startAttempt(t, s.store, "j1", "t1", "w1")
+
+	registerWorker(t, s, "w1", "", pb.RunningTask{TaskId: "t1", JobId: "j1"}, pb.RunningTask{TaskId: "t2", JobId: "j2"})
+	if task, _ := s.store.GetTask("t1"); task.Status != models.JobStatusRunning {
+		t.Errorf("w1 didn't adopt its own task: %s", task.Status)
+	}
+	if _, ok := s.store.GetJob("j2"); ok {
+		t.Error("unknown job recovered from an unproven worker")
+	}
+}
@@ -1,127 +1,189 @@
 package manager
 
 import (
-	"sync"
+	"errors"
+	"fmt"
+	"path/filepath"
 	"time"
 
 	"titan/pkg/models"
 )
 
-// Store manages all cluster state in-memory
-type Store struct {
-	mu      sync.RWMutex
-	jobs    map[string]*models.Job
-	workers map[string]*models.Worker
-}
+// heartbeatTimeout is how long a worker may go without a heartbeat
+// before it is considered unhealthy
+const heartbeatTimeout = 30 * time.Second
 
-// NewStore creates a new in-memory store
-func NewStore() *Store {
-	return &Store{
-		jobs:    make(map[string]*models.Job),
-		workers: make(map[string]*models.Worker),
-	}
-}
+var (
+	// ErrJobNotFound is returned when an operation refers to an unknown job
+	ErrJobNotFound = errors.New("job not found")
 
-// AddJob stores a new job
-func (s *Store) AddJob(job *models.Job) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.jobs[job.ID] = job
-}
+	// ErrTaskNotFound is returned when an operation refers to an unknown task
+	ErrTaskNotFound = errors.New("task not found")
 
-// GetJob retrieves a job by ID
-func (s *Store) GetJob(id string) (*models.Job, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	job, ok := s.jobs[id]
-	return job, ok
-}
+	// ErrJobExists is returned when adding a job whose ID is already taken
+	ErrJobExists = errors.New("job already exists")
 
-// UpdateJob updates an existing job
-func (s *Store) UpdateJob(job *models.Job) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	job.UpdatedAt = time.Now()
-	s.jobs[job.ID] = job
-}
+	// ErrWorkerNotFound is returned when an operation refers to an
+	// unregistered worker
+	ErrWorkerNotFound = errors.New("worker not found")
 
-// GetAllJobs returns all jobs
-func (s *Store) GetAllJobs() []*models.Job {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	jobs := make([]*models.Job, 0, len(s.jobs))
-	for _, job := range s.jobs {
-		jobs = append(jobs, job)
-	}
-	return jobs
-}
+	// ErrStatusConflict is returned by compare-and-swap updates when the
+	// job is no longer in the expected status
+	ErrStatusConflict = errors.New("job status changed")
 
-// GetPendingJobs returns jobs that need to be scheduled
-func (s *Store) GetPendingJobs() []*models.Job {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	pending := make([]*models.Job, 0)
-	for _, job := range s.jobs {
-		if job.Status == models.JobStatusPending {
-			pending = append(pending, job)
-		}
-	}
-	return pending
-}
+	// ErrSecretNotFound is returned when an operation refers to an unknown
+	// secret
+	ErrSecretNotFound = errors.New("secret not found")
 
-// RegisterWorker adds or updates a worker
-func (s *Store) RegisterWorker(worker *models.Worker) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.workers[worker.ID] = worker
-}
+	// ErrTemplateNotFound is returned when an operation refers to an
+	// unknown job template
+	ErrTemplateNotFound = errors.New("template not found")
+)
+
+// Store holds all cluster state.
+//
+// A store owns its records: every method that returns a job, task or
+// worker returns a copy, and every method that accepts one stores a copy.
+// Callers change state only through the update methods, which apply their
+// changes atomically.
+type Store interface {
+	// AddJob stores a new job together with any task records it already has
+	AddJob(job *models.Job, tasks ...*models.Task) error
+	// GetJob retrieves a copy of a job by ID
+	GetJob(id string) (*models.Job, bool)
+	// UpdateJob atomically applies fn to a copy of a job and stores the
+	// result. Returning an error from fn aborts the update.
+	UpdateJob(id string, fn func(*models.Job) error) (*models.Job, error)
+	// CompareAndSwapJob is UpdateJob that only applies fn while the job is
+	// still in the expected status. Otherwise it returns ErrStatusConflict.
+	CompareAndSwapJob(id string, expected models.JobStatus, fn func(*models.Job) error) (*models.Job, error)
+	// TransitionJob atomically moves a job to a new status, validating the
+	// transition against the job state machine. mutate, if not nil, is
+	// applied to a copy of the job first.
+	TransitionJob(id string, to models.JobStatus, reason string, mutate func(*models.Job) error) (*models.Job, error)
+	// GetAllJobs returns copies of all jobs
+	GetAllJobs() []*models.Job
+	// GetPendingJobs returns copies of the jobs that need to be scheduled
+	GetPendingJobs() []*models.Job
+	// RemoveJobs deletes finished jobs together with all their attempts and
+	// returns how many were removed. Unknown and unfinished jobs are skipped.
+	RemoveJobs(ids ...string) (int, error)
 
-// GetWorker retrieves a worker by ID
-func (s *Store) GetWorker(id string) (*models.Worker, bool) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	worker, ok := s.workers[id]
-	return worker, ok
+	// StartAttempt atomically moves a pending job to SCHEDULED and records
+	// task as its new attempt. The task's Attempt number is filled in.
+	StartAttempt(jobID string, task *models.Task, reason string) (*models.Job, error)
+	// ApplyTaskUpdate atomically applies a status change to a task. If the
+	// task is its job's current attempt the job is also moved to jobStatus.
+	ApplyTaskUpdate(taskID string, jobStatus models.JobStatus, reason string, mutate func(*models.Job, *models.Task) error) (*models.Job, *models.Task, error)
+	// GetTask retrieves a copy of a task by ID
+	GetTask(id string) (*models.Task, bool)
+	// GetTasksForJob returns copies of every attempt of a job, oldest first
+	GetTasksForJob(jobID string) []*models.Task
+	// GetActiveTasksForWorker returns the tasks currently scheduled or
+	// running on a worker
+	GetActiveTasksForWorker(workerID string) []*models.Task
+
+	// RegisterWorker adds or updates a worker
+	RegisterWorker(worker *models.Worker) error
+	// UpdateWorker atomically applies fn to a copy of a worker and stores
+	// the result. Returning an error from fn aborts the update.
+	UpdateWorker(id string, fn func(*models.Worker) error) (*models.Worker, error)
+	// RemoveWorker deletes a worker from the cluster, reporting whether it
+	// was registered
+	RemoveWorker(id string) (bool, error)
+	// GetWorker retrieves a copy of a worker by ID
+	GetWorker(id string) (*models.Worker, bool)
+	// UpdateWorkerHeartbeat records a heartbeat and returns the worker as
+	// it was before the update
+	UpdateWorkerHeartbeat(workerID string, usage *models.Worker) (*models.Worker, error)
+	// GetHealthyWorkers returns copies of all workers that are healthy
+	GetHealthyWorkers() []*models.Worker
+	// GetAllWorkers returns copies of all registered workers
+	GetAllWorkers() []*models.Worker
+
+	// PutSecret adds or replaces a secret
+	PutSecret(secret *models.Secret) error
+	// GetSecret retrieves a copy of a secret by name
+	GetSecret(name string) (*models.Secret, bool)
+	// GetAllSecrets returns copies of all secrets
+	GetAllSecrets() []*models.Secret
+	// RemoveSecret deletes a secret, reporting whether it existed
+	RemoveSecret(name string) (bool, error)
+
+	// PutTemplate adds or replaces a job template
+	PutTemplate(template *models.JobTemplate) error
+	// GetTemplate retrieves a copy of a job template by name
+	GetTemplate(name string) (*models.JobTemplate, bool)
+	// GetAllTemplates returns copies of all job templates
+	GetAllTemplates() []*models.JobTemplate
+	// RemoveTemplate deletes a job template, reporting whether it existed
+	RemoveTemplate(name string) (bool, error)
+
+	// Version returns the current resource version, which increases with
+	// every change. Reading it before reading state gives a version to
+	// watch from without missing changes.
+	Version() uint64
+	// Watch returns the job and worker changes after resource version
+	// since that satisfy match, waiting until there is at least one,
+	// timeout expires or cancel is closed. It also returns the version to
+	// resume from. ErrVersionExpired means the changes after since are no
+	// longer retained and the caller must re-read current state.
+	Watch(since uint64, match func(Event) bool, timeout time.Duration, cancel <-chan struct{}) ([]Event, uint64, error)
+
+	// Close flushes and releases the store. No other method may be called
+	// afterwards.
+	Close() error
 }
 
-// UpdateWorkerHeartbeat updates the last heartbeat time for a worker
-func (s *Store) UpdateWorkerHeartbeat(workerID string, usage *models.Worker) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	if worker, ok := s.workers[workerID]; ok {
-		worker.LastHeartbeat = time.Now()
-		if usage != nil {
-			worker.UsedCPU = usage.UsedCPU
-			worker.UsedMemory = usage.UsedMemory
-		}
-		worker.Status = models.WorkerStatusHealthy
-	}
+// replicatedStore is implemented by stores shared by several manager
+// replicas. Only the leader replica serves requests and schedules jobs.
+type replicatedStore interface {
+	Store
+
+	// CheckLeader returns a *pb.NotLeaderError naming the current leader
+	// unless this replica is the leader and has caught up with the log
+	CheckLeader() error
+
+	// OnLeader registers fn to run each time this replica becomes leader
+	OnLeader(fn func())
 }
 
-// GetHealthyWorkers returns all workers that are healthy
-func (s *Store) GetHealthyWorkers() []*models.Worker {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	
-	healthy := make([]*models.Worker, 0)
-	now := time.Now()
-	heartbeatTimeout := 30 * time.Second
-	
-	for _, worker := range s.workers {
-		if now.Sub(worker.LastHeartbeat) < heartbeatTimeout {
-			healthy = append(healthy, worker)
-		}
-	}
-	return healthy
+// ChangeSet is the set of records written by a single store update. Every
+// backend commits a change set as a unit, so it is also the unit of
+// persistence.
+type ChangeSet struct {
+	Jobs             []*models.Job
+	Tasks            []*models.Task
+	Workers          []*models.Worker
+	Secrets          []*models.Secret
+	Templates        []*models.JobTemplate
+	RemovedJobs      []string
+	RemovedTasks     []string
+	RemovedWorkers   []string
+	RemovedSecrets   []string
+	RemovedTemplates []string
+
+	// Volatile marks changes that only carry liveness data, such as
+	// heartbeats. Durable backends may skip persisting them: they are
+	// rebuilt within one heartbeat interval after a restart.
+	Volatile bool
 }
 
-// GetAllWorkers returns all registered workers
-func (s *Store) GetAllWorkers() []*models.Worker {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	workers := make([]*models.Worker, 0, len(s.workers))
-	for _, worker := range s.workers {
-		workers = append(workers, worker)
+// Store backends accepted by OpenStore
+const (
+	StoreBackendMemory = "memory"
+	StoreBackendBolt   = "bolt"
+)
+
+// OpenStore opens the store backend selected by name. Durable backends
+// keep their files in dataDir.
+func OpenStore(backend, dataDir string) (Store, error) {
+	switch backend {
+	case StoreBackendMemory:
+		return NewMemoryStore(), nil
+	case StoreBackendBolt:
+		return OpenBoltStore(filepath.Join(dataDir, "titan.db"))
+	default:
+		return nil, fmt.Errorf("unknown store backend %q", backend)
 	}
-	return workers
 }
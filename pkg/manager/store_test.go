@@ -0,0 +1,534 @@
+package manager
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"titan/pkg/models"
+)
+
+// storeBackends opens a fresh, empty store of each backend. Every backend
+// must pass the same conformance suite.
+var storeBackends = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"memory", func(t *testing.T) Store {
+		return NewMemoryStore()
+	}},
+	{"bolt", func(t *testing.T) Store {
+		s, err := OpenBoltStore(filepath.Join(t.TempDir(), "titan.db"))
+		if err != nil {
+			t.Fatalf("OpenBoltStore: %v", err)
+		}
+		return s
+	}},
+	{"raft", func(t *testing.T) Store {
+		stores, _ := openRaftCluster(t, 1)
+		return stores[0]
+	}},
+}
+
+// storeTests is the conformance suite run against every backend
+var storeTests = []struct {
+	name string
+	run  func(t *testing.T, s Store)
+}{
+	{"JobCRUD", testStoreJobCRUD},
+	{"CompareAndSwapJob", testStoreCompareAndSwapJob},
+	{"TransitionJob", testStoreTransitionJob},
+	{"StartAttempt", testStoreStartAttempt},
+	{"ApplyTaskUpdate", testStoreApplyTaskUpdate},
+	{"ApplyTaskUpdateSeqDedup", testStoreApplyTaskUpdateSeqDedup},
+	{"RemoveJobs", testStoreRemoveJobs},
+	{"Workers", testStoreWorkers},
+	{"Secrets", testStoreSecrets},
+	{"Templates", testStoreTemplates},
+	{"Version", testStoreVersion},
+}
+
+func TestStoreConformance(t *testing.T) {
+	for _, backend := range storeBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			for _, tc := range storeTests {
+				t.Run(tc.name, func(t *testing.T) {
+					s := backend.open(t)
+					defer s.Close()
+					tc.run(t, s)
+				})
+			}
+		})
+	}
+}
+
+// addPendingJob stores a new pending job
+func addPendingJob(t *testing.T, s Store, id string) *models.Job {
+	t.Helper()
+	job := models.NewJob(id, models.JobStatusPending, "submitted", time.Now())
+	job.Command = "echo " + id
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("AddJob(%s): %v", id, err)
+	}
+	return job
+}
+
+// startAttempt schedules a job's next attempt on a worker
+func startAttempt(t *testing.T, s Store, jobID, taskID, workerID string) *models.Task {
+	t.Helper()
+	task := &models.Task{ID: taskID, JobID: jobID, WorkerID: workerID, Status: models.JobStatusScheduled, CreatedAt: time.Now()}
+	if _, err := s.StartAttempt(jobID, task, "scheduled"); err != nil {
+		t.Fatalf("StartAttempt(%s): %v", jobID, err)
+	}
+	return task
+}
+
+// reportTask applies a worker status update the way ReportTaskStatus does,
+// discarding updates whose sequence number was already applied
+func reportTask(s Store, taskID string, status models.JobStatus, seq uint64) (*models.Job, *models.Task, error) {
+	return s.ApplyTaskUpdate(taskID, status, "reported", func(j *models.Job, t *models.Task) error {
+		if seq <= t.StatusSeq {
+			return errStaleUpdate
+		}
+		t.Status = status
+		t.StatusSeq = seq
+		return nil
+	})
+}
+
+// finishJob runs a pending job to completion in a single attempt
+func finishJob(t *testing.T, s Store, jobID string) {
+	t.Helper()
+	taskID := jobID + "-task"
+	startAttempt(t, s, jobID, taskID, "w1")
+	for i, status := range []models.JobStatus{models.JobStatusRunning, models.JobStatusCompleted} {
+		if _, _, err := reportTask(s, taskID, status, uint64(i+1)); err != nil {
+			t.Fatalf("report %s for %s: %v", status, jobID, err)
+		}
+	}
+}
+
+func testStoreJobCRUD(t *testing.T, s Store) {
+	job := addPendingJob(t, s, "j1")
+	if err := s.AddJob(job); !errors.Is(err, ErrJobExists) {
+		t.Fatalf("AddJob of a taken ID = %v, want ErrJobExists", err)
+	}
+
+	got, ok := s.GetJob("j1")
+	if !ok || got.Command != "echo j1" || got.Status != models.JobStatusPending {
+		t.Fatalf("GetJob = %+v, %v", got, ok)
+	}
+	got.Command = "changed"
+	if again, _ := s.GetJob("j1"); again.Command != "echo j1" {
+		t.Fatalf("changing a returned job changed the store: %q", again.Command)
+	}
+	if _, ok := s.GetJob("missing"); ok {
+		t.Fatal("GetJob of an unknown ID succeeded")
+	}
+
+	updated, err := s.UpdateJob("j1", func(j *models.Job) error {
+		j.Output = "hello"
+		return nil
+	})
+	if err != nil || updated.Output != "hello" {
+		t.Fatalf("UpdateJob = %+v, %v", updated, err)
+	}
+	abort := errors.New("abort")
+	if _, err := s.UpdateJob("j1", func(j *models.Job) error {
+		j.Output = "lost"
+		return abort
+	}); !errors.Is(err, abort) {
+		t.Fatalf("UpdateJob with a failing fn = %v, want %v", err, abort)
+	}
+	if got, _ := s.GetJob("j1"); got.Output != "hello" {
+		t.Fatalf("aborted update was stored: output %q", got.Output)
+	}
+	if _, err := s.UpdateJob("missing", func(*models.Job) error { return nil }); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("UpdateJob of an unknown ID = %v, want ErrJobNotFound", err)
+	}
+
+	addPendingJob(t, s, "j2")
+	startAttempt(t, s, "j2", "t2", "w1")
+	if n := len(s.GetAllJobs()); n != 2 {
+		t.Fatalf("GetAllJobs returned %d jobs, want 2", n)
+	}
+	pending := s.GetPendingJobs()
+	if len(pending) != 1 || pending[0].ID != "j1" {
+		t.Fatalf("GetPendingJobs = %v, want only j1", pending)
+	}
+}
+
+func testStoreCompareAndSwapJob(t *testing.T, s Store) {
+	addPendingJob(t, s, "j1")
+
+	if _, err := s.CompareAndSwapJob("j1", models.JobStatusPending, func(j *models.Job) error {
+		j.Output = "swapped"
+		return nil
+	}); err != nil {
+		t.Fatalf("CompareAndSwapJob in the expected status: %v", err)
+	}
+	_, err := s.CompareAndSwapJob("j1", models.JobStatusScheduled, func(j *models.Job) error {
+		j.Output = "lost"
+		return nil
+	})
+	if !errors.Is(err, ErrStatusConflict) {
+		t.Fatalf("CompareAndSwapJob in another status = %v, want ErrStatusConflict", err)
+	}
+	if got, _ := s.GetJob("j1"); got.Output != "swapped" {
+		t.Fatalf("conflicting swap was stored: output %q", got.Output)
+	}
+	if _, err := s.CompareAndSwapJob("missing", models.JobStatusPending, func(*models.Job) error { return nil }); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("CompareAndSwapJob of an unknown ID = %v, want ErrJobNotFound", err)
+	}
+}
+
+func testStoreTransitionJob(t *testing.T, s Store) {
+	addPendingJob(t, s, "j1")
+
+	job, err := s.TransitionJob("j1", models.JobStatusCancelled, "cancelled", nil)
+	if err != nil || job.Status != models.JobStatusCancelled {
+		t.Fatalf("TransitionJob = %+v, %v", job, err)
+	}
+	var transitionErr *models.TransitionError
+	if _, err := s.TransitionJob("j1", models.JobStatusRunning, "again", nil); !errors.As(err, &transitionErr) {
+		t.Fatalf("illegal TransitionJob = %v, want a TransitionError", err)
+	}
+	if got, _ := s.GetJob("j1"); got.Status != models.JobStatusCancelled || len(got.History) != 2 {
+		t.Fatalf("after an illegal transition the job is %s with %d history entries", got.Status, len(got.History))
+	}
+}
+
+func testStoreStartAttempt(t *testing.T, s Store) {
+	addPendingJob(t, s, "j1")
+
+	task := &models.Task{ID: "t1", JobID: "j1", WorkerID: "w1", Status: models.JobStatusScheduled}
+	job, err := s.StartAttempt("j1", task, "scheduled")
+	if err != nil {
+		t.Fatalf("StartAttempt: %v", err)
+	}
+	if job.Status != models.JobStatusScheduled || job.WorkerID != "w1" || job.CurrentTaskID() != "t1" {
+		t.Fatalf("StartAttempt returned %+v", job)
+	}
+	if task.Attempt != 1 {
+		t.Fatalf("first attempt numbered %d", task.Attempt)
+	}
+
+	// A scheduled job can't get a second attempt
+	var transitionErr *models.TransitionError
+	second := &models.Task{ID: "t2", JobID: "j1", WorkerID: "w2", Status: models.JobStatusScheduled}
+	if _, err := s.StartAttempt("j1", second, "scheduled"); !errors.As(err, &transitionErr) {
+		t.Fatalf("StartAttempt of a scheduled job = %v, want a TransitionError", err)
+	}
+	if _, ok := s.GetTask("t2"); ok {
+		t.Fatal("rejected attempt was stored")
+	}
+
+	// Requeued, it gets the next attempt number
+	if _, _, err := s.ApplyTaskUpdate("t1", models.JobStatusPending, "interrupted", func(j *models.Job, t *models.Task) error {
+		t.Status = models.JobStatusInterrupted
+		return nil
+	}); err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	if _, err := s.StartAttempt("j1", second, "rescheduled"); err != nil {
+		t.Fatalf("StartAttempt after requeue: %v", err)
+	}
+	if second.Attempt != 2 {
+		t.Fatalf("second attempt numbered %d", second.Attempt)
+	}
+
+	tasks := s.GetTasksForJob("j1")
+	if len(tasks) != 2 || tasks[0].ID != "t1" || tasks[1].ID != "t2" {
+		t.Fatalf("GetTasksForJob = %v, want t1, t2", tasks)
+	}
+	if active := s.GetActiveTasksForWorker("w2"); len(active) != 1 || active[0].ID != "t2" {
+		t.Fatalf("GetActiveTasksForWorker(w2) = %v, want t2", active)
+	}
+	if active := s.GetActiveTasksForWorker("w1"); len(active) != 0 {
+		t.Fatalf("GetActiveTasksForWorker(w1) = %v, want none", active)
+	}
+	if _, err := s.StartAttempt("missing", &models.Task{ID: "t3"}, "scheduled"); !errors.Is(err, ErrJobNotFound) {
+		t.Fatalf("StartAttempt of an unknown job = %v, want ErrJobNotFound", err)
+	}
+}
+
+func testStoreApplyTaskUpdate(t *testing.T, s Store) {
+	addPendingJob(t, s, "j1")
+	startAttempt(t, s, "j1", "t1", "w1")
+
+	job, task, err := reportTask(s, "t1", models.JobStatusRunning, 1)
+	if err != nil {
+		t.Fatalf("ApplyTaskUpdate: %v", err)
+	}
+	if job.Status != models.JobStatusRunning || task.Status != models.JobStatusRunning {
+		t.Fatalf("after RUNNING the job is %s and the task %s", job.Status, task.Status)
+	}
+
+	// An illegal job transition aborts the whole update
+	var transitionErr *models.TransitionError
+	if _, _, err := reportTask(s, "t1", models.JobStatusScheduled, 2); !errors.As(err, &transitionErr) {
+		t.Fatalf("illegal ApplyTaskUpdate = %v, want a TransitionError", err)
+	}
+	if got, _ := s.GetTask("t1"); got.Status != models.JobStatusRunning || got.StatusSeq != 1 {
+		t.Fatalf("aborted update changed the task: %s, seq %d", got.Status, got.StatusSeq)
+	}
+
+	// Only the current attempt moves its job
+	if _, _, err := s.ApplyTaskUpdate("t1", models.JobStatusPending, "interrupted", func(j *models.Job, t *models.Task) error {
+		t.Status = models.JobStatusInterrupted
+		return nil
+	}); err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	startAttempt(t, s, "j1", "t2", "w2")
+	job, task, err = reportTask(s, "t1", models.JobStatusFailed, 3)
+	if err != nil {
+		t.Fatalf("report for an old attempt: %v", err)
+	}
+	if task.Status != models.JobStatusFailed || job.Status != models.JobStatusScheduled {
+		t.Fatalf("old attempt's report left the task %s and moved the job to %s", task.Status, job.Status)
+	}
+
+	if _, _, err := reportTask(s, "missing", models.JobStatusRunning, 1); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("ApplyTaskUpdate of an unknown task = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func testStoreApplyTaskUpdateSeqDedup(t *testing.T, s Store) {
+	addPendingJob(t, s, "j1")
+	startAttempt(t, s, "j1", "t1", "w1")
+
+	if _, _, err := reportTask(s, "t1", models.JobStatusRunning, 1); err != nil {
+		t.Fatalf("first report: %v", err)
+	}
+	if _, _, err := reportTask(s, "t1", models.JobStatusCompleted, 2); err != nil {
+		t.Fatalf("second report: %v", err)
+	}
+
+	// Redelivered and reordered updates see the stored sequence number
+	for _, seq := range []uint64{1, 2} {
+		if _, _, err := reportTask(s, "t1", models.JobStatusRunning, seq); !errors.Is(err, errStaleUpdate) {
+			t.Fatalf("replay of seq %d = %v, want errStaleUpdate", seq, err)
+		}
+	}
+	job, _ := s.GetJob("j1")
+	task, _ := s.GetTask("t1")
+	if job.Status != models.JobStatusCompleted || task.Status != models.JobStatusCompleted || task.StatusSeq != 2 {
+		t.Fatalf("after replays the job is %s and the task %s at seq %d", job.Status, task.Status, task.StatusSeq)
+	}
+}
+
+func testStoreRemoveJobs(t *testing.T, s Store) {
+	addPendingJob(t, s, "done")
+	finishJob(t, s, "done")
+	addPendingJob(t, s, "pending")
+
+	n, err := s.RemoveJobs("done", "pending", "missing")
+	if err != nil || n != 1 {
+		t.Fatalf("RemoveJobs = %d, %v; want 1", n, err)
+	}
+	if _, ok := s.GetJob("done"); ok {
+		t.Fatal("finished job still stored")
+	}
+	if _, ok := s.GetTask("done-task"); ok {
+		t.Fatal("removed job's attempt still stored")
+	}
+	if _, ok := s.GetJob("pending"); !ok {
+		t.Fatal("unfinished job was removed")
+	}
+	if n, err := s.RemoveJobs("done"); err != nil || n != 0 {
+		t.Fatalf("removing again = %d, %v; want 0", n, err)
+	}
+}
+
+func testStoreWorkers(t *testing.T, s Store) {
+	if err := s.RegisterWorker(&models.Worker{ID: "w1", Address: "host:1", TotalCPU: 4000, LastHeartbeat: time.Now()}); err != nil {
+		t.Fatalf("RegisterWorker: %v", err)
+	}
+	if err := s.RegisterWorker(&models.Worker{ID: "w2", Address: "host:2", LastHeartbeat: time.Now().Add(-2 * heartbeatTimeout)}); err != nil {
+		t.Fatalf("RegisterWorker: %v", err)
+	}
+
+	if w, ok := s.GetWorker("w1"); !ok || w.Address != "host:1" {
+		t.Fatalf("GetWorker = %+v, %v", w, ok)
+	}
+	if n := len(s.GetAllWorkers()); n != 2 {
+		t.Fatalf("GetAllWorkers returned %d workers, want 2", n)
+	}
+	if healthy := s.GetHealthyWorkers(); len(healthy) != 1 || healthy[0].ID != "w1" {
+		t.Fatalf("GetHealthyWorkers = %v, want only w1", healthy)
+	}
+
+	prev, err := s.UpdateWorkerHeartbeat("w2", &models.Worker{UsedCPU: 500})
+	if err != nil || time.Since(prev.LastHeartbeat) < heartbeatTimeout {
+		t.Fatalf("UpdateWorkerHeartbeat returned %+v, %v; want the stale worker", prev, err)
+	}
+	if w, _ := s.GetWorker("w2"); w.UsedCPU != 500 || time.Since(w.LastHeartbeat) >= heartbeatTimeout {
+		t.Fatalf("heartbeat not applied: %+v", w)
+	}
+	if _, err := s.UpdateWorkerHeartbeat("missing", nil); !errors.Is(err, ErrWorkerNotFound) {
+		t.Fatalf("UpdateWorkerHeartbeat of an unknown worker = %v, want ErrWorkerNotFound", err)
+	}
+
+	if _, err := s.UpdateWorker("w1", func(w *models.Worker) error {
+		w.Draining = true
+		return nil
+	}); err != nil {
+		t.Fatalf("UpdateWorker: %v", err)
+	}
+	if w, _ := s.GetWorker("w1"); !w.Draining {
+		t.Fatal("UpdateWorker not applied")
+	}
+	if _, err := s.UpdateWorker("missing", func(*models.Worker) error { return nil }); !errors.Is(err, ErrWorkerNotFound) {
+		t.Fatalf("UpdateWorker of an unknown worker = %v, want ErrWorkerNotFound", err)
+	}
+
+	if removed, err := s.RemoveWorker("w1"); err != nil || !removed {
+		t.Fatalf("RemoveWorker = %v, %v", removed, err)
+	}
+	if removed, err := s.RemoveWorker("w1"); err != nil || removed {
+		t.Fatalf("RemoveWorker again = %v, %v; want false", removed, err)
+	}
+	if _, ok := s.GetWorker("w1"); ok {
+		t.Fatal("removed worker still stored")
+	}
+}
+
+func testStoreSecrets(t *testing.T, s Store) {
+	if err := s.PutSecret(&models.Secret{Name: "db", Sealed: []byte("v1")}); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+	if err := s.PutSecret(&models.Secret{Name: "db", Sealed: []byte("v2")}); err != nil {
+		t.Fatalf("PutSecret replacing: %v", err)
+	}
+	if secret, ok := s.GetSecret("db"); !ok || string(secret.Sealed) != "v2" {
+		t.Fatalf("GetSecret = %+v, %v", secret, ok)
+	}
+	if n := len(s.GetAllSecrets()); n != 1 {
+		t.Fatalf("GetAllSecrets returned %d secrets, want 1", n)
+	}
+	if removed, err := s.RemoveSecret("db"); err != nil || !removed {
+		t.Fatalf("RemoveSecret = %v, %v", removed, err)
+	}
+	if removed, err := s.RemoveSecret("db"); err != nil || removed {
+		t.Fatalf("RemoveSecret again = %v, %v; want false", removed, err)
+	}
+	if _, ok := s.GetSecret("db"); ok {
+		t.Fatal("removed secret still stored")
+	}
+}
+
+func testStoreTemplates(t *testing.T, s Store) {
+	template := &models.JobTemplate{
+		Name:   "tile",
+		Params: []models.TemplateParam{{Name: "x", Type: "int", Required: true}},
+		Job:    &models.Job{Command: "render {{x}}"},
+	}
+	if err := s.PutTemplate(template); err != nil {
+		t.Fatalf("PutTemplate: %v", err)
+	}
+	got, ok := s.GetTemplate("tile")
+	if !ok || got.Job.Command != "render {{x}}" || len(got.Params) != 1 {
+		t.Fatalf("GetTemplate = %+v, %v", got, ok)
+	}
+	got.Job.Command = "changed"
+	if again, _ := s.GetTemplate("tile"); again.Job.Command != "render {{x}}" {
+		t.Fatalf("changing a returned template changed the store: %q", again.Job.Command)
+	}
+	if n := len(s.GetAllTemplates()); n != 1 {
+		t.Fatalf("GetAllTemplates returned %d templates, want 1", n)
+	}
+	if removed, err := s.RemoveTemplate("tile"); err != nil || !removed {
+		t.Fatalf("RemoveTemplate = %v, %v", removed, err)
+	}
+	if removed, err := s.RemoveTemplate("tile"); err != nil || removed {
+		t.Fatalf("RemoveTemplate again = %v, %v; want false", removed, err)
+	}
+}
+
+func testStoreVersion(t *testing.T, s Store) {
+	before := s.Version()
+	addPendingJob(t, s, "j1")
+	after := s.Version()
+	if after <= before {
+		t.Fatalf("Version went from %d to %d after a change", before, after)
+	}
+
+	events, next, err := s.Watch(before, func(Event) bool { return true }, time.Second, nil)
+	if err != nil || len(events) == 0 || next != after {
+		t.Fatalf("Watch(%d) = %d events, %d, %v; want the new job at %d", before, len(events), next, err, after)
+	}
+}
+
+// TestBoltStoreReload checks that everything but liveness data survives
+// closing and reopening the database
+func TestBoltStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "titan.db")
+	s, err := OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("OpenBoltStore: %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		addPendingJob(t, s, fmt.Sprintf("j%d", i))
+	}
+	finishJob(t, s, "j1")
+	startAttempt(t, s, "j2", "t2", "w1")
+	if _, err := s.RemoveJobs("j1"); err != nil {
+		t.Fatalf("RemoveJobs: %v", err)
+	}
+	if err := s.RegisterWorker(&models.Worker{ID: "w1", Address: "host:1", Labels: map[string]string{"gpu": "yes"}}); err != nil {
+		t.Fatalf("RegisterWorker: %v", err)
+	}
+	if _, err := s.UpdateWorkerHeartbeat("w1", &models.Worker{UsedCPU: 500}); err != nil {
+		t.Fatalf("UpdateWorkerHeartbeat: %v", err)
+	}
+	if err := s.PutSecret(&models.Secret{Name: "db", Sealed: []byte("sealed")}); err != nil {
+		t.Fatalf("PutSecret: %v", err)
+	}
+	if err := s.PutTemplate(&models.JobTemplate{Name: "tile", Job: &models.Job{Command: "render"}}); err != nil {
+		t.Fatalf("PutTemplate: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	s, err = OpenBoltStore(path)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	defer s.Close()
+
+	if _, ok := s.GetJob("j1"); ok {
+		t.Error("removed job came back")
+	}
+	if job, ok := s.GetJob("j2"); !ok || job.Status != models.JobStatusScheduled || job.CurrentTaskID() != "t2" {
+		t.Errorf("scheduled job reloaded as %+v, %v", job, ok)
+	}
+	if job, ok := s.GetJob("j3"); !ok || job.Status != models.JobStatusPending || job.Command != "echo j3" {
+		t.Errorf("pending job reloaded as %+v, %v", job, ok)
+	}
+	if task, ok := s.GetTask("t2"); !ok || task.Attempt != 1 || task.WorkerID != "w1" {
+		t.Errorf("task reloaded as %+v, %v", task, ok)
+	}
+	if active := s.GetActiveTasksForWorker("w1"); len(active) != 1 {
+		t.Errorf("worker has %d active tasks after reload, want 1", len(active))
+	}
+	if w, ok := s.GetWorker("w1"); !ok || w.Labels["gpu"] != "yes" || w.UsedCPU != 0 {
+		t.Errorf("worker reloaded as %+v, %v; heartbeats are not persisted", w, ok)
+	}
+	if secret, ok := s.GetSecret("db"); !ok || string(secret.Sealed) != "sealed" {
+		t.Errorf("secret reloaded as %+v, %v", secret, ok)
+	}
+	if template, ok := s.GetTemplate("tile"); !ok || template.Job.Command != "render" {
+		t.Errorf("template reloaded as %+v, %v", template, ok)
+	}
+
+	// The reloaded store keeps working
+	finishJob(t, s, "j3")
+	if job, _ := s.GetJob("j3"); job.Status != models.JobStatusCompleted {
+		t.Errorf("job run after reload is %s", job.Status)
+	}
+}
@@ -0,0 +1,370 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"titan/pkg/jobtemplate"
+	"titan/pkg/logger"
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+)
+
+// PutTemplate creates or replaces a job template. The template is rendered
+// with its defaults, and sample values for required parameters, so one
+// that can't produce a valid job is rejected here rather than on use.
+func (s *Server) PutTemplate(ctx context.Context, req pb.PutTemplateRequest, resp *pb.JobTemplate) (err error) {
+	defer func() { s.audit(ctx, "PutTemplate", req.Name, req, err) }()
+	caller, _, err := s.authorizeClient(ctx, "PutTemplate")
+	if err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+	if err := jobtemplate.ValidateName(req.Name); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+	}
+	params, err := templateParams(req.Params)
+	if err != nil {
+		return err
+	}
+	if err := checkTemplateJob(req.Job, params); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template := &models.JobTemplate{
+		Name:        req.Name,
+		Description: req.Description,
+		Params:      params,
+		Job:         templateJob(req.Job),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if prev, ok := s.store.GetTemplate(req.Name); ok {
+		template.CreatedAt = prev.CreatedAt
+	}
+	if caller != nil {
+		template.UpdatedBy = caller.Name
+	}
+	if err := s.store.PutTemplate(template); err != nil {
+		return err
+	}
+
+	logger.Info("Template stored", "name", req.Name, "by", template.UpdatedBy)
+
+	*resp = jobTemplateResponse(template)
+	return nil
+}
+
+// GetTemplate returns a single job template
+func (s *Server) GetTemplate(ctx context.Context, req pb.GetTemplateRequest, resp *pb.JobTemplate) error {
+	if _, _, err := s.authorizeClient(ctx, "GetTemplate"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+
+	template, ok := s.store.GetTemplate(req.Name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, req.Name)
+	}
+	*resp = jobTemplateResponse(template)
+	return nil
+}
+
+// ListTemplates returns every job template
+func (s *Server) ListTemplates(ctx context.Context, req pb.ListTemplatesRequest, resp *pb.ListTemplatesResponse) error {
+	if _, _, err := s.authorizeClient(ctx, "ListTemplates"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+
+	list := s.store.GetAllTemplates()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+
+	resp.Templates = make([]pb.JobTemplate, len(list))
+	for i, template := range list {
+		resp.Templates[i] = jobTemplateResponse(template)
+	}
+	return nil
+}
+
+// DeleteTemplate deletes a job template. Jobs already submitted from it
+// are not affected.
+func (s *Server) DeleteTemplate(ctx context.Context, req pb.DeleteTemplateRequest, resp *pb.JobTemplate) (err error) {
+	defer func() { s.audit(ctx, "DeleteTemplate", req.Name, req, err) }()
+	if _, _, err := s.authorizeClient(ctx, "DeleteTemplate"); err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+
+	template, ok := s.store.GetTemplate(req.Name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, req.Name)
+	}
+	if _, err := s.store.RemoveTemplate(req.Name); err != nil {
+		return err
+	}
+
+	logger.Info("Template deleted", "name", req.Name)
+
+	*resp = jobTemplateResponse(template)
+	return nil
+}
+
+// SubmitFromTemplate renders a job template with the given parameter
+// values and submits the result like any other job
+func (s *Server) SubmitFromTemplate(ctx context.Context, req pb.SubmitFromTemplateRequest, resp *pb.JobResponse) (err error) {
+	defer func() { s.audit(ctx, "SubmitFromTemplate", resp.JobId, req, err) }()
+	caller, _, err := s.authorizeClient(ctx, "SubmitFromTemplate")
+	if err != nil {
+		return err
+	}
+	if err := s.checkLeader(); err != nil {
+		return err
+	}
+
+	template, ok := s.store.GetTemplate(req.Name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, req.Name)
+	}
+	jobReq, err := renderTemplate(template, req.Params)
+	if err != nil {
+		return err
+	}
+	job, err := s.submitJob(jobReq, caller, "submitted from template "+template.Name)
+	if err != nil {
+		return err
+	}
+
+	*resp = pb.JobResponse{
+		JobId:  job.ID,
+		Status: string(job.Status),
+	}
+	return nil
+}
+
+// templateParams validates the parameters of a template and converts them
+// for the store, with canonical types and defaults
+func templateParams(in []pb.TemplateParam) ([]models.TemplateParam, error) {
+	out := make([]models.TemplateParam, len(in))
+	seen := make(map[string]bool)
+	for i, p := range in {
+		if err := jobtemplate.ValidateParamName(p.Name); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArgument, err)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("%w: parameter %s is declared twice", ErrInvalidArgument, p.Name)
+		}
+		seen[p.Name] = true
+		typ, err := jobtemplate.NormalizeType(p.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parameter %s: %v", ErrInvalidArgument, p.Name, err)
+		}
+		def := p.DefaultValue
+		if p.Required {
+			if def != "" {
+				return nil, fmt.Errorf("%w: parameter %s is required, so it can't have a default", ErrInvalidArgument, p.Name)
+			}
+		} else if def, err = jobtemplate.CheckValue(typ, def); err != nil {
+			return nil, fmt.Errorf("%w: parameter %s: default %v; give a valid default or make it required", ErrInvalidArgument, p.Name, err)
+		}
+		out[i] = models.TemplateParam{Name: p.Name, Type: typ, Default: def, Required: p.Required, Description: p.Description}
+	}
+	return out, nil
+}
+
+// checkTemplateJob checks that a template's job only refers to declared
+// parameters and renders to a valid job request
+func checkTemplateJob(job pb.JobRequest, params []models.TemplateParam) error {
+	declared := make(map[string]bool)
+	for _, p := range params {
+		declared[p.Name] = true
+	}
+	var unknown []string
+	forEachTemplated(&job, func(s string) string {
+		for _, name := range jobtemplate.Placeholders(s) {
+			if !declared[name] {
+				unknown = append(unknown, "{{"+name+"}}")
+			}
+		}
+		return s
+	})
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: job refers to undeclared parameters %s", ErrInvalidArgument, strings.Join(unknown, ", "))
+	}
+
+	samples := make(map[string]string)
+	for _, p := range params {
+		samples[p.Name] = p.Default
+		if p.Required {
+			samples[p.Name] = sampleValue(p.Type)
+		}
+	}
+	if err := validateJobRequest(expandRequest(job, samples)); err != nil {
+		return fmt.Errorf("template job is invalid: %w", err)
+	}
+	return nil
+}
+
+// sampleValue is a valid value of a parameter type
+func sampleValue(typ string) string {
+	switch typ {
+	case jobtemplate.TypeInt, jobtemplate.TypeFloat:
+		return "0"
+	case jobtemplate.TypeBool:
+		return "false"
+	}
+	return "x"
+}
+
+// renderTemplate checks the parameter values given for a template and
+// returns the job request it renders to. Every problem with the values is
+// reported at once.
+func renderTemplate(template *models.JobTemplate, given map[string]string) (pb.JobRequest, error) {
+	var problems []string
+	declared := make(map[string]bool)
+	values := make(map[string]string)
+	for _, p := range template.Params {
+		declared[p.Name] = true
+		value, ok := given[p.Name]
+		switch {
+		case !ok && p.Required:
+			problems = append(problems, fmt.Sprintf("missing required parameter %s (%s)", p.Name, p.Type))
+			continue
+		case !ok:
+			value = p.Default
+		}
+		value, err := jobtemplate.CheckValue(p.Type, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("parameter %s: %v", p.Name, err))
+			continue
+		}
+		values[p.Name] = value
+	}
+	var unknown []string
+	for name := range given {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		problems = append(problems, fmt.Sprintf("unknown parameter %s", name))
+	}
+	if len(problems) > 0 {
+		return pb.JobRequest{}, fmt.Errorf("%w: template %s: %s", ErrInvalidArgument, template.Name, strings.Join(problems, "; "))
+	}
+	return expandRequest(templateRequest(template.Job), values), nil
+}
+
+// expandRequest returns a copy of a job request with its placeholders
+// replaced by values
+func expandRequest(req pb.JobRequest, values map[string]string) pb.JobRequest {
+	req.Argv = append([]string(nil), req.Argv...)
+	req.Env = copyStringMap(req.Env)
+	req.Labels = copyStringMap(req.Labels)
+	req.Constraints = copyStringMap(req.Constraints)
+	forEachTemplated(&req, func(s string) string {
+		return jobtemplate.Expand(s, values)
+	})
+	return req
+}
+
+// forEachTemplated replaces each string of a job request that may hold
+// placeholders by fn's result: the command, argv, and the values of env,
+// labels and constraints
+func forEachTemplated(req *pb.JobRequest, fn func(string) string) {
+	req.Command = fn(req.Command)
+	for i := range req.Argv {
+		req.Argv[i] = fn(req.Argv[i])
+	}
+	for _, m := range []map[string]string{req.Env, req.Labels, req.Constraints} {
+		for k, v := range m {
+			m[k] = fn(v)
+		}
+	}
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// templateJob converts the job of a template request for the store
+func templateJob(req pb.JobRequest) *models.Job {
+	job := &models.Job{
+		Command:     req.Command,
+		Argv:        req.Argv,
+		Env:         req.Env,
+		Labels:      req.Labels,
+		CPU:         req.Resources.CpuMillicores,
+		Memory:      req.Resources.MemoryMb,
+		Priority:    req.Priority,
+		MaxRetries:  req.MaxRetries,
+		Timeout:     time.Duration(req.TimeoutSeconds) * time.Second,
+		Constraints: req.Constraints,
+	}
+	for _, ref := range req.Secrets {
+		job.Secrets = append(job.Secrets, models.SecretRef{Name: ref.Name, Env: ref.Env, File: ref.File})
+	}
+	return job
+}
+
+// templateRequest converts the stored job of a template back into a
+// request
+func templateRequest(job *models.Job) pb.JobRequest {
+	return pb.JobRequest{
+		Command:        job.Command,
+		Argv:           job.Argv,
+		Env:            job.Env,
+		Labels:         job.Labels,
+		Resources:      pb.ResourceRequirements{CpuMillicores: job.CPU, MemoryMb: job.Memory},
+		Secrets:        secretRefsResponse(job.Secrets),
+		Priority:       job.Priority,
+		MaxRetries:     job.MaxRetries,
+		TimeoutSeconds: int64(job.Timeout / time.Second),
+		Constraints:    job.Constraints,
+	}
+}
+
+// jobTemplateResponse builds the RPC view of a template
+func jobTemplateResponse(template *models.JobTemplate) pb.JobTemplate {
+	resp := pb.JobTemplate{
+		Name:        template.Name,
+		Description: template.Description,
+		Params:      make([]pb.TemplateParam, len(template.Params)),
+		Job:         templateRequest(template.Job),
+		UpdatedBy:   template.UpdatedBy,
+		CreatedAt:   template.CreatedAt.Unix(),
+		UpdatedAt:   template.UpdatedAt.Unix(),
+	}
+	for i, p := range template.Params {
+		resp.Params[i] = pb.TemplateParam{
+			Name:         p.Name,
+			Type:         p.Type,
+			DefaultValue: p.Default,
+			Required:     p.Required,
+			Description:  p.Description,
+		}
+	}
+	return resp
+}
@@ -0,0 +1,254 @@
+// Package managerclient provides a client for a manager or a cluster of
+// manager replicas, over net/rpc or gRPC
+package managerclient
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"strings"
+	"sync"
+	"time"
+
+	"titan/pkg/auth"
+	"titan/pkg/certs"
+	"titan/pkg/logger"
+	pb "titan/pkg/proto"
+)
+
+const (
+	minReconnectBackoff = 500 * time.Millisecond
+	maxReconnectBackoff = 30 * time.Second
+	dialTimeout         = 3 * time.Second
+
+	// maxRedirects bounds how often one call follows not-leader replies
+	maxRedirects = 5
+	// electionWait is how long to wait before retrying a call that was
+	// rejected while no leader was known
+	electionWait = 250 * time.Millisecond
+)
+
+// Client is a client for the manager that survives manager restarts
+// and follows the leader of a replicated manager cluster. The connection is
+// dialed lazily and dropped whenever a call fails at the transport level;
+// redials try every known manager and are spaced out with exponential
+// backoff once all of them have failed.
+type Client struct {
+	addrs []string
+	opts  Options
+
+	mu       sync.Mutex
+	client   conn
+	addr     string // Address of the current connection
+	next     int    // Index in addrs of the next manager to dial
+	leader   string // Leader address named by a follower, dialed first
+	backoff  time.Duration
+	nextDial time.Time
+	closed   bool
+}
+
+// Options configures a Client
+type Options struct {
+	Transport Transport // Defaults to TransportRPC
+
+	// TLS, if set, is used for every connection. Managers must present a
+	// manager certificate.
+	TLS *tls.Config
+
+	// Token is an API token sent to the manager, if set
+	Token string
+}
+
+// New creates a net/rpc client for the managers at addrs. Any replica will
+// do: the client finds the leader on its own.
+func New(addrs ...string) *Client {
+	return NewWithOptions(Options{}, addrs...)
+}
+
+// NewWithOptions creates a client for the managers at addrs
+func NewWithOptions(opts Options, addrs ...string) *Client {
+	if opts.Transport == "" {
+		opts.Transport = TransportRPC
+	}
+	if opts.TLS != nil {
+		opts.TLS = certs.RequirePeer(opts.TLS, certs.RequireKind(auth.KindManager))
+	}
+	return &Client{
+		addrs:   addrs,
+		opts:    opts,
+		backoff: minReconnectBackoff,
+	}
+}
+
+// SplitAddrs parses a comma-separated list of manager addresses
+func SplitAddrs(list string) []string {
+	var addrs []string
+	for _, addr := range strings.Split(list, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// Call invokes a manager RPC, reconnecting first if needed. Calls rejected
+// by a replica that is not the leader are retried against the leader.
+func (c *Client) Call(method string, args any, reply any) error {
+	var err error
+	for redirect := 0; redirect <= maxRedirects; redirect++ {
+		var client conn
+		client, err = c.conn()
+		if err != nil {
+			return err
+		}
+
+		err = client.Call(method, args, reply)
+		var serverErr rpc.ServerError
+		if err != nil && !errors.As(err, &serverErr) {
+			// The connection is broken; the next call will redial
+			c.drop(client)
+			return err
+		}
+
+		leader, notLeader := pb.ParseNotLeader(err)
+		if !notLeader {
+			return err
+		}
+		known := leader != ""
+		if c.opts.Transport == TransportGRPC {
+			// Replicas name the leader by its net/rpc address, which is of
+			// no use here; find it by trying the other replicas instead
+			leader = ""
+		}
+		c.redirect(client, leader)
+		if !known {
+			time.Sleep(electionWait)
+		}
+	}
+	return err
+}
+
+// Close closes the connection and prevents further calls
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	if c.client == nil {
+		return nil
+	}
+	err := c.client.Close()
+	c.client = nil
+	return err
+}
+
+// conn returns the current connection, dialing a new one if allowed
+func (c *Client) conn() (conn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, rpc.ErrShutdown
+	}
+	if c.client != nil {
+		return c.client, nil
+	}
+	if wait := time.Until(c.nextDial); wait > 0 {
+		return nil, fmt.Errorf("manager unreachable, retrying in %s", wait.Round(time.Millisecond))
+	}
+
+	candidates := make([]string, 0, len(c.addrs)+1)
+	if c.leader != "" {
+		candidates = append(candidates, c.leader)
+	}
+	for i := range c.addrs {
+		candidates = append(candidates, c.addrs[(c.next+i)%len(c.addrs)])
+	}
+	c.leader = ""
+
+	var lastErr error
+	for _, addr := range candidates {
+		client, err := c.dial(addr)
+		if err != nil {
+			lastErr = err
+			c.next++
+			continue
+		}
+		logger.Info("Connected to manager", "address", addr, "transport", c.opts.Transport)
+		c.client = client
+		c.addr = addr
+		c.backoff = minReconnectBackoff
+		return c.client, nil
+	}
+
+	c.nextDial = time.Now().Add(c.backoff)
+	c.backoff *= 2
+	if c.backoff > maxReconnectBackoff {
+		c.backoff = maxReconnectBackoff
+	}
+	return nil, fmt.Errorf("failed to connect to manager: %w", lastErr)
+}
+
+// dial connects to the manager at addr over the configured transport
+func (c *Client) dial(addr string) (conn, error) {
+	if c.opts.Transport == TransportGRPC {
+		return dialGRPC(addr, c.opts.TLS, c.opts.Token)
+	}
+	var netConn net.Conn
+	var err error
+	if c.opts.TLS != nil {
+		dialer := &net.Dialer{Timeout: dialTimeout}
+		netConn, err = tls.DialWithDialer(dialer, "tcp", addr, c.opts.TLS)
+	} else {
+		netConn, err = net.DialTimeout("tcp", addr, dialTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	client := rpc.NewClient(netConn)
+	if c.opts.Token != "" {
+		// net/rpc has no call metadata; the token authenticates the connection
+		var resp pb.AuthResponse
+		if err := client.Call("AuthService.Authenticate", pb.AuthRequest{Token: c.opts.Token}, &resp); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// drop discards a broken connection unless it was already replaced. The
+// next dial starts with the following manager.
+func (c *Client) drop(client conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client != client {
+		return
+	}
+	client.Close()
+	c.client = nil
+	c.next++
+	logger.Warn("Lost connection to manager", "address", c.addr)
+}
+
+// redirect abandons a connection to a replica that is not the leader, so
+// the next call dials leader, or the following manager if it is unknown
+func (c *Client) redirect(client conn, leader string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client != client {
+		return
+	}
+	client.Close()
+	c.client = nil
+	if leader == "" || leader == c.addr {
+		c.next++
+	} else {
+		c.leader = leader
+	}
+	logger.Info("Manager is not the leader, redirecting", "address", c.addr, "leader", leader)
+}
@@ -0,0 +1,151 @@
+package managerclient
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/rpc"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	pb "titan/pkg/proto"
+	"titan/pkg/proto/titanpb"
+)
+
+// Transport selects the protocol a Client speaks to the manager
+type Transport string
+
+const (
+	TransportRPC  Transport = "rpc"  // net/rpc with gob
+	TransportGRPC Transport = "grpc" // gRPC with the messages in titanpb
+)
+
+// ParseTransport parses a --transport flag value
+func ParseTransport(name string) (Transport, error) {
+	switch t := Transport(strings.ToLower(name)); t {
+	case TransportRPC, TransportGRPC:
+		return t, nil
+	}
+	return "", fmt.Errorf("unknown transport %q (want rpc or grpc)", name)
+}
+
+// conn is a connection to one manager. *rpc.Client is the net/rpc one.
+type conn interface {
+	Call(method string, args any, reply any) error
+	Close() error
+}
+
+// grpcConn makes net/rpc style calls, such as "ManagerService.SubmitJob"
+// with the plain request and reply structs, over a gRPC connection
+type grpcConn struct {
+	cc *grpc.ClientConn
+}
+
+func dialGRPC(addr string, tlsConfig *tls.Config, token string) (*grpcConn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithBlock(),
+		grpc.FailOnNonTempDialError(true),
+	}
+	if token != "" {
+		opts = append(opts, grpc.WithPerRPCCredentials(tokenCredentials(token)))
+	}
+	cc, err := grpc.DialContext(ctx, addr, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &grpcConn{cc: cc}, nil
+}
+
+func (g *grpcConn) Call(method string, args any, reply any) error {
+	service, name, ok := strings.Cut(method, ".")
+	if !ok {
+		return rpc.ServerError("rpc: service/method request ill-formed: " + method)
+	}
+	desc := titanpb.File_titan_proto.Services().ByName(protoreflect.Name(service))
+	if desc == nil {
+		return rpc.ServerError("rpc: can't find service " + method)
+	}
+	m := desc.Methods().ByName(protoreflect.Name(name))
+	if m == nil {
+		return rpc.ServerError("rpc: can't find method " + method)
+	}
+
+	in, err := newMessage(m.Input())
+	if err != nil {
+		return err
+	}
+	out, err := newMessage(m.Output())
+	if err != nil {
+		return err
+	}
+	if err := pb.Convert(in, args); err != nil {
+		return err
+	}
+
+	fullMethod := fmt.Sprintf("/%s/%s", desc.FullName(), m.Name())
+	if err := g.cc.Invoke(context.Background(), fullMethod, in, out); err != nil {
+		return callError(err)
+	}
+	return pb.Convert(reply, out)
+}
+
+func (g *grpcConn) Close() error {
+	return g.cc.Close()
+}
+
+// tokenCredentials sends an API token with every gRPC call
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity allows tokens over plaintext so development
+// clusters without TLS can use them; use TLS wherever the network is shared
+func (t tokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// newMessage returns an empty generated message of the given type
+func newMessage(desc protoreflect.MessageDescriptor) (protoreflect.ProtoMessage, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(desc.FullName())
+	if err != nil {
+		return nil, fmt.Errorf("no generated type for %s: %w", desc.FullName(), err)
+	}
+	return mt.New().Interface(), nil
+}
+
+// callError turns an error returned by the manager into an rpc.ServerError,
+// as net/rpc would, so Client.Call handles both transports alike. Errors
+// from the connection itself are left as they are.
+func callError(err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch st.Code() {
+	case codes.Unavailable:
+		// Also the code of a not-leader reply, which is an answer
+		if _, notLeader := pb.ParseNotLeader(rpc.ServerError(st.Message())); notLeader {
+			return rpc.ServerError(st.Message())
+		}
+		return err
+	case codes.Canceled, codes.DeadlineExceeded:
+		return err
+	}
+	return rpc.ServerError(st.Message())
+}
@@ -0,0 +1,87 @@
+package managerclient
+
+import (
+	"fmt"
+	"time"
+
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+)
+
+const (
+	// maxWaitFailures is how many consecutive failed calls WaitForJob
+	// tolerates, e.g. while the manager restarts or a new leader is elected
+	maxWaitFailures = 10
+
+	// waitRetryDelay spaces out retries after a failed call
+	waitRetryDelay = time.Second
+)
+
+// WaitForJob blocks until a job reaches a terminal status and returns its
+// final status, including output. It watches the manager for changes to
+// the job rather than polling it.
+func (c *Client) WaitForJob(jobID string) (pb.JobStatusResponse, error) {
+	failures := 0
+	retry := func(err error) error {
+		failures++
+		if failures >= maxWaitFailures {
+			return err
+		}
+		time.Sleep(waitRetryDelay)
+		return nil
+	}
+
+	var status pb.JobStatusResponse
+	fetch := true
+	var version uint64
+	for {
+		if fetch {
+			status = pb.JobStatusResponse{}
+			if err := c.Call("ManagerService.GetJobStatus", pb.JobStatusRequest{JobId: jobID}, &status); err != nil {
+				if err := retry(err); err != nil {
+					return status, fmt.Errorf("failed to get job status: %w", err)
+				}
+				continue
+			}
+			failures = 0
+			if models.JobStatus(status.Status).IsTerminal() {
+				return status, nil
+			}
+			version = status.ResourceVersion
+			fetch = false
+		}
+
+		req := pb.WatchRequest{
+			ResourceVersion: version,
+			JobIds:          []string{jobID},
+		}
+		var resp pb.WatchResponse
+		if err := c.Call("ManagerService.WatchJobs", req, &resp); err != nil {
+			// The manager may have changed; start again from its state
+			if err := retry(err); err != nil {
+				return status, fmt.Errorf("failed to watch job: %w", err)
+			}
+			fetch = true
+			continue
+		}
+		failures = 0
+
+		if resp.Expired {
+			fetch = true
+			continue
+		}
+		version = resp.ResourceVersion
+		for _, event := range resp.Events {
+			if event.Job == nil {
+				continue
+			}
+			if event.Type == "DELETED" {
+				return status, fmt.Errorf("job %s was deleted before it finished", jobID)
+			}
+			if models.JobStatus(event.Job.Status).IsTerminal() {
+				// Fetch the full status, which includes the output
+				fetch = true
+			}
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrUnknownStatus is returned for a status string that is not a job status
+var ErrUnknownStatus = errors.New("unknown job status")
+
+// TransitionError is returned when a job is asked to move to a state that
+// is not reachable from its current one
+type TransitionError struct {
+	JobID string
+	From  JobStatus
+	To    JobStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("job %s: illegal transition %s -> %s", e.JobID, e.From, e.To)
+}
+
+// StatusTransition records a single change of a job's status
+type StatusTransition struct {
+	From   JobStatus
+	To     JobStatus
+	At     time.Time
+	Reason string
+}
+
+// jobTransitions lists the legal next states for each job status.
+// Terminal states have no entry.
+var jobTransitions = map[JobStatus][]JobStatus{
+	JobStatusPending: {
+		JobStatusScheduled,
+		JobStatusCancelled,
+	},
+	JobStatusScheduled: {
+		JobStatusRunning,
+		JobStatusPending, // Dispatch failed or worker shut down
+		JobStatusCompleted,
+		JobStatusFailed,
+		JobStatusCancelled,
+		JobStatusTimedOut,
+		JobStatusLost,
+	},
+	JobStatusRunning: {
+		JobStatusPending, // Worker shut down before the task finished
+		JobStatusCompleted,
+		JobStatusFailed,
+		JobStatusCancelled,
+		JobStatusTimedOut,
+		JobStatusLost,
+	},
+	JobStatusLost: {
+		JobStatusPending, // Rescheduled
+		JobStatusCancelled,
+	},
+}
+
+// ParseJobStatus converts a status string to a JobStatus
+func ParseJobStatus(s string) (JobStatus, error) {
+	status := JobStatus(s)
+	switch status {
+	case JobStatusPending, JobStatusScheduled, JobStatusRunning,
+		JobStatusCompleted, JobStatusFailed, JobStatusCancelled,
+		JobStatusTimedOut, JobStatusLost:
+		return status, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownStatus, s)
+}
+
+// IsTerminal reports whether no further transitions are possible
+func (s JobStatus) IsTerminal() bool {
+	switch s {
+	case JobStatusCompleted, JobStatusFailed, JobStatusCancelled, JobStatusTimedOut:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo reports whether a job may move from s to next
+func (s JobStatus) CanTransitionTo(next JobStatus) bool {
+	for _, allowed := range jobTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Transition moves the job to a new status and records it in the job's
+// history. It returns a *TransitionError if the move is not allowed.
+func (j *Job) Transition(to JobStatus, reason string, at time.Time) error {
+	if !j.Status.CanTransitionTo(to) {
+		return &TransitionError{JobID: j.ID, From: j.Status, To: to}
+	}
+	j.History = append(j.History, StatusTransition{
+		From:   j.Status,
+		To:     to,
+		At:     at,
+		Reason: reason,
+	})
+	j.Status = to
+	j.UpdatedAt = at
+	return nil
+}
+
+// NewJob creates a job in its initial status, recording why it exists
+func NewJob(id string, initial JobStatus, reason string, at time.Time) *Job {
+	return &Job{
+		ID:     id,
+		Status: initial,
+		History: []StatusTransition{{
+			To:     initial,
+			At:     at,
+			Reason: reason,
+		}},
+		CreatedAt: at,
+		UpdatedAt: at,
+	}
+}
+
+// Clone returns a deep copy of the job
+func (j *Job) Clone() *Job {
+	c := *j
+	c.Env = cloneStringMap(j.Env)
+	c.Secrets = append([]SecretRef(nil), j.Secrets...)
+	c.Argv = append([]string(nil), j.Argv...)
+	c.Labels = cloneStringMap(j.Labels)
+	c.Constraints = cloneStringMap(j.Constraints)
+	c.TaskIDs = append([]string(nil), j.TaskIDs...)
+	c.History = append([]StatusTransition(nil), j.History...)
+	return &c
+}
+
+// CommandLine returns the job's command as it is shown to users: the
+// shell command, or the argument vector joined by spaces
+func (j *Job) CommandLine() string {
+	if len(j.Argv) > 0 {
+		return strings.Join(j.Argv, " ")
+	}
+	return j.Command
+}
+
+// CurrentTaskID returns the ID of the job's latest attempt, if any
+func (j *Job) CurrentTaskID() string {
+	if len(j.TaskIDs) == 0 {
+		return ""
+	}
+	return j.TaskIDs[len(j.TaskIDs)-1]
+}
+
+// Clone returns a deep copy of the task
+func (t *Task) Clone() *Task {
+	c := *t
+	c.Argv = append([]string(nil), t.Argv...)
+	c.Env = cloneStringMap(t.Env)
+	return &c
+}
+
+// Clone returns a deep copy of the worker
+func (w *Worker) Clone() *Worker {
+	c := *w
+	c.Labels = cloneStringMap(w.Labels)
+	return &c
+}
+
+// Clone returns a deep copy of the secret
+func (s *Secret) Clone() *Secret {
+	c := *s
+	c.Sealed = append([]byte(nil), s.Sealed...)
+	return &c
+}
+
+// Clone returns a deep copy of the template
+func (t *JobTemplate) Clone() *JobTemplate {
+	c := *t
+	c.Params = append([]TemplateParam(nil), t.Params...)
+	if t.Job != nil {
+		c.Job = t.Job.Clone()
+	}
+	return &c
+}
+
+// cloneStringMap copies a map, preserving nil
+func cloneStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -11,6 +11,13 @@ const (
 	JobStatusRunning   JobStatus = "RUNNING"
 	JobStatusCompleted JobStatus = "COMPLETED"
 	JobStatusFailed    JobStatus = "FAILED"
+	JobStatusCancelled JobStatus = "CANCELLED"
+	JobStatusTimedOut  JobStatus = "TIMED_OUT"
+	JobStatusLost      JobStatus = "LOST" // Worker disappeared while running it
+
+	// JobStatusInterrupted is reported by a worker for a task it stopped
+	// while shutting down. The manager requeues the job.
+	JobStatusInterrupted JobStatus = "INTERRUPTED"
 )
 
 // Job represents a unit of work to be executed
@@ -18,14 +25,31 @@ type Job struct {
 	ID        string
 	Command   string
 	Env       map[string]string
+	Secrets   []SecretRef       // Delivered to each task at dispatch
+	Labels    map[string]string // User-defined, for filtering
+	Owner     string            // Identity that submitted the job, if known
 	Status    JobStatus
 	WorkerID  string // Assigned worker
 	Output    string
 	ExitCode  int32
+	TaskIDs   []string // One task per attempt, oldest first
+	History   []StatusTransition
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	Argv        []string          // Run without a shell instead of Command
+	CPU         int32             // Millicores the job needs
+	Memory      int64             // MB the job needs
+	Priority    int32             // Higher runs first
+	Timeout     time.Duration     // Kill the task after this long; 0 means no limit
+	MaxRetries  int32             // Times a failed or timed out job is requeued
+	Retries     int32             // Failed attempts requeued so far
+	Constraints map[string]string // Labels a worker must have to run the job
 }
 
+// MaxJobRetries bounds how often a job may ask to be requeued after failing
+const MaxJobRetries = 100
+
 // WorkerStatus represents the health state of a worker
 type WorkerStatus string
 
@@ -43,18 +67,68 @@ type Worker struct {
 	UsedCPU          int32
 	UsedMemory       int64
 	Status           WorkerStatus
+	Draining         bool // Runs its current tasks but gets no new ones
+	Revoked          bool // Barred from registering and receiving tasks
+	RevokedReason    string
+	Labels           map[string]string // Matched against job constraints
 	LastHeartbeat    time.Time
 	RegisteredAt     time.Time
 }
 
-// Task represents a running instance of a job on a worker
+// Task represents a single attempt at running a job on a worker
 type Task struct {
-	ID       string
-	JobID    string
-	WorkerID string
-	Command  string
-	Env      map[string]string
-	Status   JobStatus
-	Output   string
-	ExitCode int32
+	ID         string
+	JobID      string
+	WorkerID   string
+	Attempt    int // 1 for the first attempt
+	Command    string
+	Argv       []string
+	Env        map[string]string
+	Timeout    time.Duration
+	Status     JobStatus
+	Output     string
+	ExitCode   int32
+	StatusSeq  uint64 // Sequence number of the last applied status update
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// SecretRef names a secret a job needs and how its tasks receive it:
+// either in an environment variable or in a file
+type SecretRef struct {
+	Name string
+	Env  string // Environment variable to set
+	File string // Path relative to the task's secrets directory
+}
+
+// Secret is a named value kept by the manager. Only its encrypted value is
+// ever stored.
+type Secret struct {
+	Name      string
+	Sealed    []byte // Value encrypted with the manager's secrets key
+	UpdatedBy string // Identity that last set the value, if known
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// JobTemplate is a named job with typed parameters. Each submission from
+// it starts from a copy of Job with the parameters filled in.
+type JobTemplate struct {
+	Name        string
+	Description string
+	Params      []TemplateParam
+	Job         *Job   // Only the spec fields are set; may hold {{param}} placeholders
+	UpdatedBy   string // Identity that last set the template, if known
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// TemplateParam is a parameter of a job template
+type TemplateParam struct {
+	Name        string
+	Type        string // string, int, float or bool
+	Default     string // Used when no value is given
+	Required    bool   // A value must be given; Default is unused
+	Description string
 }
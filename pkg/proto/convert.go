@@ -0,0 +1,114 @@
+package proto
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Convert copies src into dst, where one is a plain struct from this
+// package and the other the generated gRPC message of the same name in
+// titanpb. Fields are matched by name, so both sides must stay in step
+// with proto/titan.proto; a field missing on either side is an error
+// rather than silently dropped. dst must be a pointer.
+func Convert(dst, src any) error {
+	d := reflect.ValueOf(dst)
+	if d.Kind() != reflect.Pointer || d.IsNil() {
+		return fmt.Errorf("convert: destination must be a non-nil pointer, got %T", dst)
+	}
+	if src == nil {
+		return fmt.Errorf("convert: nil source for %T", dst)
+	}
+	return convertValue(d.Elem(), reflect.ValueOf(src))
+}
+
+// convertValue copies src into dst, following pointers on either side so
+// that plain structs nest by value and generated messages by pointer
+func convertValue(dst, src reflect.Value) error {
+	for src.Kind() == reflect.Pointer || src.Kind() == reflect.Interface {
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		src = src.Elem()
+	}
+	if dst.Kind() == reflect.Pointer {
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return convertValue(dst.Elem(), src)
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		if src.Kind() != reflect.Struct {
+			return fmt.Errorf("convert: cannot copy %s into %s", src.Type(), dst.Type())
+		}
+		return convertStruct(dst, src)
+	case reflect.Slice:
+		if src.Kind() != reflect.Slice {
+			return fmt.Errorf("convert: cannot copy %s into %s", src.Type(), dst.Type())
+		}
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		out := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			if err := convertValue(out.Index(i), src.Index(i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+		return nil
+	case reflect.Map:
+		if src.Kind() != reflect.Map || !src.Type().ConvertibleTo(dst.Type()) {
+			return fmt.Errorf("convert: cannot copy %s into %s", src.Type(), dst.Type())
+		}
+		if src.IsNil() {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		out := reflect.MakeMapWithSize(dst.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), iter.Value())
+		}
+		dst.Set(out)
+		return nil
+	default:
+		if src.Kind() != dst.Kind() {
+			return fmt.Errorf("convert: cannot copy %s into %s", src.Type(), dst.Type())
+		}
+		dst.Set(src.Convert(dst.Type()))
+		return nil
+	}
+}
+
+// convertStruct copies the exported fields of src into the fields of dst
+// with the same names. Unexported fields, such as the generated messages'
+// internal state, are skipped.
+func convertStruct(dst, src reflect.Value) error {
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if _, ok := dst.Type().FieldByName(field.Name); !ok {
+			return fmt.Errorf("convert: %s has no field %s", dst.Type(), field.Name)
+		}
+	}
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		value := src.FieldByName(field.Name)
+		if !value.IsValid() {
+			return fmt.Errorf("convert: %s has no field %s", src.Type(), field.Name)
+		}
+		if err := convertValue(dst.Field(i), value); err != nil {
+			return fmt.Errorf("%s.%s: %w", dst.Type().Name(), field.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package proto
+
+import (
+	"errors"
+	"net/rpc"
+	"strings"
+)
+
+// notLeaderPrefix starts the message of every NotLeaderError, so clients
+// can recognise one after it has crossed the wire as a plain string
+const notLeaderPrefix = "not leader"
+
+// NotLeaderError is returned by a manager replica that is not the cluster
+// leader. The call had no effect and may be retried against the leader.
+type NotLeaderError struct {
+	Leader string // RPC address of the current leader, empty while unknown
+}
+
+func (e *NotLeaderError) Error() string {
+	if e.Leader == "" {
+		return notLeaderPrefix + "; leader unknown"
+	}
+	return notLeaderPrefix + "; leader is " + e.Leader
+}
+
+// ParseNotLeader reports whether err is a NotLeaderError returned by a
+// manager and, if so, the leader address it names
+func ParseNotLeader(err error) (leader string, ok bool) {
+	var serverErr rpc.ServerError
+	if !errors.As(err, &serverErr) || !strings.HasPrefix(string(serverErr), notLeaderPrefix) {
+		return "", false
+	}
+	if leader, found := strings.CutPrefix(string(serverErr), notLeaderPrefix+"; leader is "); found {
+		return leader, true
+	}
+	return "", true
+}
@@ -6,7 +6,22 @@ package proto
 type JobRequest struct {
 	Command   string
 	Env       map[string]string
+	Labels    map[string]string
 	Resources ResourceRequirements
+	Secrets   []SecretRef // Delivered to the task when it starts
+	Argv      []string    // Run without a shell; exclusive with Command
+
+	Priority       int32             // Higher runs first
+	MaxRetries     int32             // Times a failed or timed out job is requeued
+	TimeoutSeconds int64             // Kill the task after this long; 0 means no limit
+	Constraints    map[string]string // Labels a worker must have to run the job
+}
+
+// SecretRef names a secret a job needs. Exactly one of Env and File is set.
+type SecretRef struct {
+	Name string
+	Env  string // Environment variable to set
+	File string // Path relative to $TITAN_SECRETS_DIR
 }
 
 type ResourceRequirements struct {
@@ -24,25 +39,182 @@ type JobStatusRequest struct {
 }
 
 type JobStatusResponse struct {
-	JobId    string
-	Status   string
-	WorkerId string
-	Output   string
-	ExitCode int32
+	JobId     string
+	Command   string
+	Status    string
+	WorkerId  string
+	Output    string
+	ExitCode  int32
+	History   []StatusTransition
+	Attempts  []TaskAttempt
+	CreatedAt int64 // Unix timestamp
+	Labels    map[string]string
+
+	// ResourceVersion is the store version this view was read at, to
+	// watch for later changes from. Only set by GetJobStatus.
+	ResourceVersion uint64
+
+	Owner   string      // Identity that submitted the job, if known
+	Secrets []SecretRef // References only, never values
+
+	// The rest of the job's spec. Env is only set by GetJobStatus and
+	// full listings.
+	Env            map[string]string
+	Argv           []string
+	Resources      ResourceRequirements
+	Priority       int32
+	MaxRetries     int32
+	Retries        int32 // Failed attempts requeued so far
+	TimeoutSeconds int64
+	Constraints    map[string]string
+}
+
+// CancelJobRequest stops a job that has not finished yet
+type CancelJobRequest struct {
+	JobId  string
+	Reason string // Recorded in the job's history
+}
+
+type TaskAttempt struct {
+	TaskId      string
+	Attempt     int32
+	WorkerId    string
+	Status      string
+	ExitCode    int32
+	ScheduledAt int64 // Unix timestamp
+	StartedAt   int64 // Unix timestamp, 0 if never started
+	FinishedAt  int64 // Unix timestamp, 0 if not finished
 }
 
+type StatusTransition struct {
+	From   string
+	To     string
+	At     int64 // Unix timestamp
+	Reason string
+}
+
+// ListJobsRequest filters, orders and pages the job list. Empty filter
+// fields match every job.
 type ListJobsRequest struct {
-	// Empty
+	Statuses        []string // Any of these
+	WorkerId        string
+	Labels          map[string]string // All of these
+	Command         string            // Substring of the command
+	SubmittedAfter  int64             // Unix timestamp
+	SubmittedBefore int64             // Unix timestamp
+	OldestFirst     bool              // Default is newest first
+	PageSize        int32             // 0 means the server default
+	PageToken       string            // NextPageToken of the previous page
+	Summary         bool              // Omit output, history and attempts
 }
 
 type ListJobsResponse struct {
-	Jobs []JobStatusResponse
+	Jobs            []JobStatusResponse
+	NextPageToken   string // Empty on the last page
+	ResourceVersion uint64 // Store version the page was read at
+}
+
+// WatchRequest waits for job and worker changes after a resource version.
+// Empty filter fields match every job.
+type WatchRequest struct {
+	ResourceVersion uint64            // Return changes after this version
+	JobIds          []string          // Only these jobs
+	Labels          map[string]string // Only jobs with all of these labels
+	IncludeWorkers  bool              // Also return worker changes
+	TimeoutSeconds  int32             // 0 means the server default
+}
+
+// WatchResponse returns the changes found, which may be none if the
+// request timed out
+type WatchResponse struct {
+	Events          []WatchEvent
+	ResourceVersion uint64 // Pass in the next request
+
+	// Expired means changes after the requested version are no longer
+	// retained. Re-read current state and watch from its version.
+	Expired bool
+}
+
+type WatchEvent struct {
+	Type            string // ADDED, MODIFIED or DELETED
+	ResourceVersion uint64
+	Job             *JobStatusResponse    // Summary, without output
+	Worker          *WorkerStatusResponse // Only for worker changes
+}
+
+// HistoryRequest searches the archive of jobs evicted by retention.
+// Empty fields match every job.
+type HistoryRequest struct {
+	JobId           string
+	Status          string
+	Command         string // Substring of the command
+	SubmittedAfter  int64  // Unix timestamp
+	SubmittedBefore int64  // Unix timestamp
+	Limit           int32  // 0 means the server default
+}
+
+type HistoryResponse struct {
+	Jobs []ArchivedJob // Most recently archived first
+}
+
+type ArchivedJob struct {
+	Job        JobStatusResponse
+	ArchivedAt int64 // Unix timestamp
+}
+
+// AuditRequest searches the audit log. Zero fields don't filter.
+type AuditRequest struct {
+	Since  int64 // Unix timestamp
+	Until  int64 // Unix timestamp
+	Caller string
+	Method string // e.g. SubmitJob
+	Limit  int32  // 0 means the server default
+}
+
+type AuditResponse struct {
+	Entries []AuditEntry // Newest first
+}
+
+// AuditEntry records one cluster-mutating call
+type AuditEntry struct {
+	Time       int64  // Unix timestamp
+	Caller     string // Empty for anonymous calls
+	CallerKind string
+	Method     string
+	Target     string // ID of the job or worker acted on
+	Args       string // Request as JSON, secrets redacted
+	Result     string // OK, or the error's gRPC code
+	Error      string
+}
+
+// AuthRequest checks a token. Over net/rpc a valid token authenticates
+// the rest of the connection.
+type AuthRequest struct {
+	Token string // Empty to ask who the connection is authenticated as
+}
+
+type AuthResponse struct {
+	Name      string
+	Kind      string // client, worker or manager; empty if anonymous
+	ExpiresAt int64  // Unix timestamp; 0 if the credential doesn't expire
+	Groups    []string
+	Role      string // Role granted by the manager's policy; empty if none
 }
 
 type WorkerInfo struct {
-	WorkerId string
-	Address  string
-	Capacity ResourceCapacity
+	WorkerId     string
+	Address      string
+	Capacity     ResourceCapacity
+	RunningTasks []RunningTask
+	JoinToken    string            // Admits the worker if the manager requires join tokens
+	Labels       map[string]string // Matched against job constraints
+}
+
+// RunningTask describes a task a worker is executing when it (re)registers
+type RunningTask struct {
+	TaskId  string
+	JobId   string
+	Command string
 }
 
 type ResourceCapacity struct {
@@ -55,10 +227,16 @@ type RegistrationResponse struct {
 	Message  string
 }
 
+type DeregisterRequest struct {
+	WorkerId  string
+	JoinToken string // Proves WorkerId without a worker certificate
+}
+
 type HeartbeatRequest struct {
 	WorkerId     string
 	Timestamp    int64
 	CurrentUsage ResourceUsage
+	JoinToken    string // Proves WorkerId without a worker certificate
 }
 
 type ResourceUsage struct {
@@ -68,6 +246,7 @@ type ResourceUsage struct {
 
 type HeartbeatResponse struct {
 	Acknowledged bool
+	Reregister   bool // The manager does not know this worker
 }
 
 type TaskRequest struct {
@@ -75,6 +254,19 @@ type TaskRequest struct {
 	JobId   string
 	Command string
 	Env     map[string]string
+	Secrets []TaskSecret
+
+	Argv           []string // Run without a shell instead of Command
+	TimeoutSeconds int64    // Kill the task after this long; 0 means no limit
+}
+
+// TaskSecret is a secret value, sent to the worker only when its task
+// starts
+type TaskSecret struct {
+	Name  string
+	Env   string
+	File  string
+	Value []byte
 }
 
 type TaskResponse struct {
@@ -86,17 +278,143 @@ type StopTaskRequest struct {
 	TaskId string
 }
 
-type StopTaskResponse struct{
+type StopTaskResponse struct {
 	Stopped bool
 }
 
 type TaskStatusUpdate struct {
 	TaskId   string
+	JobId    string
 	Status   string
 	Output   string
 	ExitCode int32
+	Seq      uint64 // Per-task sequence number, starting at 1
+
+	WorkerId  string // Reporting worker; must be the task's
+	JoinToken string `json:"-"` // Proves WorkerId without a worker certificate; never persisted
 }
 
 type Ack struct {
-	Ok bool
+	Ok      bool
+	Message string // Why the request was rejected, if it was
+}
+
+// PutSecretRequest creates or replaces a secret
+type PutSecretRequest struct {
+	Name  string
+	Value []byte // Never returned by any call
+}
+
+type ListSecretsRequest struct {
+	// Empty
+}
+
+type ListSecretsResponse struct {
+	Secrets []SecretInfo // Sorted by name
+}
+
+type DeleteSecretRequest struct {
+	Name string
+}
+
+// SecretInfo describes a secret without its value
+type SecretInfo struct {
+	Name      string
+	UpdatedBy string // Identity that last set the value, if known
+	CreatedAt int64  // Unix timestamp
+	UpdatedAt int64  // Unix timestamp
+}
+
+// TemplateParam is a parameter of a job template. {{name}} in the
+// template job's command, argv, env values, label values and constraint
+// values is replaced by the parameter's value.
+type TemplateParam struct {
+	Name         string
+	Type         string // string (default), int, float or bool
+	DefaultValue string // Used when no value is given
+	Required     bool   // A value must be given; no default
+	Description  string
+}
+
+// PutTemplateRequest creates or replaces a job template
+type PutTemplateRequest struct {
+	Name        string
+	Description string
+	Params      []TemplateParam
+	Job         JobRequest // May contain {{param}} placeholders
+}
+
+// JobTemplate is a named job with parameters
+type JobTemplate struct {
+	Name        string
+	Description string
+	Params      []TemplateParam
+	Job         JobRequest
+	UpdatedBy   string // Identity that last set the template, if known
+	CreatedAt   int64  // Unix timestamp
+	UpdatedAt   int64  // Unix timestamp
+}
+
+type GetTemplateRequest struct {
+	Name string
+}
+
+type ListTemplatesRequest struct {
+	// Empty
+}
+
+type ListTemplatesResponse struct {
+	Templates []JobTemplate // Sorted by name
+}
+
+type DeleteTemplateRequest struct {
+	Name string
+}
+
+// SubmitFromTemplateRequest submits the job a template renders to
+type SubmitFromTemplateRequest struct {
+	Name   string
+	Params map[string]string // Parameter values, checked against their types
+}
+
+type ListWorkersRequest struct {
+	// Empty
+}
+
+type ListWorkersResponse struct {
+	Workers []WorkerStatusResponse
+}
+
+type GetWorkerRequest struct {
+	WorkerId string
+}
+
+// DrainWorkerRequest stops placing new tasks on a worker, or resumes it.
+// Tasks already on the worker keep running.
+type DrainWorkerRequest struct {
+	WorkerId string
+	Resume   bool // Accept new tasks again
+}
+
+// RevokeWorkerRequest bars a worker from registering and receiving tasks,
+// or lifts the ban. Tasks on a revoked worker are requeued.
+type RevokeWorkerRequest struct {
+	WorkerId string
+	Reason   string
+	Restore  bool // Let the worker register again
+}
+
+type WorkerStatusResponse struct {
+	WorkerId      string
+	Address       string
+	Status        string
+	Draining      bool // Gets no new tasks
+	Revoked       bool // Barred from registering and receiving tasks
+	RevokedReason string
+	Capacity      ResourceCapacity
+	Usage         ResourceUsage
+	LastHeartbeat int64 // Unix timestamp
+	RegisteredAt  int64 // Unix timestamp
+	RunningTasks  []string
+	Labels        map[string]string // Matched against job constraints
 }
@@ -0,0 +1,238 @@
+package rpcserver
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/gob"
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"time"
+
+	"titan/pkg/auth"
+	"titan/pkg/logger"
+)
+
+// handshakeTimeout bounds how long a TLS client may take to handshake
+const handshakeTimeout = 10 * time.Second
+
+// errShuttingDown is returned to net/rpc for requests read after Shutdown
+// has begun, which makes it close the connection
+var errShuttingDown = errors.New("server is shutting down")
+
+// Server serves a net/rpc server on a listener and supports graceful
+// shutdown: it stops accepting connections, lets in-flight calls finish
+// and then closes idle connections.
+type Server struct {
+	newServer func(ctx context.Context) *rpc.Server
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	active   int // Calls read but not yet answered
+	closing  bool
+	drained  chan struct{}
+}
+
+// New wraps a net/rpc server that serves every connection
+func New(server *rpc.Server) *Server {
+	return NewPerConn(func(context.Context) *rpc.Server { return server })
+}
+
+// NewPerConn serves each connection with its own net/rpc server, made by
+// newServer. Its context carries the caller if the connection is TLS and
+// the client presented a verified certificate, and is cancelled when the
+// connection closes.
+func NewPerConn(newServer func(ctx context.Context) *rpc.Server) *Server {
+	return &Server{
+		newServer: newServer,
+		conns:     make(map[net.Conn]struct{}),
+		drained:   make(chan struct{}),
+	}
+}
+
+// Serve accepts connections until Shutdown is called. It returns nil after
+// a graceful shutdown.
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosing() {
+				return nil
+			}
+			logger.Error("Accept error", "error", err)
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closing {
+			s.mu.Unlock()
+			conn.Close()
+			continue
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Shutdown stops accepting connections and waits for in-flight calls to
+// finish or for ctx to expire, whichever comes first. All connections are
+// closed before it returns.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closing = true
+	if s.listener != nil {
+		s.listener.Close()
+	}
+	if s.active == 0 {
+		close(s.drained)
+	}
+	s.mu.Unlock()
+
+	var err error
+	select {
+	case <-s.drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	return err
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		tlsConn.SetDeadline(time.Now().Add(handshakeTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			logger.Warn("TLS handshake failed", "remote", conn.RemoteAddr().String(), "error", err)
+			conn.Close()
+			return
+		}
+		tlsConn.SetDeadline(time.Time{})
+		state := tlsConn.ConnectionState()
+		if caller, ok := auth.FromTLS(&state); ok {
+			ctx = auth.NewContext(ctx, caller)
+		}
+	}
+
+	buf := bufio.NewWriter(conn)
+	s.newServer(ctx).ServeCodec(&drainingCodec{
+		server: s,
+		rwc:    conn,
+		dec:    gob.NewDecoder(conn),
+		enc:    gob.NewEncoder(buf),
+		encBuf: buf,
+		cancel: cancel,
+	})
+}
+
+func (s *Server) isClosing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closing
+}
+
+// begin records a newly read call. It fails once shutdown has started.
+func (s *Server) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing {
+		return false
+	}
+	s.active++
+	return true
+}
+
+// end records an answered call
+func (s *Server) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active--
+	if s.closing && s.active == 0 {
+		close(s.drained)
+	}
+}
+
+// drainingCodec is the standard net/rpc gob codec with call accounting
+type drainingCodec struct {
+	server *Server
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	closed bool
+
+	// cancel cancels the connection's context. net/rpc waits for calls in
+	// flight before it returns from ServeCodec, so it is called as soon as
+	// reading fails, to release calls that wait, such as watches.
+	cancel context.CancelFunc
+}
+
+func (c *drainingCodec) ReadRequestHeader(r *rpc.Request) error {
+	if err := c.dec.Decode(r); err != nil {
+		c.cancel()
+		return err
+	}
+	if !c.server.begin() {
+		return errShuttingDown
+	}
+	return nil
+}
+
+func (c *drainingCodec) ReadRequestBody(body any) error {
+	return c.dec.Decode(body)
+}
+
+// WriteResponse is called exactly once for every header that was read
+func (c *drainingCodec) WriteResponse(r *rpc.Response, body any) (err error) {
+	defer c.server.end()
+
+	if err = c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			// Gob couldn't encode the header. Should not happen, so if it does,
+			// shut down the connection to signal that the connection is broken.
+			c.Close()
+		}
+		return
+	}
+	if err = c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			// Was a gob problem encoding the body but the header has been written.
+			// Shut down the connection to signal that the connection is broken.
+			c.Close()
+		}
+		return
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *drainingCodec) Close() error {
+	if c.closed {
+		// Only call c.rwc.Close once; otherwise the semantics are undefined.
+		return nil
+	}
+	c.closed = true
+	return c.rwc.Close()
+}
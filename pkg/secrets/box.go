@@ -0,0 +1,135 @@
+// Package secrets encrypts job secrets so the manager can keep them at rest
+// without storing their values in plain text
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// keySize is the length of an AES-256 key
+const keySize = 32
+
+// MaxValueSize bounds the value of a single secret
+const MaxValueSize = 64 << 10
+
+// ErrDecrypt is returned for ciphertexts that were not sealed with the key
+// in use or were tampered with
+var ErrDecrypt = errors.New("secret can't be decrypted with this key")
+
+var (
+	// validName matches secret names: they appear in job specs and paths
+	validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+	// validEnvName matches environment variable names
+	validEnvName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Box seals and opens secret values with AES-256-GCM. The secret's name is
+// bound to its ciphertext, so a value can't be moved to another name.
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox creates a box for a 32-byte key
+func NewBox(key []byte) (*Box, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("secrets key must be %d bytes, got %d", keySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// LoadBox reads a hex encoded key from a file and creates a box for it
+func LoadBox(path string) (*Box, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("secrets key in %s is not hex encoded: %w", path, err)
+	}
+	box, err := NewBox(key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return box, nil
+}
+
+// NewKey returns a random key, hex encoded for a key file
+func NewKey() ([]byte, error) {
+	raw := make([]byte, keySize)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	return []byte(hex.EncodeToString(raw)), nil
+}
+
+// Seal encrypts the value of the secret called name. The result starts
+// with a random nonce.
+func (b *Box) Seal(name string, value []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize(), b.aead.NonceSize()+len(value)+b.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return b.aead.Seal(nonce, nonce, value, []byte(name)), nil
+}
+
+// Open decrypts a value sealed for the secret called name
+func (b *Box) Open(name string, sealed []byte) ([]byte, error) {
+	if len(sealed) < b.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:b.aead.NonceSize()], sealed[b.aead.NonceSize():]
+	value, err := b.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return value, nil
+}
+
+// ValidateName checks a secret name
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid secret name %q (want letters, digits, '.', '_' or '-', at most 128)", name)
+	}
+	return nil
+}
+
+// ValidateEnvName checks the name of the environment variable a secret is
+// delivered in. TITAN_ variables are reserved for the worker.
+func ValidateEnvName(name string) error {
+	if !validEnvName.MatchString(name) {
+		return fmt.Errorf("invalid environment variable name %q", name)
+	}
+	if strings.HasPrefix(name, "TITAN_") {
+		return fmt.Errorf("environment variable %s is reserved", name)
+	}
+	return nil
+}
+
+// ValidateFilePath checks the path a secret is delivered at, which is
+// relative to the task's secrets directory and may not leave it
+func ValidateFilePath(file string) error {
+	if file == "" || strings.Contains(file, `\`) || path.IsAbs(file) || path.Clean(file) != file ||
+		file == "." || file == ".." || strings.HasPrefix(file, "../") {
+		return fmt.Errorf("invalid secret file %q (want a clean relative path, like db/password)", file)
+	}
+	return nil
+}
@@ -2,39 +2,54 @@ package worker
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"net/rpc"
 	"os/exec"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"titan/pkg/logger"
 	"titan/pkg/models"
 	pb "titan/pkg/proto"
 )
 
+// taskWaitDelay bounds how long output pipes may stay open after a task exits
+const taskWaitDelay = time.Second
+
+// runningTask tracks a task process owned by the executor
+type runningTask struct {
+	jobID       string
+	command     string
+	cmd         *exec.Cmd
+	interrupted bool // Stopped because the worker is shutting down
+	timedOut    bool // Killed for running past its timeout
+}
+
 // Executor manages task execution
 type Executor struct {
 	mu            sync.RWMutex
-	tasks         map[string]*exec.Cmd
-	managerClient *rpc.Client
+	tasks         map[string]*runningTask
+	wg            sync.WaitGroup
+	outbox        *Outbox
 }
 
-// NewExecutor creates a new executor
-func NewExecutor(managerAddr string) (*Executor, error) {
-	client, err := rpc.Dial("tcp", managerAddr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to manager: %w", err)
-	}
-
+// NewExecutor creates a new executor that reports task status through outbox
+func NewExecutor(outbox *Outbox) *Executor {
 	return &Executor{
-		tasks:         make(map[string]*exec.Cmd),
-		managerClient: client,
-	}, nil
+		tasks:  make(map[string]*runningTask),
+		outbox: outbox,
+	}
 }
 
-// StartTask starts a new task process
-func (e *Executor) StartTask(taskID, jobID, command string, env map[string]string) error {
+// StartTask starts a new task process. Each secret is set in the task's
+// environment or written to a file under $TITAN_SECRETS_DIR, a private
+// directory removed when the task ends. Secret values are masked in the
+// task's output. A task given argv runs that program directly instead of
+// command through the shell. A task still running after a non-zero timeout
+// is killed and reported as TIMED_OUT.
+func (e *Executor) StartTask(taskID, jobID, command string, argv []string, env map[string]string, secrets []pb.TaskSecret, timeout time.Duration) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
@@ -43,7 +58,13 @@ func (e *Executor) StartTask(taskID, jobID, command string, env map[string]strin
 	}
 
 	// Create command
-	cmd := exec.Command("cmd", "/C", command) // Windows-specific shell
+	var cmd *exec.Cmd
+	if len(argv) > 0 {
+		cmd = exec.Command(argv[0], argv[1:]...)
+		command = strings.Join(argv, " ")
+	} else {
+		cmd = exec.Command("cmd", "/C", command) // Windows-specific shell
+	}
 	
 	// Set environment variables
 	cmd.Env = append(cmd.Env, fmt.Sprintf("TITAN_JOB_ID=%s", jobID))
@@ -51,30 +72,73 @@ func (e *Executor) StartTask(taskID, jobID, command string, env map[string]strin
 	for k, v := range env {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
+	for _, secret := range secrets {
+		if secret.Env != "" {
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", secret.Env, secret.Value))
+		}
+	}
+	secretsDir, err := writeSecretFiles(taskID, secrets)
+	if err != nil {
+		return err
+	}
+	if secretsDir != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("TITAN_SECRETS_DIR=%s", secretsDir))
+	}
 
 	// Capture output
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	
+	// Don't let child processes that inherited the output pipes keep Wait
+	// blocked after the task itself has exited or been killed
+	cmd.WaitDelay = taskWaitDelay
 
 	// Start process
 	if err := cmd.Start(); err != nil {
+		removeSecretsDir(secretsDir)
 		return fmt.Errorf("failed to start command: %w", err)
 	}
 
-	e.tasks[taskID] = cmd
+	task := &runningTask{jobID: jobID, command: command, cmd: cmd}
+	e.tasks[taskID] = task
+	e.outbox.ResetTask(taskID)
+	e.wg.Add(1)
+
+	var timer *time.Timer
+	if timeout > 0 {
+		timer = time.AfterFunc(timeout, func() {
+			e.mu.Lock()
+			defer e.mu.Unlock()
+			// A process that exited on its own just before the deadline
+			// keeps its real exit status
+			if err := cmd.Process.Kill(); err != nil {
+				logger.Error("Failed to kill timed out task", "task_id", taskID, "error", err)
+				return
+			}
+			task.timedOut = true
+			logger.Warn("Task timed out", "task_id", taskID, "timeout", timeout)
+		})
+	}
 
 	// Monitor process in background
 	go func() {
+		defer e.wg.Done()
+
 		// Report RUNNING status
-		e.reportStatus(taskID, models.JobStatusRunning, "", 0)
+		e.reportStatus(taskID, jobID, models.JobStatusRunning, "", 0)
 
 		// Wait for completion
 		err := cmd.Wait()
+		if timer != nil {
+			timer.Stop()
+		}
 
 		// Get exit code
 		exitCode := 0
-		if err != nil {
+		if errors.Is(err, exec.ErrWaitDelay) {
+			exitCode = cmd.ProcessState.ExitCode()
+		} else if err != nil {
 			if exitError, ok := err.(*exec.ExitError); ok {
 				if status, ok := exitError.Sys().(syscall.WaitStatus); ok {
 					exitCode = status.ExitStatus()
@@ -86,14 +150,23 @@ func (e *Executor) StartTask(taskID, jobID, command string, env map[string]strin
 			}
 		}
 
-		output := stdout.String() + stderr.String()
+		removeSecretsDir(secretsDir)
+		output := redactSecrets(stdout.String()+stderr.String(), secrets)
 		status := models.JobStatusCompleted
 		if exitCode != 0 {
 			status = models.JobStatusFailed
 		}
 
+		e.mu.RLock()
+		if task.interrupted {
+			status = models.JobStatusInterrupted
+		} else if task.timedOut {
+			status = models.JobStatusTimedOut
+		}
+		e.mu.RUnlock()
+
 		// Report final status
-		e.reportStatus(taskID, status, output, int32(exitCode))
+		e.reportStatus(taskID, jobID, status, output, int32(exitCode))
 
 		// Cleanup
 		e.mu.Lock()
@@ -109,32 +182,80 @@ func (e *Executor) StopTask(taskID string) error {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	cmd, exists := e.tasks[taskID]
+	task, exists := e.tasks[taskID]
 	if !exists {
 		return fmt.Errorf("task %s not found", taskID)
 	}
 
-	if err := cmd.Process.Kill(); err != nil {
+	if err := task.cmd.Process.Kill(); err != nil {
 		return fmt.Errorf("failed to kill process: %w", err)
 	}
 
 	return nil
 }
 
-// reportStatus sends a status update to the manager
-func (e *Executor) reportStatus(taskID string, status models.JobStatus, output string, exitCode int32) {
-	req := pb.TaskStatusUpdate{
+// RunningTasks returns the number of tasks currently executing
+func (e *Executor) RunningTasks() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.tasks)
+}
+
+// Snapshot describes the tasks currently executing, for re-registration
+func (e *Executor) Snapshot() []pb.RunningTask {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	tasks := make([]pb.RunningTask, 0, len(e.tasks))
+	for taskID, task := range e.tasks {
+		tasks = append(tasks, pb.RunningTask{
+			TaskId:  taskID,
+			JobId:   task.jobID,
+			Command: task.command,
+		})
+	}
+	return tasks
+}
+
+// Wait blocks until every task has finished and reported its final status,
+// or until the timeout expires. It returns true if all tasks finished.
+func (e *Executor) Wait(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// InterruptAll kills every running task. Each one is reported to the
+// manager as INTERRUPTED so it can be requeued elsewhere.
+func (e *Executor) InterruptAll() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for taskID, task := range e.tasks {
+		task.interrupted = true
+		if err := task.cmd.Process.Kill(); err != nil {
+			logger.Error("Failed to interrupt task", "task_id", taskID, "error", err)
+			continue
+		}
+		logger.Warn("Task interrupted", "task_id", taskID)
+	}
+}
+
+// reportStatus queues a status update for reliable delivery to the manager
+func (e *Executor) reportStatus(taskID, jobID string, status models.JobStatus, output string, exitCode int32) {
+	e.outbox.Enqueue(pb.TaskStatusUpdate{
 		TaskId:   taskID,
+		JobId:    jobID,
 		Status:   string(status),
 		Output:   output,
 		ExitCode: exitCode,
-	}
-
-	var resp pb.Ack
-	err := e.managerClient.Call("ManagerService.ReportTaskStatus", req, &resp)
-	if err != nil {
-		logger.Error("Failed to report task status", 
-			"task_id", taskID, 
-			"error", err)
-	}
+	})
 }
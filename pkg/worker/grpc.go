@@ -0,0 +1,28 @@
+package worker
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"titan/pkg/grpcserver"
+	"titan/pkg/proto/titanpb"
+)
+
+// RegisterGRPC registers the server's WorkerService with a gRPC server.
+// Only the task methods are served; the rest belong to the manager.
+func (s *Server) RegisterGRPC(server *grpc.Server) {
+	titanpb.RegisterWorkerServiceServer(server, &grpcWorkerService{s: s})
+}
+
+type grpcWorkerService struct {
+	titanpb.UnimplementedWorkerServiceServer
+	s *Server
+}
+
+func (g *grpcWorkerService) StartTask(ctx context.Context, in *titanpb.TaskRequest) (*titanpb.TaskResponse, error) {
+	return grpcserver.Handle(in, &titanpb.TaskResponse{}, g.s.StartTask, nil)
+}
+
+func (g *grpcWorkerService) StopTask(ctx context.Context, in *titanpb.StopTaskRequest) (*titanpb.StopTaskResponse, error) {
+	return grpcserver.Handle(in, &titanpb.StopTaskResponse{}, g.s.StopTask, nil)
+}
@@ -1,28 +1,34 @@
 package worker
 
 import (
-	"net/rpc"
 	"time"
 
 	"titan/pkg/logger"
+	"titan/pkg/managerclient"
 	pb "titan/pkg/proto"
 )
 
 // Heartbeater manages periodic heartbeats to the manager
 type Heartbeater struct {
 	workerID      string
-	managerClient *rpc.Client
+	joinToken     string
+	managerClient *managerclient.Client
 	interval      time.Duration
 	stopChan      chan struct{}
+	reregister    func() error
 }
 
-// NewHeartbeater creates a new heartbeater
-func NewHeartbeater(workerID string, client *rpc.Client, interval time.Duration) *Heartbeater {
+// NewHeartbeater creates a new heartbeater. reregister is called when the
+// manager no longer recognises the worker, e.g. after a manager restart.
+// joinToken, if set, is presented with every heartbeat.
+func NewHeartbeater(workerID, joinToken string, client *managerclient.Client, interval time.Duration, reregister func() error) *Heartbeater {
 	return &Heartbeater{
 		workerID:      workerID,
+		joinToken:     joinToken,
 		managerClient: client,
 		interval:      interval,
 		stopChan:      make(chan struct{}),
+		reregister:    reregister,
 	}
 }
 
@@ -58,11 +64,20 @@ func (h *Heartbeater) sendHeartbeat() {
 			UsedCpuMillicores: 100, // Mock usage
 			UsedMemoryMb:      256, // Mock usage
 		},
+		JoinToken: h.joinToken,
 	}
 	
 	var resp pb.HeartbeatResponse
-	err := h.managerClient.Call("ManagerService.Heartbeat", req, &resp)
+	err := h.managerClient.Call("WorkerService.Heartbeat", req, &resp)
 	if err != nil {
 		logger.Error("Failed to send heartbeat", "error", err)
+		return
+	}
+	
+	if resp.Reregister {
+		logger.Warn("Manager does not recognise worker, re-registering", "worker_id", h.workerID)
+		if err := h.reregister(); err != nil {
+			logger.Error("Failed to re-register with manager", "worker_id", h.workerID, "error", err)
+		}
 	}
 }
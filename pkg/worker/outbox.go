@@ -0,0 +1,302 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"titan/pkg/logger"
+	"titan/pkg/managerclient"
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+)
+
+const (
+	minRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff = 30 * time.Second
+)
+
+// outboxEntry is a status update waiting to be acknowledged by the manager
+type outboxEntry struct {
+	file   string
+	update pb.TaskStatusUpdate
+}
+
+// Outbox delivers task status updates to the manager reliably. Updates are
+// written to disk before delivery and removed only once the manager has
+// acknowledged them, so they survive both manager outages and worker
+// restarts. Each update carries a per-task sequence number that lets the
+// manager discard duplicates and stale updates.
+type Outbox struct {
+	dir    string
+	client *managerclient.Client
+
+	// Sent with every update so the manager can check who reports it.
+	// Neither is written to disk.
+	workerID  string
+	joinToken string
+
+	mu       sync.Mutex
+	pending  []*outboxEntry
+	nextFile uint64
+	taskSeq  map[string]uint64
+
+	notify   chan struct{}
+	stopChan chan struct{}
+	doneChan chan struct{}
+}
+
+// NewOutbox opens the outbox stored in dir, loading any updates left over
+// from a previous run. Updates are delivered as workerID, with joinToken
+// if it is set.
+func NewOutbox(dir string, client *managerclient.Client, workerID, joinToken string) (*Outbox, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+
+	o := &Outbox{
+		dir:       dir,
+		client:    client,
+		workerID:  workerID,
+		joinToken: joinToken,
+		nextFile:  1,
+		taskSeq:   make(map[string]uint64),
+		notify:    make(chan struct{}, 1),
+		stopChan:  make(chan struct{}),
+		doneChan:  make(chan struct{}),
+	}
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// load reads pending updates from disk in the order they were queued
+func (o *Outbox) load() error {
+	names, err := filepath.Glob(filepath.Join(o.dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("failed to read queued update: %w", err)
+		}
+		var update pb.TaskStatusUpdate
+		if err := json.Unmarshal(data, &update); err != nil {
+			logger.Warn("Discarding corrupt queued update", "file", name, "error", err)
+			os.Remove(name)
+			continue
+		}
+
+		o.pending = append(o.pending, &outboxEntry{file: name, update: update})
+		if update.Seq > o.taskSeq[update.TaskId] {
+			o.taskSeq[update.TaskId] = update.Seq
+		}
+		n, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), ".json"), 10, 64)
+		if err == nil && n >= o.nextFile {
+			o.nextFile = n + 1
+		}
+	}
+
+	if len(o.pending) > 0 {
+		logger.Info("Loaded undelivered status updates", "count", len(o.pending))
+	}
+	return nil
+}
+
+// ResetTask restarts the sequence for a task that is about to run
+func (o *Outbox) ResetTask(taskID string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.taskSeq, taskID)
+}
+
+// Enqueue assigns the update its sequence number, persists it and queues
+// it for delivery
+func (o *Outbox) Enqueue(update pb.TaskStatusUpdate) {
+	o.mu.Lock()
+	o.taskSeq[update.TaskId]++
+	update.Seq = o.taskSeq[update.TaskId]
+	entry := &outboxEntry{
+		file:   filepath.Join(o.dir, fmt.Sprintf("%020d.json", o.nextFile)),
+		update: update,
+	}
+	o.nextFile++
+	if err := writeFileAtomic(entry.file, update); err != nil {
+		// Still deliver from memory; only restart durability is lost
+		logger.Error("Failed to persist status update", "task_id", update.TaskId, "error", err)
+	}
+	o.pending = append(o.pending, entry)
+	o.mu.Unlock()
+
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Len returns the number of undelivered updates
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// Run delivers queued updates until Stop is called, retrying failures
+// with exponential backoff
+func (o *Outbox) Run() {
+	defer close(o.doneChan)
+
+	backoff := minRetryBackoff
+	for {
+		wait := time.Duration(0)
+		if !o.deliver() {
+			wait = backoff
+			backoff *= 2
+			if backoff > maxRetryBackoff {
+				backoff = maxRetryBackoff
+			}
+		} else {
+			backoff = minRetryBackoff
+		}
+
+		if wait == 0 && o.Len() > 0 {
+			continue
+		}
+
+		var retry <-chan time.Time
+		if wait > 0 {
+			retry = time.After(wait)
+		}
+		select {
+		case <-o.notify:
+		case <-retry:
+		case <-o.stopChan:
+			return
+		}
+	}
+}
+
+// Drain waits until every queued update has been delivered or the timeout
+// expires. It returns true if the outbox is empty.
+func (o *Outbox) Drain(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for o.Len() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return true
+}
+
+// Stop halts delivery. Undelivered updates stay on disk for the next run.
+func (o *Outbox) Stop() {
+	close(o.stopChan)
+	<-o.doneChan
+}
+
+// deliver makes one pass over the queue. Updates for a task are sent in
+// order: once one fails, later updates for the same task wait for the next
+// pass. It returns false if any delivery failed.
+func (o *Outbox) deliver() bool {
+	o.mu.Lock()
+	batch := make([]*outboxEntry, len(o.pending))
+	copy(batch, o.pending)
+	o.mu.Unlock()
+
+	blocked := make(map[string]bool)
+	ok := true
+	for _, entry := range batch {
+		if blocked[entry.update.TaskId] {
+			continue
+		}
+
+		update := entry.update
+		update.WorkerId = o.workerID
+		update.JoinToken = o.joinToken
+		var resp pb.Ack
+		err := o.client.Call("WorkerService.ReportTaskStatus", update, &resp)
+		if err == nil && !resp.Ok {
+			// The manager received the update but will never accept it
+			logger.Warn("Manager rejected task status, dropping it",
+				"task_id", entry.update.TaskId,
+				"status", entry.update.Status,
+				"seq", entry.update.Seq,
+				"reason", resp.Message)
+		}
+		if err != nil {
+			logger.Warn("Failed to report task status, will retry",
+				"task_id", entry.update.TaskId,
+				"status", entry.update.Status,
+				"seq", entry.update.Seq,
+				"error", err)
+			blocked[entry.update.TaskId] = true
+			ok = false
+			continue
+		}
+
+		o.remove(entry)
+	}
+	return ok
+}
+
+// remove drops an acknowledged update from the queue and from disk. Once a
+// task's final update is acknowledged its sequence is forgotten, so the map
+// doesn't grow with every task a long-lived worker runs.
+func (o *Outbox) remove(entry *outboxEntry) {
+	o.mu.Lock()
+	for i, e := range o.pending {
+		if e == entry {
+			o.pending = append(o.pending[:i], o.pending[i+1:]...)
+			break
+		}
+	}
+	update := entry.update
+	final := models.JobStatus(update.Status) != models.JobStatusRunning
+	if final && o.taskSeq[update.TaskId] == update.Seq {
+		delete(o.taskSeq, update.TaskId)
+	}
+	o.mu.Unlock()
+
+	if err := os.Remove(entry.file); err != nil && !os.IsNotExist(err) {
+		logger.Warn("Failed to remove delivered update", "file", entry.file, "error", err)
+	}
+}
+
+// writeFileAtomic writes v as JSON so that readers never see a partial file
+func writeFileAtomic(path string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,206 @@
+package worker
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"titan/pkg/managerclient"
+	"titan/pkg/models"
+	pb "titan/pkg/proto"
+)
+
+// fakeManager accepts status updates the way the manager does, ignoring
+// any whose sequence number it has already seen for the task
+type fakeManager struct {
+	mu       sync.Mutex
+	down     bool // Fail every report without reading it
+	loseAcks int  // Number of reports to apply but then fail
+	applied  []pb.TaskStatusUpdate
+	lastSeq  map[string]uint64
+}
+
+// startFakeManager serves a fake manager on a loopback port and returns a
+// client for it
+func startFakeManager(t *testing.T) (*fakeManager, *managerclient.Client) {
+	t.Helper()
+	m := &fakeManager{lastSeq: make(map[string]uint64)}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("WorkerService", m); err != nil {
+		t.Fatalf("RegisterName: %v", err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn)
+		}
+	}()
+
+	client := managerclient.New(ln.Addr().String())
+	t.Cleanup(func() { client.Close() })
+	return m, client
+}
+
+// ReportTaskStatus implements WorkerService.ReportTaskStatus
+func (m *fakeManager) ReportTaskStatus(update pb.TaskStatusUpdate, resp *pb.Ack) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.down {
+		return errors.New("manager unavailable")
+	}
+	if update.Seq > m.lastSeq[update.TaskId] {
+		m.lastSeq[update.TaskId] = update.Seq
+		m.applied = append(m.applied, update)
+	}
+	if m.loseAcks > 0 {
+		m.loseAcks--
+		return errors.New("connection reset")
+	}
+	*resp = pb.Ack{Ok: true}
+	return nil
+}
+
+// setDown makes the manager fail or accept every report
+func (m *fakeManager) setDown(down bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.down = down
+}
+
+// received returns the updates the manager applied, in order
+func (m *fakeManager) received() []pb.TaskStatusUpdate {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]pb.TaskStatusUpdate(nil), m.applied...)
+}
+
+// statusUpdate returns a status update for a task of job j1
+func statusUpdate(taskID string, status models.JobStatus) pb.TaskStatusUpdate {
+	return pb.TaskStatusUpdate{TaskId: taskID, JobId: "j1", Status: string(status)}
+}
+
+// queuedFiles returns the number of updates persisted in dir
+func queuedFiles(t *testing.T, dir string) int {
+	t.Helper()
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(names)
+}
+
+// TestOutboxSurvivesRestart checks that updates the manager never
+// acknowledged are delivered, in order, by the next run of the worker
+func TestOutboxSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	m, client := startFakeManager(t)
+	m.setDown(true)
+
+	o, err := NewOutbox(dir, client, "w1", "")
+	if err != nil {
+		t.Fatalf("NewOutbox: %v", err)
+	}
+	o.Enqueue(statusUpdate("t1", models.JobStatusRunning))
+	o.Enqueue(statusUpdate("t1", models.JobStatusCompleted))
+	if o.deliver() {
+		t.Fatal("delivered to a manager that is down")
+	}
+	if n := queuedFiles(t, dir); n != 2 {
+		t.Fatalf("%d updates on disk, want 2", n)
+	}
+
+	// The worker restarts while the manager is still down
+	o, err = NewOutbox(dir, client, "w1", "")
+	if err != nil {
+		t.Fatalf("reopen NewOutbox: %v", err)
+	}
+	if n := o.Len(); n != 2 {
+		t.Fatalf("reopened outbox holds %d updates, want 2", n)
+	}
+	o.Enqueue(statusUpdate("t2", models.JobStatusRunning))
+
+	m.setDown(false)
+	if !o.deliver() {
+		t.Fatal("delivery failed once the manager was back")
+	}
+	got := m.received()
+	want := []struct {
+		task   string
+		status models.JobStatus
+		seq    uint64
+	}{
+		{"t1", models.JobStatusRunning, 1},
+		{"t1", models.JobStatusCompleted, 2},
+		{"t2", models.JobStatusRunning, 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("manager received %d updates, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].TaskId != w.task || got[i].Status != string(w.status) || got[i].Seq != w.seq || got[i].WorkerId != "w1" {
+			t.Errorf("update %d = %+v, want %s %s at seq %d from w1", i, got[i], w.task, w.status, w.seq)
+		}
+	}
+	if n := queuedFiles(t, dir); n != 0 || o.Len() != 0 {
+		t.Errorf("%d updates on disk and %d queued after delivery", n, o.Len())
+	}
+}
+
+// TestOutboxSequence checks that redelivering an update whose ack was lost
+// doesn't apply it twice, and that a task's sequence is forgotten once its
+// final update is acknowledged
+func TestOutboxSequence(t *testing.T) {
+	m, client := startFakeManager(t)
+	o, err := NewOutbox(t.TempDir(), client, "w1", "")
+	if err != nil {
+		t.Fatalf("NewOutbox: %v", err)
+	}
+
+	o.ResetTask("t1")
+	o.Enqueue(statusUpdate("t1", models.JobStatusRunning))
+	m.mu.Lock()
+	m.loseAcks = 1
+	m.mu.Unlock()
+	if o.deliver() {
+		t.Fatal("delivery succeeded although the ack was lost")
+	}
+	if !o.deliver() {
+		t.Fatal("redelivery failed")
+	}
+	if got := m.received(); len(got) != 1 || got[0].Seq != 1 {
+		t.Fatalf("manager applied %+v, want RUNNING once at seq 1", got)
+	}
+
+	o.Enqueue(statusUpdate("t1", models.JobStatusCompleted))
+	o.mu.Lock()
+	seq := o.taskSeq["t1"]
+	o.mu.Unlock()
+	if seq != 2 {
+		t.Fatalf("final update of t1 has seq %d, want 2", seq)
+	}
+	if !o.deliver() {
+		t.Fatal("final update not delivered")
+	}
+	o.mu.Lock()
+	tracked := len(o.taskSeq)
+	o.mu.Unlock()
+	if tracked != 0 {
+		t.Errorf("outbox still tracks %d tasks after their final updates", tracked)
+	}
+	if got := m.received(); len(got) != 2 || got[1].Status != string(models.JobStatusCompleted) || got[1].Seq != 2 {
+		t.Errorf("manager applied %+v, want RUNNING then COMPLETED", got)
+	}
+}
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"titan/pkg/logger"
+	pb "titan/pkg/proto"
+)
+
+const (
+	// tmpfsDir holds task secret files where it exists, so they never
+	// reach a disk
+	tmpfsDir = "/dev/shm"
+
+	// minRedactedLength is the shortest secret value masked in task
+	// output; masking shorter ones would mangle unrelated text
+	minRedactedLength = 4
+
+	// redacted replaces secret values in task output
+	redacted = "[REDACTED]"
+)
+
+// writeSecretFiles writes the file secrets of a task into a new directory
+// only the worker's user can read, and returns it. It returns "" if the
+// task has no file secrets.
+func writeSecretFiles(taskID string, secrets []pb.TaskSecret) (string, error) {
+	var files []pb.TaskSecret
+	for _, secret := range secrets {
+		if secret.File != "" {
+			files = append(files, secret)
+		}
+	}
+	if len(files) == 0 {
+		return "", nil
+	}
+
+	base := os.TempDir()
+	if info, err := os.Stat(tmpfsDir); err == nil && info.IsDir() {
+		base = tmpfsDir
+	}
+	dir, err := os.MkdirTemp(base, "titan-secrets-"+taskID+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+	for _, secret := range files {
+		// The manager validated the path; check again rather than trust it
+		path := filepath.Join(dir, filepath.FromSlash(secret.File))
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("secret %s: invalid file %q", secret.Name, secret.File)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to write secret %s: %w", secret.Name, err)
+		}
+		if err := os.WriteFile(path, secret.Value, 0o600); err != nil {
+			os.RemoveAll(dir)
+			return "", fmt.Errorf("failed to write secret %s: %w", secret.Name, err)
+		}
+	}
+	return dir, nil
+}
+
+// redactSecrets masks the values of a task's secrets in its output
+func redactSecrets(output string, secrets []pb.TaskSecret) string {
+	for _, secret := range secrets {
+		// Files often end in a newline the task's output keeps apart
+		if value := strings.TrimSpace(string(secret.Value)); len(value) >= minRedactedLength {
+			output = strings.ReplaceAll(output, value, redacted)
+		}
+	}
+	return output
+}
+
+// removeSecretsDir deletes a task's secrets directory, if it has one
+func removeSecretsDir(dir string) {
+	if dir == "" {
+		return
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		logger.Error("Failed to remove task secrets", "dir", dir, "error", err)
+	}
+}
@@ -3,32 +3,48 @@ package worker
 import (
 	"fmt"
 	"net/rpc"
+	"path/filepath"
+	"sync/atomic"
+	"time"
 
 	"titan/pkg/logger"
+	"titan/pkg/managerclient"
 	pb "titan/pkg/proto"
 )
 
 // Server implements the Worker RPC service
 type Server struct {
-	executor     *Executor
-	heartbeater  *Heartbeater
-	workerID     string
-	address      string
-	managerAddr  string
+	managerClient *managerclient.Client
+	executor      *Executor
+	outbox        *Outbox
+	heartbeater   *Heartbeater
+	workerID      string
+	address       string
+	joinToken     string
+	labels        map[string]string
+	draining      atomic.Bool
 }
 
-// NewServer creates a new Worker server
-func NewServer(workerID, address, managerAddr string) (*Server, error) {
-	executor, err := NewExecutor(managerAddr)
+// NewServer creates a new Worker server reporting to the managers at
+// managerAddrs, connecting with managerOpts. joinToken, if set, is presented
+// when registering, along with labels that job constraints can select.
+// Undelivered task status updates are kept in stateDir across restarts.
+func NewServer(workerID, address, joinToken string, labels map[string]string, managerAddrs []string, managerOpts managerclient.Options, stateDir string) (*Server, error) {
+	client := managerclient.NewWithOptions(managerOpts, managerAddrs...)
+	
+	outbox, err := NewOutbox(filepath.Join(stateDir, "outbox"), client, workerID, joinToken)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create executor: %w", err)
+		return nil, fmt.Errorf("failed to open status outbox: %w", err)
 	}
 	
 	return &Server{
-		executor:    executor,
-		workerID:    workerID,
-		address:     address,
-		managerAddr: managerAddr,
+		managerClient: client,
+		executor:      NewExecutor(outbox),
+		outbox:        outbox,
+		workerID:      workerID,
+		address:       address,
+		joinToken:     joinToken,
+		labels:        labels,
 	}, nil
 }
 
@@ -39,15 +55,19 @@ func (s *Server) Start() error {
 		return fmt.Errorf("failed to register with manager: %w", err)
 	}
 	
+	// Deliver status updates, including any left over from a previous run
+	go s.outbox.Run()
+	
 	// Start heartbeat
-	s.heartbeater = NewHeartbeater(s.workerID, s.executor.managerClient, 10*1e9) // 10 seconds
+	s.heartbeater = NewHeartbeater(s.workerID, s.joinToken, s.managerClient, 10*1e9, s.register) // 10 seconds
 	go s.heartbeater.Start()
 	
 	logger.Info("Worker server started", "worker_id", s.workerID, "address", s.address)
 	return nil
 }
 
-// register registers the worker with the manager
+// register registers the worker with the manager, reporting any tasks that
+// are already running so the manager can rebuild its view after a restart
 func (s *Server) register() error {
 	req := pb.WorkerInfo{
 		WorkerId: s.workerID,
@@ -56,10 +76,13 @@ func (s *Server) register() error {
 			TotalCpuMillicores: 4000, // 4 cores
 			TotalMemoryMb:      8192, // 8 GB
 		},
+		RunningTasks: s.executor.Snapshot(),
+		JoinToken:    s.joinToken,
+		Labels:       s.labels,
 	}
 	
 	var resp pb.RegistrationResponse
-	err := s.executor.managerClient.Call("ManagerService.RegisterWorker", req, &resp)
+	err := s.managerClient.Call("WorkerService.RegisterWorker", req, &resp)
 	if err != nil {
 		return fmt.Errorf("failed to register: %w", err)
 	}
@@ -72,11 +95,64 @@ func (s *Server) register() error {
 	return nil
 }
 
+// Shutdown leaves the cluster gracefully. The worker deregisters from the
+// manager and stops accepting tasks, then gives running tasks up to timeout
+// to finish. Tasks still running after that are killed and reported as
+// interrupted so the manager can requeue them.
+func (s *Server) Shutdown(timeout time.Duration) {
+	s.draining.Store(true)
+	
+	if s.heartbeater != nil {
+		s.heartbeater.Stop()
+	}
+	
+	req := pb.DeregisterRequest{WorkerId: s.workerID, JoinToken: s.joinToken}
+	var resp pb.Ack
+	if err := s.managerClient.Call("WorkerService.DeregisterWorker", req, &resp); err != nil {
+		logger.Error("Failed to deregister from manager", "worker_id", s.workerID, "error", err)
+	}
+	
+	if running := s.executor.RunningTasks(); running > 0 {
+		logger.Info("Waiting for running tasks to finish", 
+			"worker_id", s.workerID, 
+			"tasks", running, 
+			"timeout", timeout.String())
+		
+		if !s.executor.Wait(timeout) {
+			s.executor.InterruptAll()
+			// Give the interrupted tasks a moment to report back
+			s.executor.Wait(5 * time.Second)
+		}
+	}
+	
+	if !s.outbox.Drain(5 * time.Second) {
+		logger.Warn("Some status updates were not delivered and will be resent on restart",
+			"pending", s.outbox.Len())
+	}
+	s.outbox.Stop()
+	
+	if err := s.managerClient.Close(); err != nil {
+		logger.Error("Failed to close manager connection", "error", err)
+	}
+	
+	logger.Info("Worker shut down", "worker_id", s.workerID)
+}
+
 // StartTask handles task execution requests from the manager
 func (s *Server) StartTask(req pb.TaskRequest, resp *pb.TaskResponse) error {
 	logger.Info("Received task", "task_id", req.TaskId, "job_id", req.JobId)
 	
-	err := s.executor.StartTask(req.TaskId, req.JobId, req.Command, req.Env)
+	if s.draining.Load() {
+		logger.Warn("Rejecting task, worker is shutting down", "task_id", req.TaskId)
+		*resp = pb.TaskResponse{
+			Accepted: false,
+			Message:  "worker is shutting down",
+		}
+		return nil
+	}
+	
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	err := s.executor.StartTask(req.TaskId, req.JobId, req.Command, req.Argv, req.Env, req.Secrets, timeout)
 	if err != nil {
 		logger.Error("Failed to start task", "task_id", req.TaskId, "error", err)
 		*resp = pb.TaskResponse{